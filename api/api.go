@@ -0,0 +1,112 @@
+// Package api serves rollup, DA proof and challenge data over JSON-RPC, so
+// frontends can query a node without shelling out to the cli.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"hummingbird/defender"
+	"hummingbird/health"
+	"hummingbird/node"
+	"hummingbird/rollup"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type Opts struct {
+	Logger      *slog.Logger
+	Addr        string   // Address to listen on, e.g. 127.0.0.1:8080.
+	CORSOrigins []string // Origins allowed to make cross origin requests, "*" allows any.
+}
+
+type Server struct {
+	*node.Node
+	Opts *Opts
+
+	rollup   *rollup.Rollup
+	defender *defender.Defender
+	mux      *http.ServeMux
+}
+
+func NewServer(n *node.Node, opts *Opts) (*Server, error) {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	s := &Server{
+		Node:     n,
+		Opts:     opts,
+		rollup:   rollup.NewRollup(n, &rollup.Opts{Logger: opts.Logger}),
+		defender: defender.NewDefender(n, &defender.Opts{Logger: opts.Logger}),
+		mux:      http.NewServeMux(),
+	}
+
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("hb", &service{s}); err != nil {
+		return nil, fmt.Errorf("failed to register hb service: %w", err)
+	}
+	s.mux.Handle("/", rpcServer)
+	if n.Metrics != nil {
+		s.mux.Handle("/metrics", n.Metrics.Handler())
+	}
+	// the api has no main loop, so only liveness is checked
+	health.NewChecker(n, &health.Opts{Logger: opts.Logger}).Register(s.mux)
+
+	return s, nil
+}
+
+// Handler returns the http handler serving every endpoint.
+func (s *Server) Handler() http.Handler {
+	return s.cors(s.mux)
+}
+
+// Start serves the api on Addr until ctx is done, then shuts the server
+// down gracefully.
+func (s *Server) Start(ctx context.Context) error {
+	s.Opts.Logger.Info("Starting api server", "addr", s.Opts.Addr)
+
+	srv := &http.Server{
+		Addr:              s.Opts.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	stop := context.AfterFunc(ctx, func() { shutdown(srv) })
+	defer stop()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// shutdown gives in-flight requests up to 5s to complete before closing the
+// server.
+func shutdown(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+// cors allows cross origin requests from CORSOrigins.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (slices.Contains(s.Opts.CORSOrigins, "*") || slices.Contains(s.Opts.CORSOrigins, origin)) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"hummingbird/defender"
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+	"hummingbird/rollup"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	challengeContract "hummingbird/node/contracts/Challenge.sol"
+)
+
+// service implements the hb_ JSON-RPC methods. Each exported method is
+// served as hb_<method>, e.g. GetRollupBlock as hb_getRollupBlock.
+type service struct {
+	s *Server
+}
+
+// BlockRef refers to a rollup block by hash, or by index as a number or a
+// hex quantity.
+type BlockRef struct {
+	Hash  *common.Hash
+	Index *uint64
+}
+
+func (r *BlockRef) UnmarshalJSON(data []byte) error {
+	var index uint64
+	if err := json.Unmarshal(data, &index); err == nil {
+		r.Index = &index
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("rollup block must be a hash or index")
+	}
+	if len(str) == 2+2*common.HashLength {
+		buf, err := hexutil.Decode(str)
+		if err != nil {
+			return fmt.Errorf("invalid rollup block hash: %w", err)
+		}
+		hash := common.BytesToHash(buf)
+		r.Hash = &hash
+		return nil
+	}
+
+	index, err := hexutil.DecodeUint64(str)
+	if err != nil {
+		return fmt.Errorf("invalid rollup block index: %w", err)
+	}
+	r.Index = &index
+	return nil
+}
+
+// blockHash returns the hash of the rollup block the ref refers to.
+func (api *service) blockHash(ctx context.Context, ref BlockRef) (common.Hash, error) {
+	if ref.Hash != nil {
+		return *ref.Hash, nil
+	}
+	if ref.Index == nil {
+		return common.Hash{}, fmt.Errorf("rollup block must be a hash or index")
+	}
+
+	header, err := api.s.Ethereum.GetRollupHeader(ctx, *ref.Index)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get rollup block %d: %w", *ref.Index, err)
+	}
+	return api.s.Ethereum.HashHeader(ctx, &header)
+}
+
+// GetRollupInfo returns the current rollup state.
+func (api *service) GetRollupInfo(ctx context.Context) (*rollup.RollupInfo, error) {
+	return api.s.rollup.GetInfo(ctx)
+}
+
+// GetRollupBlock returns the rollup block with the given hash or index.
+func (api *service) GetRollupBlock(ctx context.Context, ref BlockRef) (*rollup.RollupBlockInfo, error) {
+	hash, err := api.blockHash(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return api.s.rollup.GetBlockInfo(ctx, hash)
+}
+
+// Bundle is a bundle of L2 blocks published to Celestia.
+type Bundle struct {
+	PointerIndex uint8
+	Pointer      *node.CelestiaPointer
+	Blocks       []BundleBlock
+}
+
+type BundleBlock struct {
+	Number uint64
+	Hash   common.Hash // hash without extra data, as checked by the challenge contract
+}
+
+// GetBundle returns the bundle at the pointer index of the rollup block.
+func (api *service) GetBundle(ctx context.Context, ref BlockRef, pointerIndex uint8) (*Bundle, error) {
+	hash, err := api.blockHash(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pointers, err := api.s.GetDAPointer(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pointers: %w", err)
+	}
+	if int(pointerIndex) >= len(pointers) {
+		return nil, fmt.Errorf("pointer index %d out of range, rollup block has %d pointers", pointerIndex, len(pointers))
+	}
+
+	shares, err := api.s.Celestia.GetSharesByNamespace(ctx, pointers[pointerIndex])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shares: %w", err)
+	}
+	bundle, err := node.NewBundleFromShares(shares)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	res := &Bundle{PointerIndex: pointerIndex, Pointer: pointers[pointerIndex]}
+	for _, b := range bundle.Blocks {
+		res.Blocks = append(res.Blocks, BundleBlock{Number: b.NumberU64(), Hash: utils.HashWithoutExtraData(b)})
+	}
+	return res, nil
+}
+
+// GetHeaderShareProof returns a proof the L2 header is in the shares of the
+// rollup block, as ChainOracle.sol accepts it.
+func (api *service) GetHeaderShareProof(ctx context.Context, rblock common.Hash, l2Block common.Hash) (*defender.L2ShareProof, error) {
+	return api.s.defender.GetL2HeaderShareProof(ctx, rblock, l2Block)
+}
+
+// GetTxShareProof returns a proof the L2 tx is in the shares of the rollup
+// block, as ChainOracle.sol accepts it.
+func (api *service) GetTxShareProof(ctx context.Context, rblock common.Hash, l2Tx common.Hash) (*defender.L2ShareProof, error) {
+	return api.s.defender.GetL2TxShareProof(ctx, rblock, l2Tx)
+}
+
+// GetDAChallenge returns the DA challenge of the share in the rollup block.
+func (api *service) GetDAChallenge(ctx context.Context, rblock common.Hash, pointerIndex uint8, shareIndex uint32) (*contracts.ChallengeDaInfo, error) {
+	info, err := api.s.defender.InfoDA(ctx, rblock, pointerIndex, shareIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DAProof is a proof the share is available, as Challenge.sol accepts it.
+type DAProof struct {
+	ChallengeKey common.Hash
+	SharesProof  *challengeContract.SharesProof
+}
+
+// GetDAProof returns a proof the share in the rollup block is available,
+// used to defend a DA challenge.
+func (api *service) GetDAProof(ctx context.Context, rblock common.Hash, pointerIndex uint8, shareIndex uint32) (*DAProof, error) {
+	key, proof, err := api.s.defender.GetDaProof(ctx, rblock, pointerIndex, shareIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &DAProof{ChallengeKey: *key, SharesProof: proof}, nil
+}
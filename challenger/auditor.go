@@ -0,0 +1,278 @@
+package challenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FaultType is the kind of challenge a fault can be disputed with.
+type FaultType string
+
+const (
+	FaultDA       FaultType = "da"        // data is missing from Celestia
+	FaultL2Header FaultType = "l2-header" // an L2 header is missing or does not match LightLink
+)
+
+// Fault is an inconsistency found while auditing a rollup block.
+type Fault struct {
+	Type      FaultType
+	Index     uint64      // rollup block index
+	BlockHash common.Hash // rollup block hash
+	Reason    string
+
+	// set for DA faults
+	PointerIndex uint8
+	ShareIndex   uint32
+
+	// set for L2 header faults
+	L2Number uint64
+}
+
+// Audit downloads the rollup block at the given index from Celestia and
+// re-checks it against LightLink. It returns the faults found, which is
+// empty if the block is valid. Checking stops at the first fault, as a
+// single successful challenge rolls back the whole block.
+func (c *Challenger) Audit(ctx context.Context, index uint64) ([]*Fault, error) {
+	// 1. get the rollup block and its parent
+	if index == 0 {
+		return nil, fmt.Errorf("can not audit the genesis rollup block")
+	}
+	header, err := c.Ethereum.GetRollupHeader(ctx, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup block %d: %w", index, err)
+	}
+	prev, err := c.Ethereum.GetRollupHeader(ctx, index-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup block %d: %w", index-1, err)
+	}
+	hash, err := c.Ethereum.HashHeader(ctx, &header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash rollup block %d: %w", index, err)
+	}
+
+	// 2. download the bundles, if any are missing from Celestia or can't be
+	// decoded the data is challenged instead as the headers can't be checked.
+	// Any other error is returned to retry on the next pass
+	_, bundles, err := c.FetchRollupBlock(ctx, hash)
+	if err != nil {
+		fault, ferr := c.findDAFault(ctx, index, hash)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if fault == nil {
+			return nil, fmt.Errorf("failed to fetch rollup block %d: %w", index, err)
+		}
+		return []*Fault{fault}, nil
+	}
+
+	// 3. get the canonical blocks from LightLink, including the last block
+	// of the previous rollup block to check the parent hash of the first.
+	// The whole range is fetched, as it can span several bundles
+	canonical, err := c.LightLink.GetBlocks(ctx, prev.L2Height, header.L2Height, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks %d to %d from LightLink: %w", prev.L2Height, header.L2Height, err)
+	}
+	if uint64(len(canonical)) != header.L2Height-prev.L2Height+1 {
+		return nil, fmt.Errorf("got %d blocks from LightLink, expected %d to %d", len(canonical), prev.L2Height, header.L2Height)
+	}
+
+	l2HeaderFault := func(num uint64, reason string) []*Fault {
+		return []*Fault{{Type: FaultL2Header, Index: index, BlockHash: hash, L2Number: num, Reason: reason}}
+	}
+
+	// 4. check every block in the bundles matches LightLink, and links to
+	// the block before it
+	expected := prev.L2Height + 1
+	parent := canonical[0]
+	for _, bundle := range bundles {
+		for _, block := range bundle.Blocks {
+			if expected > header.L2Height {
+				break
+			}
+			if block.NumberU64() != expected {
+				return l2HeaderFault(expected, fmt.Sprintf("block %d is missing, found block %d in its place", expected, block.NumberU64())), nil
+			}
+			if utils.HashWithoutExtraData(block) != utils.HashWithoutExtraData(canonical[expected-prev.L2Height]) {
+				return l2HeaderFault(expected, "block hash does not match LightLink"), nil
+			}
+			if block.ParentHash() != parent.Header().Hash() {
+				return l2HeaderFault(expected, "block does not link to its parent"), nil
+			}
+			parent = block
+			expected++
+		}
+	}
+	if expected <= header.L2Height {
+		return l2HeaderFault(expected, fmt.Sprintf("block %d is missing, bundles end at block %d", expected, expected-1)), nil
+	}
+
+	// 5. recompute the output root from the last block
+	output, err := c.LightLink.GetOutputV0(ctx, canonical[len(canonical)-1].Header())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output for block %d: %w", header.L2Height, err)
+	}
+	if output.Root() != common.Hash(header.OutputRoot) {
+		return l2HeaderFault(header.L2Height, "output root does not match LightLink"), nil
+	}
+
+	return nil, nil
+}
+
+// findDAFault returns a DA fault for the first pointer in the rollup block
+// whose shares are missing from Celestia or can't be decoded, or nil if
+// every pointer can. Errors that don't show the shares are missing, such as
+// an unreachable node, are returned rather than treated as a fault.
+func (c *Challenger) findDAFault(ctx context.Context, index uint64, hash common.Hash) (*Fault, error) {
+	pointers, err := c.GetDAPointer(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pointers for rollup block %d: %w", index, err)
+	}
+
+	for i, pointer := range pointers {
+		var reason string
+		shares, err := c.Celestia.GetSharesByNamespace(ctx, pointer)
+		switch {
+		case errors.Is(err, node.ErrSharesMissing):
+			reason = fmt.Sprintf("shares are missing: %v", err)
+		case err != nil:
+			return nil, fmt.Errorf("failed to get shares for pointer %d of rollup block %d: %w", i, index, err)
+		default:
+			if _, err := node.NewBundleFromShares(shares); err != nil {
+				reason = fmt.Sprintf("failed to decode bundle: %v", err)
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		return &Fault{
+			Type:         FaultDA,
+			Index:        index,
+			BlockHash:    hash,
+			Reason:       reason,
+			PointerIndex: uint8(i),
+			ShareIndex:   uint32(pointer.ShareStart),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// isChallenged returns true if the fault has already been challenged, by us
+// or anyone else.
+func (c *Challenger) isChallenged(ctx context.Context, f *Fault) (bool, error) {
+	switch f.Type {
+	case FaultDA:
+		info, err := c.Ethereum.GetDataRootInclusionChallenge(ctx, f.BlockHash, f.PointerIndex, f.ShareIndex)
+		if err != nil {
+			return false, err
+		}
+		return info.Status != contracts.ChallengeDAStatusNone, nil
+	case FaultL2Header:
+		challengeHash, err := c.Ethereum.GetL2HeaderChallengeHash(ctx, f.BlockHash, new(big.Int).SetUint64(f.L2Number))
+		if err != nil {
+			return false, err
+		}
+		info, err := c.Ethereum.GetL2HeaderChallenge(ctx, challengeHash)
+		if err != nil {
+			return false, err
+		}
+		return info.Status != contracts.ChallengeL2HeaderStatusNone, nil
+	default:
+		return false, fmt.Errorf("unknown fault type %q", f.Type)
+	}
+}
+
+// Challenge opens a challenge for the fault, if it has not been challenged
+// already and the fee fits in the remaining budget. In dry run mode the
+// fault is only reported.
+func (c *Challenger) Challenge(ctx context.Context, f *Fault) error {
+	log := c.Opts.Logger.With(
+		"type", f.Type,
+		"rblockIndex", f.Index,
+		"rblockHash", f.BlockHash.Hex(),
+		"reason", f.Reason,
+	)
+	if f.Type == FaultDA {
+		log = log.With("pointerIndex", f.PointerIndex, "shareIndex", f.ShareIndex)
+	} else {
+		log = log.With("l2Number", f.L2Number)
+	}
+
+	// 1. skip faults someone has already challenged
+	challenged, err := c.isChallenged(ctx, f)
+	if err != nil {
+		return fmt.Errorf("failed to check for an existing challenge: %w", err)
+	}
+	if challenged {
+		log.Info("Fault found in rollup block has already been challenged")
+		return nil
+	}
+
+	if c.Opts.DryRun {
+		log.Warn("Fault found in rollup block, dry run enabled so not challenging")
+		return nil
+	}
+
+	// 2. check the fee fits in the budget
+	fee, err := c.Ethereum.GetChallengeFee(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get challenge fee: %w", err)
+	}
+	spent, err := c.loadSpent(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load spent budget: %w", err)
+	}
+	budget := c.Opts.Budget
+	if budget == nil {
+		budget = new(big.Int)
+	}
+	if new(big.Int).Add(spent, fee).Cmp(budget) > 0 {
+		log.Warn("Fault found in rollup block, challenge budget exhausted so not challenging", "fee", fee, "spent", spent, "budget", budget)
+		return nil
+	}
+
+	// 3. open the challenge
+	log.Warn("Fault found in rollup block, challenging")
+
+	var txHash common.Hash
+	switch f.Type {
+	case FaultDA:
+		tx, _, err := c.ChallengeDA(ctx, f.Index, f.PointerIndex, f.ShareIndex)
+		if err != nil {
+			return fmt.Errorf("failed to challenge data availability: %w", err)
+		}
+		txHash = tx.Hash()
+	case FaultL2Header:
+		tx, _, err := c.ChallengeL2Header(ctx, f.Index, f.L2Number)
+		if err != nil {
+			return fmt.Errorf("failed to challenge L2 header: %w", err)
+		}
+		txHash = tx.Hash()
+	}
+
+	// 4. wait for the challenge, the fee is only spent if it succeeds
+	receipt, err := c.Ethereum.Wait(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to wait for challenge tx %s: %w", txHash.Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("challenge tx %s failed", txHash.Hex())
+	}
+
+	if err := c.saveSpent(ctx, spent.Add(spent, fee)); err != nil {
+		log.Error("Failed to save spent budget", "error", err)
+	}
+
+	log.Info("Challenge opened", "tx", txHash.Hex(), "fee", fee, "spent", spent)
+	return nil
+}
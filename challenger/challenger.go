@@ -1,27 +1,77 @@
 package challenger
 
 import (
+	"context"
+	"fmt"
 	"hummingbird/node"
 	"log/slog"
+	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type Opts struct {
-	Logger *slog.Logger
-	DryRun bool // DryRun indicates whether or not to actually submit the block to the L1 rollup contract.
+	Logger      *slog.Logger
+	DryRun      bool          // DryRun indicates whether or not to actually submit the block to the L1 rollup contract.
+	WorkerDelay time.Duration // Delay between each scan for new rollup blocks.
+	Budget      *big.Int      // Max total challenge fees to spend in wei, once spent faults are only reported.
 }
 
 type Challenger struct {
 	*node.Node
 	Opts *Opts
+
+	spent *big.Int // total challenge fees spent, used when there is no store
 }
 
 func NewChallenger(node *node.Node, opts *Opts) *Challenger {
-	return &Challenger{Node: node, Opts: opts}
+	return &Challenger{Node: node, Opts: opts, spent: new(big.Int)}
+}
+
+func (c *Challenger) ChallengeDA(ctx context.Context, index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error) {
+	return c.Ethereum.ChallengeDataRootInclusion(ctx, index, pointerIndex, shareIndex)
+}
+
+// ChallengeL2Header challenges the publisher to prove the L2 header at
+// l2Num, and its parent, are included in the rollup block at index. It
+// returns the challenge hash used to look up and settle the challenge.
+func (c *Challenger) ChallengeL2Header(ctx context.Context, index uint64, l2Num uint64) (*types.Transaction, common.Hash, error) {
+	header, err := c.Ethereum.GetRollupHeader(ctx, index)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to get rollup block %d: %w", index, err)
+	}
+	rblockHash, err := c.Ethereum.HashHeader(ctx, &header)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to hash rollup block %d: %w", index, err)
+	}
+	challengeHash, err := c.Ethereum.GetL2HeaderChallengeHash(ctx, rblockHash, new(big.Int).SetUint64(l2Num))
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to get challenge hash: %w", err)
+	}
+
+	tx, err := c.Ethereum.ChallengeL2Header(ctx, new(big.Int).SetUint64(index), new(big.Int).SetUint64(l2Num))
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	return tx, challengeHash, nil
+}
+
+// SettleL2HeaderChallenge settles an expired L2 header challenge, rolling
+// back the rollup block if the defender did not respond in time.
+func (c *Challenger) SettleL2HeaderChallenge(ctx context.Context, rblockHash common.Hash, l2Num uint64) (*types.Transaction, error) {
+	challengeHash, err := c.Ethereum.GetL2HeaderChallengeHash(ctx, rblockHash, new(big.Int).SetUint64(l2Num))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge hash: %w", err)
+	}
+
+	return c.Ethereum.SettleL2HeaderChallenge(ctx, challengeHash)
 }
 
-func (c *Challenger) ChallengeDA(index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error) {
-	return c.Ethereum.ChallengeDataRootInclusion(index, pointerIndex, shareIndex)
+// InvalidateHeader rolls back the rollup block at index if its header is
+// invalid.
+func (c *Challenger) InvalidateHeader(ctx context.Context, index uint64) (*types.Transaction, error) {
+	return c.Ethereum.InvalidateHeader(ctx, index)
 }
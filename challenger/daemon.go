@@ -0,0 +1,172 @@
+package challenger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"hummingbird/node"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+var (
+	lastScannedKey = []byte("challenger_last_scanned") // last L1 block scanned for new rollup blocks
+	spentKey       = []byte("challenger_spent")        // total challenge fees spent, in wei
+)
+
+// maxScanRange is the max number of L1 blocks to filter logs for at once, to
+// avoid hitting eth_getLogs range limits.
+const maxScanRange = uint64(10000)
+
+// Start starts the challenger. It follows the BlockAdded events emitted by
+// CanonicalStateChain.sol, starting from the last block scanned or the start
+// of the challenge window, and audits every new rollup block. Any faults
+// found are challenged, up to the budget.
+func (c *Challenger) Start(ctx context.Context) error {
+	lastScanned, err := c.loadLastScanned(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load last scanned block: %w", err)
+	}
+
+	ticker := time.NewTicker(c.Opts.WorkerDelay)
+	defer ticker.Stop()
+
+	for {
+		c.Heartbeat.Beat("scan")
+		scanRanges, err := c.Ethereum.GetChallengeWindowBlockRanges(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get challenge window block ranges: %w", err)
+		}
+
+		start := scanRanges[0][0]
+		end := scanRanges[len(scanRanges)-1][1]
+		if lastScanned >= start {
+			start = lastScanned + 1
+		}
+
+		for from := start; from <= end; from += maxScanRange {
+			to := min(from+maxScanRange-1, end)
+
+			if err := c.auditBlocksAdded(ctx, from, to); err != nil {
+				return err
+			}
+
+			lastScanned = to
+			if err := c.saveLastScanned(ctx, to); err != nil {
+				c.Opts.Logger.Warn("Failed to save last scanned block", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// auditBlocksAdded audits every rollup block added in the given L1 block
+// range, and challenges any faults found.
+func (c *Challenger) auditBlocksAdded(ctx context.Context, startBlock, endBlock uint64) error {
+	log := c.Opts.Logger.With("startBlock", startBlock, "endBlock", endBlock)
+	log.Debug("Scanning logs for new rollup blocks")
+
+	events, err := c.Ethereum.FilterBlockAdded(&bind.FilterOpts{Start: startBlock, End: &endBlock, Context: ctx}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to filter BlockAdded events: %w", err)
+	}
+	defer events.Close()
+
+	for events.Next() {
+		index := events.Event.BlockNumber.Uint64()
+
+		// skip blocks that have since been rolled back
+		height, err := c.Ethereum.GetRollupHeight(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get rollup height: %w", err)
+		}
+		if index > height {
+			log.Info("Rollup block has been rolled back, skipping", "rblockIndex", index, "rollupHeight", height)
+			continue
+		}
+
+		faults, err := c.Audit(ctx, index)
+		if err != nil {
+			return fmt.Errorf("failed to audit rollup block %d: %w", index, err)
+		}
+		if len(faults) == 0 {
+			log.Info("Audited rollup block, no faults found", "rblockIndex", index)
+			continue
+		}
+
+		for _, f := range faults {
+			if err := c.Challenge(ctx, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return events.Error()
+}
+
+// loadLastScanned returns the last L1 block scanned for new rollup blocks,
+// or 0 if there is no store or the challenger has not run before.
+func (c *Challenger) loadLastScanned(ctx context.Context) (uint64, error) {
+	if c.Store == nil {
+		return 0, nil
+	}
+
+	buf, err := c.Store.Get(ctx, lastScannedKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("invalid last scanned block")
+	}
+
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// saveLastScanned persists the last L1 block scanned for new rollup blocks.
+func (c *Challenger) saveLastScanned(ctx context.Context, block uint64) error {
+	if c.Store == nil {
+		return nil
+	}
+
+	return c.Store.Put(ctx, lastScannedKey, binary.BigEndian.AppendUint64(nil, block))
+}
+
+// loadSpent returns the total challenge fees spent. Without a store, the
+// spend is only tracked for the life of the challenger.
+func (c *Challenger) loadSpent(ctx context.Context) (*big.Int, error) {
+	if c.Store == nil {
+		return new(big.Int).Set(c.spent), nil
+	}
+
+	buf, err := c.Store.Get(ctx, spentKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// saveSpent persists the total challenge fees spent.
+func (c *Challenger) saveSpent(ctx context.Context, spent *big.Int) error {
+	c.spent.Set(spent)
+	if c.Store == nil {
+		return nil
+	}
+
+	return c.Store.Put(ctx, spentKey, spent.Bytes())
+}
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hummingbird/cli/hb/cmd"
@@ -9,19 +9,13 @@ import (
 	"hummingbird/node"
 	"hummingbird/utils"
 	"log/slog"
-	"os"
 	"strings"
-
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func makeNode() (*node.Node, *slog.Logger, error) {
+func makeNode(ctx context.Context) (*node.Node, *slog.Logger, error) {
 	cfg := config.Load()
 	log := cmd.ConsoleLogger()
-	ethKey := getEthKey()
-
-	n, err := node.NewFromConfig(cfg, log, ethKey)
+	n, err := node.NewFromConfig(ctx, cfg, log, cmd.GetSigner(cfg))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -29,20 +23,6 @@ func makeNode() (*node.Node, *slog.Logger, error) {
 	return n, log, nil
 }
 
-func getEthKey() *ecdsa.PrivateKey {
-	key := os.Getenv("ETH_KEY")
-	if key == "" {
-		return nil
-	}
-
-	ethKey, err := crypto.ToECDSA(hexutil.MustDecode(key))
-	if err != nil {
-		return nil
-	}
-
-	return ethKey
-}
-
 // panicErr panics if err is not nil, with an optional prefix
 func panicErr(err error, prefix ...string) {
 	if err != nil {
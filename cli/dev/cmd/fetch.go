@@ -46,6 +46,7 @@ var (
 			"data-hash",
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
 
 			// 0. parse args
 			dataType := args[0]
@@ -53,14 +54,14 @@ var (
 			dataHash := common.HexToHash(args[2])
 
 			// 1. make node
-			n, log, err := makeNode()
+			n, log, err := makeNode(ctx)
 			panicErr(err, "failed to create node")
 			r := rollup.NewRollup(n, &rollup.Opts{
 				Logger: log.With("ctx", "Rollup"),
 			})
 
 			// 2. Get rblock and celestia pointer
-			rblock, err := r.GetBlockByHash(rblockHash)
+			rblock, err := r.GetBlockByHash(ctx, rblockHash)
 			panicErr(err, "failed to get rollup block")
 			log.Debug("✔️  Got Rollup Block", "hash", rblockHash)
 
@@ -114,13 +115,13 @@ var (
 			// 5. Generate proofs
 			var proof *chainoracleContract.SharesProof
 			if withProof {
-				shareProof, err := n.Celestia.GetSharesProof(celPointer, sharePointer)
+				shareProof, err := n.Celestia.GetSharesProof(ctx, celPointer, sharePointer)
 				panicErr(err, "failed to get share proof")
 
-				commitment, err := n.Ethereum.GetBlobstreamCommitment(int64(celPointer.Height))
+				commitment, err := n.Ethereum.GetBlobstreamCommitment(ctx, int64(celPointer.Height))
 				panicErr(err, "failed to get blobstream commitment")
 
-				celProof, err := n.Celestia.GetProof(celPointer, commitment.StartBlock, commitment.EndBlock, *commitment.ProofNonce)
+				celProof, err := n.Celestia.GetProof(ctx, celPointer, commitment.StartBlock, commitment.EndBlock, *commitment.ProofNonce)
 				panicErr(err, "failed to get celestia proof")
 
 				attestationProof := chainoracleContract.AttestationProof{
@@ -28,7 +28,8 @@ var InspectCmd = &cobra.Command{
 	Args:  cobra.MinimumNArgs(1),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		n, log, err := makeNode()
+		ctx := cmd.Context()
+		n, log, err := makeNode(ctx)
 		panicErr(err, "failed to create node")
 
 		r := rollup.NewRollup(n, &rollup.Opts{
@@ -46,7 +47,7 @@ var InspectCmd = &cobra.Command{
 		hash := common.HexToHash(args[0])
 		panicErr(err, "invalid block hash")
 		log.Info("Fetching Rollup Block", "hash", hash)
-		rblock, err := r.GetBlockByHash(hash)
+		rblock, err := r.GetBlockByHash(ctx, hash)
 		panicErr(err, "failed to get rollup block")
 
 		// 4. print the rollup block
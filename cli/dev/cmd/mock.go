@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"hummingbird/node"
 	"hummingbird/node/contracts"
 	canonicalstatechain "hummingbird/node/contracts/CanonicalStateChain.sol"
@@ -55,6 +56,7 @@ var MockCmd = &cobra.Command{
 		"blocks",
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		// 0. parse args
 		rblockPointer := strings.Split(args[0], ":")
@@ -68,14 +70,14 @@ var MockCmd = &cobra.Command{
 		}
 
 		// 1. make node
-		n, log, err := makeNode()
+		n, log, err := makeNode(ctx)
 		panicErr(err, "failed to create node")
 		r := rollup.NewRollup(n, &rollup.Opts{
 			Logger: log.With("ctx", "Rollup"),
 		})
 
 		// 2. Get rblock
-		rblock, err := r.GetBlockByHash(rblockHash)
+		rblock, err := r.GetBlockByHash(ctx, rblockHash)
 		panicErr(err, "failed to get rollup block")
 		log.Info("Got rblock", "hash", rblockHash.String(), "bundles", len(rblock.Bundles))
 
@@ -85,13 +87,13 @@ var MockCmd = &cobra.Command{
 		for i, blockNum := range blocks {
 
 			// – Fetch the blocks header data
-			hds[i] = getHeaderData(r, rblock, pointerIndex, blockNum)
+			hds[i] = getHeaderData(ctx, r, rblock, pointerIndex, blockNum)
 
 			// – Fetch the blocks transactions data
 			txCount := len(rblock.Bundles[pointerIndex].Blocks[blockNum].Transactions())
 			txs[i] = make([]TransactionData, txCount)
 			for txNum := 0; txNum < txCount; txNum++ {
-				txs[i][txNum] = getTransactionData(r, rblock, pointerIndex, blockNum, txNum)
+				txs[i][txNum] = getTransactionData(ctx, r, rblock, pointerIndex, blockNum, txNum)
 			}
 
 			log.Info("Got block", "blockNum", blockNum, "headerHash", hds[i].HeaderHash.String(), "txCount", txCount)
@@ -109,7 +111,7 @@ var MockCmd = &cobra.Command{
 	},
 }
 
-func getHeaderData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blockNum int) HeaderData {
+func getHeaderData(ctx context.Context, r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blockNum int) HeaderData {
 	bundle := rblock.Bundles[pointerIndex]
 
 	// - Get the header
@@ -120,10 +122,10 @@ func getHeaderData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blo
 	sharePointer, err := bundle.FindHeaderShares(headerHash, r.Namespace())
 	panicErr(err, "failed to find header shares")
 
-	shareProof, err := r.Celestia.GetSharesProof(rblock.GetCelestiaPointers()[pointerIndex], sharePointer)
+	shareProof, err := r.Celestia.GetSharesProof(ctx, rblock.GetCelestiaPointers()[pointerIndex], sharePointer)
 	panicErr(err, "failed to get share proof")
 
-	shareProofs, err := contracts.NewShareProof(shareProof, getAttestations(r.Node, rblock.GetCelestiaPointers()[pointerIndex]))
+	shareProofs, err := contracts.NewShareProof(shareProof, getAttestations(ctx, r.Node, rblock.GetCelestiaPointers()[pointerIndex]))
 	panicErr(err, "failed to get share proofs")
 
 	return HeaderData{
@@ -135,7 +137,7 @@ func getHeaderData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blo
 	}
 }
 
-func getTransactionData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blockNum int, txNum int) TransactionData {
+func getTransactionData(ctx context.Context, r *rollup.Rollup, rblock *rollup.Block, pointerIndex int, blockNum int, txNum int) TransactionData {
 	bundle := rblock.Bundles[pointerIndex]
 
 	// - Get the Transaction
@@ -145,10 +147,10 @@ func getTransactionData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int
 	sharePointer, err := bundle.FindTxShares(tx.Hash(), r.Namespace())
 	panicErr(err, "failed to find header shares")
 
-	shareProof, err := r.Celestia.GetSharesProof(rblock.GetCelestiaPointers()[pointerIndex], sharePointer)
+	shareProof, err := r.Celestia.GetSharesProof(ctx, rblock.GetCelestiaPointers()[pointerIndex], sharePointer)
 	panicErr(err, "failed to get share proof")
 
-	shareProofs, err := contracts.NewShareProof(shareProof, getAttestations(r.Node, rblock.GetCelestiaPointers()[pointerIndex]))
+	shareProofs, err := contracts.NewShareProof(shareProof, getAttestations(ctx, r.Node, rblock.GetCelestiaPointers()[pointerIndex]))
 	panicErr(err, "failed to get share proofs")
 
 	return TransactionData{
@@ -160,11 +162,11 @@ func getTransactionData(r *rollup.Rollup, rblock *rollup.Block, pointerIndex int
 	}
 }
 
-func getAttestations(n *node.Node, celPointer *node.CelestiaPointer) chainoracle.AttestationProof {
-	commitment, err := n.Ethereum.GetBlobstreamCommitment(int64(celPointer.Height))
+func getAttestations(ctx context.Context, n *node.Node, celPointer *node.CelestiaPointer) chainoracle.AttestationProof {
+	commitment, err := n.Ethereum.GetBlobstreamCommitment(ctx, int64(celPointer.Height))
 	panicErr(err, "failed to get blobstream commitment")
 
-	celProof, err := n.Celestia.GetProof(celPointer, commitment.StartBlock, commitment.EndBlock, *commitment.ProofNonce)
+	celProof, err := n.Celestia.GetProof(ctx, celPointer, commitment.StartBlock, commitment.EndBlock, *commitment.ProofNonce)
 	panicErr(err, "failed to get celestia proof")
 
 	return chainoracle.AttestationProof{
@@ -38,6 +38,7 @@ var MockDaCmd = &cobra.Command{
 	},
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		// 0. parse args
 		rblockPointer := strings.Split(args[0], ":")
@@ -46,14 +47,14 @@ var MockDaCmd = &cobra.Command{
 		shareIndex, _ := strconv.Atoi(rblockPointer[2])
 
 		// 1. make node
-		n, log, err := makeNode()
+		n, log, err := makeNode(ctx)
 		panicErr(err, "failed to create node")
 		r := rollup.NewRollup(n, &rollup.Opts{
 			Logger: log.With("ctx", "Rollup"),
 		})
 
 		// 2. Get rblock
-		rblock, err := r.GetBlockByHash(rblockHash)
+		rblock, err := r.GetBlockByHash(ctx, rblockHash)
 		panicErr(err, "failed to get rollup block")
 		log.Info("Got rblock", "hash", rblockHash.String(), "bundles", len(rblock.Bundles))
 
@@ -62,7 +63,7 @@ var MockDaCmd = &cobra.Command{
 			Logger: log.With("ctx", "Defender"),
 		})
 
-		key, shareProof, err := d.GetDaProof(rblockHash, uint8(pointerIndex), uint32(shareIndex))
+		key, shareProof, err := d.GetDaProof(ctx, rblockHash, uint8(pointerIndex), uint32(shareIndex))
 		panicErr(err, "failed to get da proof")
 
 		// 4. Output the mock data
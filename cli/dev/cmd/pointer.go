@@ -27,7 +27,8 @@ var PointerCmd = &cobra.Command{
 	Args:       cobra.MinimumNArgs(1),
 	ArgAliases: []string{"tx-hash"},
 	Run: func(cmd *cobra.Command, args []string) {
-		n, log, err := makeNode()
+		ctx := cmd.Context()
+		n, log, err := makeNode(ctx)
 		panicErr(err, "failed to create node")
 
 		// 0. parse flags
@@ -36,13 +37,13 @@ var PointerCmd = &cobra.Command{
 
 		// 1. get the data pointer
 		log.Info("Fetching celestia pointer", "tx", common.Hash(txHash))
-		dataPointer, err := n.Celestia.GetPointer(common.BytesToHash(txHash))
+		dataPointer, err := n.Celestia.GetPointer(ctx, common.BytesToHash(txHash))
 		panicErr(err, "failed to get data pointer")
 
 		// 2. verify the data pointer
 		if verifyPointer {
 			log.Info("Verifying Data Pointer", "celestiaHeight", dataPointer.Height, "shareStart", dataPointer.ShareStart, "shareLength", dataPointer.ShareLen)
-			shares, err := n.Celestia.GetSharesByPointer(dataPointer)
+			shares, err := n.Celestia.GetSharesByPointer(ctx, dataPointer)
 			panicErr(err, "failed to get shares")
 
 			data := utils.ExtractDataFromShares(shares)
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	BridgeDepositsCmd.Flags().Bool("json", false, "output deposits in json format")
+	BridgeDepositsCmd.Flags().Uint64("from", 0, "L1 block to search for deposits from (default is 300 blocks before --to)")
+	BridgeDepositsCmd.Flags().Uint64("to", 0, "L1 block to search for deposits to (default is the current L1 height)")
+	BridgeDepositsCmd.Flags().Duration("timeout", 30*time.Minute, "time after which a deposit not found on LightLink is reported missing")
+}
+
+var BridgeDepositsCmd = &cobra.Command{
+	Use:   "deposits",
+	Short: "deposits will report whether each deposit made on L1 is pending, included or missing on LightLink",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		// get the L1 block range to search
+		to, _ := cmd.Flags().GetUint64("to")
+		if !cmd.Flags().Changed("to") {
+			to, err = n.Ethereum.GetHeight(ctx)
+			utils.NoErr(err)
+		}
+		from, _ := cmd.Flags().GetUint64("from")
+		if !cmd.Flags().Changed("from") && to > 300 {
+			from = to - 300
+		}
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		deposits, err := n.GetDeposits(ctx, from, to)
+		utils.NoErr(err)
+
+		err = n.TrackDeposits(ctx, deposits, timeout)
+		utils.NoErr(err)
+
+		if useJson, _ := cmd.Flags().GetBool("json"); useJson {
+			buf, err := json.MarshalIndent(deposits, "", "  ")
+			utils.NoErr(err)
+			fmt.Println(string(buf))
+			return
+		}
+
+		fmt.Println("Deposits in L1 blocks", from, "to", to)
+		fmt.Println(" ")
+		if len(deposits) == 0 {
+			fmt.Println("→ No deposits were found")
+			fmt.Println(" ")
+			return
+		}
+
+		for _, d := range deposits {
+			fmt.Println("Deposit:", d.L1TxHash.Hex(), "from", d.From.Hex(), "to", d.To.Hex())
+			switch d.State {
+			case node.DepositIncluded:
+				fmt.Println("→ Included in L2 block", d.L2Height, "tx", d.L2TxHash.Hex())
+			case node.DepositMissing:
+				fmt.Println("→ Missing, not found on LightLink", timeout, "after the deposit")
+			default:
+				fmt.Println("→ Pending, not found on LightLink yet")
+			}
+			fmt.Println(" ")
+		}
+	},
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var BridgeFinalizeCmd = &cobra.Command{
+	Use:        "finalize",
+	Short:      "finalize will finalize the proven withdrawals initiated by an L2 tx, once past the challenge window",
+	ArgAliases: []string{"l2TxHash"},
+	Args:       cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		l2TxHash := common.HexToHash(args[0])
+		withdrawals, err := n.GetWithdrawals(ctx, l2TxHash)
+		utils.NoErr(err)
+		if len(withdrawals) == 0 {
+			logger.Warn("No withdrawals found in tx", "l2TxHash", l2TxHash.Hex())
+			return
+		}
+
+		for _, w := range withdrawals {
+			logger.Info("Finalizing withdrawal", "withdrawal", w.Hash.Hex())
+			tx, err := n.FinalizeWithdrawal(ctx, w)
+			utils.NoErr(err)
+
+			_, err = n.Ethereum.Wait(ctx, tx.Hash())
+			utils.NoErr(err)
+
+			logger.Info("Finalized withdrawal", "withdrawal", w.Hash.Hex(), "tx", tx.Hash().Hex())
+		}
+	},
+}
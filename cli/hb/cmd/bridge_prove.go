@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var BridgeProveCmd = &cobra.Command{
+	Use:        "prove",
+	Short:      "prove will prove the withdrawals initiated by an L2 tx in LightLinkPortal, against the first rollup block that includes them",
+	ArgAliases: []string{"l2TxHash"},
+	Args:       cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		l2TxHash := common.HexToHash(args[0])
+		withdrawals, err := n.GetWithdrawals(ctx, l2TxHash)
+		utils.NoErr(err)
+		if len(withdrawals) == 0 {
+			logger.Warn("No withdrawals found in tx", "l2TxHash", l2TxHash.Hex())
+			return
+		}
+
+		for _, w := range withdrawals {
+			status, err := n.GetWithdrawalStatus(ctx, w)
+			utils.NoErr(err)
+			if status.Proven {
+				logger.Info("Withdrawal already proven", "withdrawal", w.Hash.Hex(), "rblockIndex", status.L2OutputIndex)
+				continue
+			}
+
+			logger.Info("Proving withdrawal", "withdrawal", w.Hash.Hex(), "l2Height", w.L2Height)
+			tx, err := n.ProveWithdrawal(ctx, w)
+			utils.NoErr(err)
+
+			_, err = n.Ethereum.Wait(ctx, tx.Hash())
+			utils.NoErr(err)
+
+			logger.Info("Proved withdrawal", "withdrawal", w.Hash.Hex(), "tx", tx.Hash().Hex())
+		}
+	},
+}
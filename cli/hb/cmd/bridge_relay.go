@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/relayer"
+	"hummingbird/utils"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var BridgeRelayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "relay will start the relayer, which proves and finalizes new withdrawals from LightLink",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		// parse the allowlist
+		senders := []common.Address{}
+		for _, s := range cfg.Relayer.Senders {
+			senders = append(senders, common.HexToAddress(s))
+		}
+		targets := []common.Address{}
+		for _, t := range cfg.Relayer.Targets {
+			targets = append(targets, common.HexToAddress(t))
+		}
+
+		r := relayer.NewRelayer(n, &relayer.Opts{
+			Logger:      logger.With("ctx", "Relayer"),
+			WorkerDelay: time.Duration(cfg.Relayer.WorkerDelay) * time.Millisecond,
+			StartHeight: cfg.Relayer.StartHeight,
+			Senders:     senders,
+			Targets:     targets,
+		})
+
+		serveStatus(ctx, n, logger, cfg, ethSigner)
+
+		runUntilStopped(ctx, logger, "Relayer.Start", r.Start)
+	},
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/relayer"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var BridgeRequeueCmd = &cobra.Command{
+	Use:        "requeue",
+	Short:      "requeue will move a withdrawal the relayer failed to relay back to pending, run it while the relayer is stopped",
+	ArgAliases: []string{"withdrawalHash"},
+	Args:       cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		if n.Store == nil {
+			utils.NoErr(errors.New("the store is disabled, so the relayer keeps no withdrawals to requeue"))
+		}
+
+		hash := common.HexToHash(args[0])
+		r := relayer.NewRelayer(n, &relayer.Opts{Logger: logger.With("ctx", "Relayer")})
+		utils.NoErr(r.Requeue(ctx, hash))
+
+		logger.Info("Requeued withdrawal", "withdrawal", hash.Hex())
+	},
+}
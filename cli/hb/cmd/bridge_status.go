@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	BridgeStatusCmd.Flags().Bool("json", false, "output status in json format")
+}
+
+var BridgeStatusCmd = &cobra.Command{
+	Use:        "status",
+	Short:      "status will show the proven and finalized state of each withdrawal initiated by an L2 tx",
+	ArgAliases: []string{"l2TxHash"},
+	Args:       cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		l2TxHash := common.HexToHash(args[0])
+		withdrawals, err := n.GetWithdrawals(ctx, l2TxHash)
+		utils.NoErr(err)
+
+		statuses := []*node.WithdrawalStatus{}
+		for _, w := range withdrawals {
+			status, err := n.GetWithdrawalStatus(ctx, w)
+			utils.NoErr(err)
+			statuses = append(statuses, status)
+		}
+
+		if useJson, _ := cmd.Flags().GetBool("json"); useJson {
+			buf, err := json.MarshalIndent(statuses, "", "  ")
+			utils.NoErr(err)
+			fmt.Println(string(buf))
+			return
+		}
+
+		fmt.Println("Withdrawal Status")
+		fmt.Println(" ")
+		if len(statuses) == 0 {
+			fmt.Println("→ No withdrawals were found in this tx")
+			fmt.Println(" ")
+			return
+		}
+
+		for _, status := range statuses {
+			fmt.Println("Withdrawal:", status.Hash.Hex())
+			switch {
+			case status.Finalized:
+				fmt.Println("→ The withdrawal has been finalized")
+			case status.ReadyToFinalize:
+				fmt.Println("→ The withdrawal was proven against rollup block", status.L2OutputIndex, "at", status.ProvenAt.Format(time.RFC1123Z))
+				fmt.Println(" ⏳	Next: Ready to finalize")
+			case status.Proven:
+				fmt.Println("→ The withdrawal was proven against rollup block", status.L2OutputIndex, "at", status.ProvenAt.Format(time.RFC1123Z))
+				fmt.Println(" ⏳	Next: Awaiting the end of the challenge window...")
+			case status.RolledUp:
+				fmt.Println("→ The withdrawal is included in rollup block", status.RollupIndex)
+				fmt.Println(" ⏳	Next: Ready to prove")
+			default:
+				fmt.Println("→ The withdrawal is not yet included in a rollup block")
+				fmt.Println(" ⏳	Next: Awaiting the next rollup block...")
+			}
+			fmt.Println(" ")
+		}
+	},
+}
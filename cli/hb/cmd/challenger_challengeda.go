@@ -19,16 +19,18 @@ var ChallengerChallengedaCmd = &cobra.Command{
 	Args:       cobra.MinimumNArgs(3),
 
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
 		// is dry run enabled?
 		dryRun, _ := cmd.Flags().GetBool("dry")
 		cfg.DryRun = dryRun
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		c := challenger.NewChallenger(n, &challenger.Opts{
 			Logger: logger.With("ctx", "Challenger"),
@@ -55,7 +57,7 @@ var ChallengerChallengedaCmd = &cobra.Command{
 			panic(err)
 		}
 
-		tx, blockHash, err := c.ChallengeDA(blockIndex, uint8(pointerIndex), uint32(shareIndex))
+		tx, blockHash, err := c.ChallengeDA(ctx, blockIndex, uint8(pointerIndex), uint32(shareIndex))
 		if err != nil {
 			logger.Error("Failed to challenge data availability", "err", err)
 			panic(err)
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"hummingbird/challenger"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ChallengerChallengeHeaderCmd = &cobra.Command{
+	Use:        "challenge-header",
+	Short:      "challenge-header will challenge the publisher to prove an L2 header is included in a rollup block",
+	ArgAliases: []string{"rblock", "l2num"},
+	Args:       cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		c := challenger.NewChallenger(n, &challenger.Opts{
+			Logger: logger.With("ctx", "Challenger"),
+		})
+
+		// get rollup block index and l2 block number from args
+		blockIndex, err := strconv.ParseUint(args[0], 10, 64)
+		utils.NoErr(err)
+		l2num, err := strconv.ParseUint(args[1], 10, 64)
+		utils.NoErr(err)
+		logger.Info("Challenging L2 header", "rblock", blockIndex, "l2num", l2num)
+
+		tx, challengeHash, err := c.ChallengeL2Header(ctx, blockIndex, l2num)
+		utils.NoErr(err)
+
+		_, err = n.Ethereum.Wait(ctx, tx.Hash())
+		utils.NoErr(err)
+
+		logger.Info("Challenged L2 header", "tx", tx.Hash().Hex(), "challengeHash", challengeHash.Hex())
+	},
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"hummingbird/challenger"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ChallengerInvalidateHeaderCmd = &cobra.Command{
+	Use:        "invalidate-header",
+	Short:      "invalidate-header will roll back a rollup block whose header is invalid",
+	ArgAliases: []string{"rblock"},
+	Args:       cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		c := challenger.NewChallenger(n, &challenger.Opts{
+			Logger: logger.With("ctx", "Challenger"),
+		})
+
+		// get rollup block index from args
+		blockIndex, err := strconv.ParseUint(args[0], 10, 64)
+		utils.NoErr(err)
+		logger.Info("Invalidating rollup block header", "rblock", blockIndex)
+
+		tx, err := c.InvalidateHeader(ctx, blockIndex)
+		utils.NoErr(err)
+
+		_, err = n.Ethereum.Wait(ctx, tx.Hash())
+		utils.NoErr(err)
+
+		logger.Info("Invalidated rollup block header", "tx", tx.Hash().Hex())
+	},
+}
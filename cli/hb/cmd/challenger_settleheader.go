@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"hummingbird/challenger"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ChallengerSettleHeaderCmd = &cobra.Command{
+	Use:        "settle-header",
+	Short:      "settle-header will settle an expired L2 header challenge, rolling back the rollup block if it was not defended",
+	ArgAliases: []string{"rblock", "l2num"},
+	Args:       cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		c := challenger.NewChallenger(n, &challenger.Opts{
+			Logger: logger.With("ctx", "Challenger"),
+		})
+
+		// get rollup block hash and l2 block number from args
+		rblockHash := common.HexToHash(args[0])
+		l2num, err := strconv.ParseUint(args[1], 10, 64)
+		utils.NoErr(err)
+		logger.Info("Settling L2 header challenge", "rblock", rblockHash.Hex(), "l2num", l2num)
+
+		tx, err := c.SettleL2HeaderChallenge(ctx, rblockHash, l2num)
+		utils.NoErr(err)
+
+		_, err = n.Ethereum.Wait(ctx, tx.Hash())
+		utils.NoErr(err)
+
+		logger.Info("Settled L2 header challenge", "tx", tx.Hash().Hex())
+	},
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"hummingbird/challenger"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	ChallengerStartCmd.Flags().Bool("dry", false, "dry run will audit rollup blocks and report any faults, without opening challenges")
+}
+
+var ChallengerStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "start will start the challenger node, which audits every new rollup block and challenges any faults",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		// is dry run enabled?
+		dryRun, _ := cmd.Flags().GetBool("dry")
+		cfg.DryRun = dryRun
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		// convert the budget from ETH to wei
+		budget, _ := new(big.Float).Mul(big.NewFloat(cfg.Challenger.Budget), big.NewFloat(params.Ether)).Int(nil)
+
+		c := challenger.NewChallenger(n, &challenger.Opts{
+			Logger:      logger.With("ctx", "Challenger"),
+			DryRun:      dryRun,
+			WorkerDelay: time.Duration(cfg.Challenger.WorkerDelay) * time.Millisecond,
+			Budget:      budget,
+		})
+
+		// settle expired challenges and claim rewards, unless only reporting
+		if !dryRun {
+			startSettler(ctx, n, logger, ethSigner.Address(), time.Duration(cfg.Challenger.WorkerDelay)*time.Millisecond)
+		}
+
+		serveStatus(ctx, n, logger, cfg, ethSigner)
+
+		runUntilStopped(ctx, logger, "Challenger.Start", c.Start)
+	},
+}
@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"hummingbird/config"
+	"hummingbird/node/signer"
+	"hummingbird/utils"
 	"io"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/lmittmann/tint"
@@ -59,6 +64,38 @@ func parseLogLevel(lvl string) slog.Level {
 	}
 }
 
+// GetSigner returns the L1 tx signer set by signer.type in the config. By
+// default the raw hex private key in ETH_KEY is used, but a keystore or
+// remote signer keeps the key out of the environment.
+func GetSigner(cfg *config.Config) signer.Signer {
+	switch cfg.Signer.Type {
+	case "", "env":
+		return signer.NewPrivateKey(getEthKey())
+	case "keystore":
+		password := os.Getenv("KEYSTORE_PASSWORD")
+		if cfg.Signer.PasswordFile != "" {
+			var err error
+			password, err = signer.ReadPassword(cfg.Signer.PasswordFile)
+			must(err)
+		}
+		s, err := signer.NewKeystore(cfg.Signer.Keystore, password)
+		must(err)
+		return s
+	case "remote":
+		if !common.IsHexAddress(cfg.Signer.Address) {
+			panic("signer.address must be set to the address of the remote signer")
+		}
+		s, err := signer.NewRemote(&signer.RemoteOpts{
+			Endpoint: cfg.Signer.Endpoint,
+			Address:  common.HexToAddress(cfg.Signer.Address),
+		})
+		must(err)
+		return s
+	default:
+		panic("signer type must be 'env', 'keystore' or 'remote' got: " + cfg.Signer.Type)
+	}
+}
+
 func getEthKey() *ecdsa.PrivateKey {
 	key := os.Getenv("ETH_KEY")
 	if key == "" {
@@ -78,3 +115,23 @@ func must(err error) {
 		panic(err)
 	}
 }
+
+// runUntilStopped calls start until ctx is done, such as on SIGINT or
+// SIGTERM, retrying 5s after it fails.
+func runUntilStopped(ctx context.Context, logger *slog.Logger, name string, start func(context.Context) error) {
+	for {
+		err := start(ctx)
+		if ctx.Err() != nil {
+			logger.Info("Stopped "+name, "reason", context.Cause(ctx))
+			return
+		}
+		if err != nil {
+			logger.Error(name+" failed", "err", err, "retry_in", "5s")
+		}
+
+		if utils.Sleep(ctx, 5*time.Second) != nil {
+			logger.Info("Stopped "+name, "reason", context.Cause(ctx))
+			return
+		}
+	}
+}
@@ -24,16 +24,18 @@ var DefenderDefendDaCmd = &cobra.Command{
 	Args:       cobra.MinimumNArgs(3),
 
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := ConsoleLogger()
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
 		// is dry run enabled?
 		dryRun, _ := cmd.Flags().GetBool("dry")
 		cfg.DryRun = dryRun
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		if dryRun {
 			logger.Warn("DryRun is enabled, using mock celestia client")
@@ -51,7 +53,7 @@ var DefenderDefendDaCmd = &cobra.Command{
 		pointerIndex, _ := strconv.Atoi(args[1])
 		shareIndex, _ := strconv.Atoi(args[2])
 
-		tx, err := d.DefendDA(blockHash, uint8(pointerIndex), uint32(shareIndex))
+		tx, err := d.DefendDA(ctx, blockHash, uint8(pointerIndex), uint32(shareIndex))
 		if err != nil {
 			if strings.Contains(err.Error(), "no data commitment has been generated for the provided height") {
 				logger.Error("Failed to defend data availability, please wait for Celestia validators to commit data root", "err", err)
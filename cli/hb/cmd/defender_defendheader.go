@@ -17,12 +17,14 @@ var DefendHeaderCmd = &cobra.Command{
 	ArgAliases: []string{"rblock", "l2num"},
 	Args:       cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := ConsoleLogger()
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		d := defender.NewDefender(n, &defender.Opts{
 			Logger: logger.With("ctx", "Defender"),
@@ -33,7 +35,7 @@ var DefendHeaderCmd = &cobra.Command{
 		l2num, _ := new(big.Int).SetString(args[1], 10)
 		logger.Info("Defending L2 header", "rblock", rblockHash.Hex(), "l2num", l2num.String())
 
-		tx, err := d.DefendL2Header(rblockHash, l2num)
+		tx, err := d.DefendL2Header(ctx, rblockHash, l2num)
 		utils.NoErr(err)
 
 		logger.Info("Defended L2 header", "tx", tx.Hash().Hex())
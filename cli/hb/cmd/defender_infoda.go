@@ -24,12 +24,14 @@ var DefenderInfoDaCmd = &cobra.Command{
 	ArgAliases: []string{"block", "pointerIndex", "shareIndex"},
 	Args:       cobra.ExactArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		d := defender.NewDefender(n, &defender.Opts{
 			Logger: logger.With("ctx", "Defender"),
@@ -47,7 +49,7 @@ var DefenderInfoDaCmd = &cobra.Command{
 			panic(err)
 		}
 
-		info, err := d.InfoDA(blockHash, uint8(pointerIndex), uint32(shareIndex))
+		info, err := d.InfoDA(ctx, blockHash, uint8(pointerIndex), uint32(shareIndex))
 		if err != nil {
 			logger.Error("Failed to get data availability info", "err", err)
 			panic(err)
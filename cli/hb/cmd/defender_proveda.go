@@ -31,12 +31,14 @@ var DefenderProveDaCmd = &cobra.Command{
 		"pointerIndex",
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		d := defender.NewDefender(n, &defender.Opts{
 			Logger: logger.With("ctx", "Defender"),
@@ -49,7 +51,7 @@ var DefenderProveDaCmd = &cobra.Command{
 			utils.NoErr(err)
 		}
 
-		proof, err := d.GetAttestationProof(blockHash, uint8(pointerIndex))
+		proof, err := d.GetAttestationProof(ctx, blockHash, uint8(pointerIndex))
 		if err != nil {
 			logger.Error("Failed to prove data availability", "err", err)
 			return
@@ -78,7 +80,7 @@ var DefenderProveDaCmd = &cobra.Command{
 		}
 
 		// Verify the proof against the L1 rollup contract.
-		verified, err := n.Ethereum.DAVerify(proof.TupleRootNonce, blobstreamx.DataRootTuple(proof.Tuple), blobstreamx.BinaryMerkleProof(proof.Proof))
+		verified, err := n.Ethereum.DAVerify(ctx, proof.TupleRootNonce, blobstreamx.DataRootTuple(proof.Tuple), blobstreamx.BinaryMerkleProof(proof.Proof))
 		if err != nil {
 			logger.Error("Failed to verify proof", "err", err)
 			return
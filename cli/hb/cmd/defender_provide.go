@@ -27,15 +27,17 @@ var DefenderProvideCmd = &cobra.Command{
 	},
 	Args: cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
 		rblockHash := common.HexToHash(args[0])
 		targetHash := common.HexToHash(args[1])
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		d := defender.NewDefender(n, &defender.Opts{
 			Logger: logger.With("ctx", "Defender"),
@@ -53,14 +55,14 @@ var DefenderProvideCmd = &cobra.Command{
 		switch t {
 		case "header":
 			logger.Info("Providing L2 Header...")
-			tx, err = d.ProvideL2Header(rblockHash, targetHash, skipShares)
+			tx, err = d.ProvideL2Header(ctx, rblockHash, targetHash, skipShares)
 			if err != nil {
 				logger.Error("Defender.Provide header failed", "err", err)
 				return
 			}
 		case "tx":
 			logger.Info("Providing L2 Tx...")
-			tx, err = d.ProvideL2Tx(rblockHash, targetHash, skipShares)
+			tx, err = d.ProvideL2Tx(ctx, rblockHash, targetHash, skipShares)
 			if err != nil {
 				logger.Error("Defender.Provide tx failed", "err", err)
 				return
@@ -15,24 +15,25 @@ var DefenderStartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "start will start the defender node",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		d := defender.NewDefender(n, &defender.Opts{
 			Logger:      logger.With("ctx", "Defender"),
 			WorkerDelay: time.Duration(cfg.Defender.WorkerDelay) * time.Millisecond,
+			Subscribe:   cfg.Ethereum.WSEndpoint != "",
+			Workers:     cfg.Defender.Workers,
 		})
-		for {
-			err = d.Start()
-			if err != nil {
-				logger.Error("Defender.Start failed", "err", err, "retry_in", "5s")
-			}
+		// settle expired challenges and claim rewards left after a restart
+		startSettler(ctx, n, logger, ethSigner.Address(), time.Duration(cfg.Defender.WorkerDelay)*time.Millisecond)
+		serveStatus(ctx, n, logger, cfg, ethSigner)
 
-			time.Sleep(5 * time.Second)
-		}
+		runUntilStopped(ctx, logger, "Defender.Start", d.Start)
 	},
 }
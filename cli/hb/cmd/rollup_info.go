@@ -25,13 +25,15 @@ var RollupInfoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "info will print information about the current rollup state",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 		useJson, _ := cmd.Flags().GetBool("json")
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		r := rollup.NewRollup(n, &rollup.Opts{
 			L1PollDelay: time.Duration(cfg.Rollup.L1PollDelay) * time.Millisecond,
@@ -56,9 +58,9 @@ var RollupInfoCmd = &cobra.Command{
 
 		var blockHash common.Hash
 		if useNum {
-			h, err := r.Ethereum.GetRollupHeader(num)
+			h, err := r.Ethereum.GetRollupHeader(ctx, num)
 			utils.NoErr(err)
-			blockHash, err = r.Ethereum.HashHeader(&h)
+			blockHash, err = r.Ethereum.HashHeader(ctx, &h)
 			utils.NoErr(err)
 		}
 		if useHash {
@@ -67,14 +69,14 @@ var RollupInfoCmd = &cobra.Command{
 
 		// if a hash or number is specified, get info for the block with that hash
 		if useHash || useNum {
-			info, err := r.GetBlockInfo(blockHash)
+			info, err := r.GetBlockInfo(ctx, blockHash)
 			utils.NoErr(err)
 			printInfo(info, useJson)
 
 			// if showBundle flag is set, get showBundle info
 			if showBundle, _ := cmd.Flags().GetBool("bundle"); showBundle {
 				for i, p := range info.CanonicalStateChainHeader.CelestiaPointers {
-					s, err := r.Celestia.GetSharesByNamespace(&node.CelestiaPointer{
+					s, err := r.Celestia.GetSharesByNamespace(ctx, &node.CelestiaPointer{
 						Height:     p.Height,
 						ShareStart: p.ShareStart.Uint64(),
 						ShareLen:   uint64(p.ShareLen),
@@ -96,7 +98,7 @@ var RollupInfoCmd = &cobra.Command{
 		}
 
 		// otherwise get info for the chain
-		info, err := r.GetInfo()
+		info, err := r.GetInfo(ctx)
 		utils.NoErr(err)
 		printInfo(info, useJson)
 	},
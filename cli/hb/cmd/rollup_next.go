@@ -22,16 +22,18 @@ var RollupNextCmd = &cobra.Command{
 	Use:   "next",
 	Short: "next will rollup the next batch of L2 blocks",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
 		// is dry run enabled?
 		dryRun, _ := cmd.Flags().GetBool("dry")
 		cfg.DryRun = dryRun
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		// is mock data availability enabled?
 		if mockDA, _ := cmd.Flags().GetBool("mock-da"); mockDA {
@@ -39,7 +41,7 @@ var RollupNextCmd = &cobra.Command{
 		}
 
 		// Can only run rollup node if the eth key is a publisher
-		if !n.IsPublisher(ethKey) {
+		if !n.IsPublisher(ctx, ethSigner.Address()) {
 			logger.Warn("ETH_KEY is not a publisher, cannot run rollup next command")
 			return
 		}
@@ -49,9 +51,14 @@ var RollupNextCmd = &cobra.Command{
 			L2PollDelay: time.Duration(cfg.Rollup.L2PollDelay) * time.Millisecond,
 			BundleSize:  cfg.Rollup.BundleSize,
 			BundleCount: cfg.Rollup.BundleCount,
+			MaxBlobSize: cfg.Rollup.MaxBlobSize,
+			MaxLatency:  time.Duration(cfg.Rollup.MaxLatency) * time.Millisecond,
 			Store:       cfg.Rollup.Store,
 			Logger:      logger.With("ctx", "Rollup"),
 			DryRun:      dryRun,
+
+			L2Confirmations: cfg.Rollup.L2Confirmations,
+			L2BlockTag:      cfg.Rollup.L2BlockTag,
 		})
 
 		// If dry run is enabled, swap out celestia with a mock celestia client.
@@ -61,13 +68,13 @@ var RollupNextCmd = &cobra.Command{
 		}
 
 		logger.Info("Rolling up next batch of L2 blocks")
-		b, err := r.CreateNextBlock()
+		b, err := r.CreateNextBlock(ctx)
 		if err != nil {
 			logger.Error("Failed to rollup next batch of L2 blocks", "err", err)
 			panic(err)
 		}
 
-		hash, err := r.Ethereum.HashHeader(b.CanonicalStateChainHeader)
+		hash, err := r.Ethereum.HashHeader(ctx, b.CanonicalStateChainHeader)
 		utils.NoErr(err)
 
 		// Print out the rollup block.
@@ -88,7 +95,7 @@ var RollupNextCmd = &cobra.Command{
 		fmt.Println(" ")
 
 		logger.Info(("Submitting rollup block to L1 rollup contract"))
-		tx, err := r.SubmitBlock(b)
+		tx, err := r.SubmitBlock(ctx, b)
 		if err != nil {
 			logger.Error("Failed to submit rollup block to L1 rollup contract", "err", err)
 			panic(err)
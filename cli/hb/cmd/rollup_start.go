@@ -20,16 +20,18 @@ var RollupStartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "start will start the rollup node",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg := config.Load()
 		logger := GetLogger(viper.GetString("log-type"))
-		ethKey := getEthKey()
+		ethSigner := GetSigner(cfg)
 
 		// is dry run enabled?
 		dryRun, _ := cmd.Flags().GetBool("dry")
 		cfg.DryRun = dryRun
 
-		n, err := node.NewFromConfig(cfg, logger, ethKey)
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
 		utils.NoErr(err)
+		defer n.Close()
 
 		// is mock data availability enabled?
 		if mockDA, _ := cmd.Flags().GetBool("mock-da"); mockDA {
@@ -37,7 +39,7 @@ var RollupStartCmd = &cobra.Command{
 		}
 
 		// Can only run rollup node if the eth key is a publisher
-		if !n.IsPublisher(ethKey) {
+		if !n.IsPublisher(ctx, ethSigner.Address()) {
 			logger.Warn("ETH_KEY is not a publisher, cannot run rollup start command")
 			return
 		}
@@ -47,9 +49,14 @@ var RollupStartCmd = &cobra.Command{
 			L2PollDelay: time.Duration(cfg.Rollup.L2PollDelay) * time.Millisecond,
 			BundleSize:  cfg.Rollup.BundleSize,
 			BundleCount: cfg.Rollup.BundleCount,
+			MaxBlobSize: cfg.Rollup.MaxBlobSize,
+			MaxLatency:  time.Duration(cfg.Rollup.MaxLatency) * time.Millisecond,
 			Store:       cfg.Rollup.Store,
 			Logger:      logger.With("ctx", "Rollup"),
 			DryRun:      dryRun,
+
+			L2Confirmations: cfg.Rollup.L2Confirmations,
+			L2BlockTag:      cfg.Rollup.L2BlockTag,
 		})
 
 		// If dry run is enabled, swap out celestia with a mock celestia client.
@@ -58,13 +65,8 @@ var RollupStartCmd = &cobra.Command{
 			r.Celestia = node.NewCelestiaMock(cfg.Celestia.Namespace)
 		}
 
-		for {
-			err = r.Run()
-			if err != nil {
-				logger.Error("Rollup.Run failed", "err", err, "retry_in", "5s")
-			}
-			time.Sleep(5 * time.Second)
-		}
+		serveStatus(ctx, n, logger, cfg, ethSigner)
 
+		runUntilStopped(ctx, logger, "Rollup.Run", r.Run)
 	},
 }
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"hummingbird/api"
+	"hummingbird/config"
+	"hummingbird/node"
+	"hummingbird/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve will start a JSON-RPC server for rollup blocks, bundles, share proofs and DA challenges",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := config.Load()
+		logger := GetLogger(viper.GetString("log-type"))
+		ethSigner := GetSigner(cfg)
+
+		n, err := node.NewFromConfig(ctx, cfg, logger, ethSigner)
+		utils.NoErr(err)
+		defer n.Close()
+
+		s, err := api.NewServer(n, &api.Opts{
+			Logger:      logger.With("ctx", "API"),
+			Addr:        cfg.API.Addr,
+			CORSOrigins: cfg.API.CORSOrigins,
+		})
+		utils.NoErr(err)
+
+		runUntilStopped(ctx, logger, "Server.Start", s.Start)
+	},
+}
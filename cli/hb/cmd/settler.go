@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"context"
+	"hummingbird/node"
+	"hummingbird/settler"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// startSettler starts a settler in the background, which settles expired
+// challenges and claims the rewards of challenges won by the given address.
+func startSettler(ctx context.Context, n *node.Node, logger *slog.Logger, address common.Address, workerDelay time.Duration) {
+	s := settler.NewSettler(n, &settler.Opts{
+		Logger:      logger.With("ctx", "Settler"),
+		WorkerDelay: workerDelay,
+		Address:     address,
+	})
+
+	go runUntilStopped(ctx, logger, "Settler.Start", s.Start)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"hummingbird/config"
+	"hummingbird/health"
+	"hummingbird/node"
+	"hummingbird/node/signer"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// serveStatus serves the node metrics at /metrics on metrics.addr, and the
+// /healthz and /readyz endpoints on health.addr, in the background. Both can
+// share an address, and either is not served if its address is empty. The
+// servers are shut down once ctx is done.
+func serveStatus(ctx context.Context, n *node.Node, logger *slog.Logger, cfg *config.Config, ethSigner signer.Signer) {
+	muxes := make(map[string]*http.ServeMux)
+	mux := func(addr string) *http.ServeMux {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+
+	if cfg.Metrics.Addr != "" && n.Metrics != nil {
+		mux(cfg.Metrics.Addr).Handle("/metrics", n.Metrics.Handler())
+	}
+	if cfg.Health.Addr != "" {
+		health.NewChecker(n, &health.Opts{
+			Logger:          logger.With("ctx", "Health"),
+			MaxHeartbeatAge: time.Duration(cfg.Health.MaxHeartbeatAge) * time.Millisecond,
+			Signer:          ethSigner,
+		}).Register(mux(cfg.Health.Addr))
+	}
+
+	for addr, m := range muxes {
+		srv := &http.Server{Addr: addr, Handler: m, ReadHeaderTimeout: 10 * time.Second}
+		context.AfterFunc(ctx, func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		})
+
+		go runUntilStopped(ctx, logger.With("addr", addr), "Status server", func(context.Context) error {
+			logger.Info("Serving status endpoints", "addr", addr)
+			return srv.ListenAndServe()
+		})
+	}
+}
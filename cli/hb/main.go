@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"hummingbird/cli/hb/cmd"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,6 +42,11 @@ var challengerCmd = &cobra.Command{
 	Short: "challenger is a command to create challenges",
 }
 
+var bridgeCmd = &cobra.Command{
+	Use:   "bridge",
+	Short: "bridge is a command to prove and finalize withdrawals from LightLink, and track deposits to it",
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgPath, "config-path", ".", "sets the config file path (default is .)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "sets the log output level (default is info)")
@@ -57,6 +66,10 @@ func main() {
 
 	// add subcommands to challenger
 	challengerCmd.AddCommand(cmd.ChallengerChallengedaCmd)
+	challengerCmd.AddCommand(cmd.ChallengerStartCmd)
+	challengerCmd.AddCommand(cmd.ChallengerChallengeHeaderCmd)
+	challengerCmd.AddCommand(cmd.ChallengerSettleHeaderCmd)
+	challengerCmd.AddCommand(cmd.ChallengerInvalidateHeaderCmd)
 
 	// add subcommands to defender
 	defenderCmd.AddCommand(cmd.DefenderProveDaCmd)
@@ -71,12 +84,27 @@ func main() {
 	rollupCmd.AddCommand(cmd.RollupNextCmd)
 	rollupCmd.AddCommand(cmd.RollupStartCmd)
 
+	// add subcommands to bridge
+	bridgeCmd.AddCommand(cmd.BridgeProveCmd)
+	bridgeCmd.AddCommand(cmd.BridgeFinalizeCmd)
+	bridgeCmd.AddCommand(cmd.BridgeStatusCmd)
+	bridgeCmd.AddCommand(cmd.BridgeRelayCmd)
+	bridgeCmd.AddCommand(cmd.BridgeRequeueCmd)
+	bridgeCmd.AddCommand(cmd.BridgeDepositsCmd)
+
 	// add all commands to root
 	rootCmd.AddCommand(rollupCmd)
 	rootCmd.AddCommand(defenderCmd)
 	rootCmd.AddCommand(challengerCmd)
+	rootCmd.AddCommand(bridgeCmd)
+	rootCmd.AddCommand(cmd.ServeCmd)
+
+	// cancel the command context on SIGINT or SIGTERM, so long running
+	// commands can finish in-flight work and close the store before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		panic(err)
 	}
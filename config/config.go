@@ -13,37 +13,82 @@ type Config struct {
 		TendermintRPC           string  `mapstructure:"tendermint_rpc"`
 		GasPrice                float64 `mapstructure:"gasPrice"`
 		GasPriceIncreasePercent int     `mapstructure:"gasPriceIncreasePercent"`
+		MaxGasPrice             float64 `mapstructure:"maxGasPrice"`
+		RetryIncreasePercent    int     `mapstructure:"retryIncreasePercent"`
 		GasAPI                  string  `mapstructure:"gasAPI"`
 		Retries                 int     `mapstructure:"retries"`
 		RetryDelay              int     `mapstructure:"retryDelay"`
+		InclusionTimeout        int     `mapstructure:"inclusionTimeout"`
 	} `mapstructure:"celestia"`
 	Ethereum struct {
-		HTTPEndpoint            string `mapstructure:"httpEndpoint"`
-		WSEndpoint              string `mapstructure:"wsEndpoint"`
-		CanonicalStateChain     string `mapstructure:"canonicalStateChain"`
-		DaOracle                string `mapstructure:"daOracle"`
-		GasPriceIncreasePercent int    `mapstructure:"gasPriceIncreasePercent"`
-		Challenge               string `mapstructure:"challenge"`
-		ChainOracle             string `mapstructure:"chainOracle"`
-		BlobstreamX             string `mapstructure:"blobstreamX"`
-		BlockTime               int    `mapstructure:"blockTime"`
-		Timeout                 int    `mapstructure:"timeout"`
+		HTTPEndpoint            string  `mapstructure:"httpEndpoint"`
+		WSEndpoint              string  `mapstructure:"wsEndpoint"`
+		CanonicalStateChain     string  `mapstructure:"canonicalStateChain"`
+		DaOracle                string  `mapstructure:"daOracle"`
+		GasPriceIncreasePercent int     `mapstructure:"gasPriceIncreasePercent"`
+		MaxFeePerGas            float64 `mapstructure:"maxFeePerGas"`
+		ReplaceAfter            int     `mapstructure:"replaceAfter"`
+		FeeBumpPercent          int     `mapstructure:"feeBumpPercent"`
+		Challenge               string  `mapstructure:"challenge"`
+		ChainOracle             string  `mapstructure:"chainOracle"`
+		BlobstreamX             string  `mapstructure:"blobstreamX"`
+		LightLinkPortal         string  `mapstructure:"lightLinkPortal"`
+		BlockTime               int     `mapstructure:"blockTime"`
+		Timeout                 int     `mapstructure:"timeout"`
 	} `mapstructure:"ethereum"`
 	LightLink struct {
-		Endpoint            string `mapstructure:"endpoint"`
-		Delay               int    `mapstructure:"delay"`
-		L2ToL1MessagePasser string `mapstructure:"l2ToL1MessagePasser"`
+		Endpoint            string   `mapstructure:"endpoint"`
+		FallbackEndpoints   []string `mapstructure:"fallbackEndpoints"`
+		Timeout             int      `mapstructure:"timeout"`
+		Retries             int      `mapstructure:"retries"`
+		Delay               int      `mapstructure:"delay"`
+		BatchSize           int      `mapstructure:"batchSize"`
+		Workers             int      `mapstructure:"workers"`
+		L2ToL1MessagePasser string   `mapstructure:"l2ToL1MessagePasser"`
 	} `mapstructure:"lightlink"`
 	Rollup struct {
-		L1PollDelay int    `mapstructure:"l1pollDelay"`
-		L2PollDelay int    `mapstructure:"l2pollDelay"`
-		BundleSize  uint64 `mapstructure:"bundleSize"`
-		BundleCount uint64 `mapstructure:"bundleCount"`
-		Store       bool   `mapstructure:"store"`
+		L1PollDelay     int    `mapstructure:"l1pollDelay"`
+		L2PollDelay     int    `mapstructure:"l2pollDelay"`
+		BundleSize      uint64 `mapstructure:"bundleSize"`
+		BundleCount     uint64 `mapstructure:"bundleCount"`
+		MaxBlobSize     uint64 `mapstructure:"maxBlobSize"`
+		MaxLatency      int    `mapstructure:"maxLatency"`
+		L2Confirmations uint64 `mapstructure:"l2Confirmations"`
+		L2BlockTag      string `mapstructure:"l2BlockTag"`
+		Store           bool   `mapstructure:"store"`
 	} `mapstructure:"rollup"`
 	Defender struct {
 		WorkerDelay int `mapstructure:"workerDelay"`
+		Workers     int `mapstructure:"workers"`
 	} `mapstructure:"defender"`
+	Challenger struct {
+		WorkerDelay int     `mapstructure:"workerDelay"`
+		Budget      float64 `mapstructure:"budget"`
+	} `mapstructure:"challenger"`
+	Relayer struct {
+		WorkerDelay int      `mapstructure:"workerDelay"`
+		StartHeight uint64   `mapstructure:"startHeight"`
+		Senders     []string `mapstructure:"senders"`
+		Targets     []string `mapstructure:"targets"`
+	} `mapstructure:"relayer"`
+	Signer struct {
+		Type         string `mapstructure:"type"`
+		Keystore     string `mapstructure:"keystore"`
+		PasswordFile string `mapstructure:"passwordFile"`
+		Endpoint     string `mapstructure:"endpoint"`
+		Address      string `mapstructure:"address"`
+	} `mapstructure:"signer"`
+	API struct {
+		Addr        string   `mapstructure:"addr"`
+		CORSOrigins []string `mapstructure:"corsOrigins"`
+	} `mapstructure:"api"`
+	Metrics struct {
+		Addr string `mapstructure:"addr"`
+	} `mapstructure:"metrics"`
+	Health struct {
+		Addr            string `mapstructure:"addr"`
+		MaxHeartbeatAge int    `mapstructure:"maxHeartbeatAge"`
+	} `mapstructure:"health"`
 	// Not typically set in config file.
 	DryRun bool `mapstructure:"dryRun,omitempty"`
 }
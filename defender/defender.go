@@ -1,12 +1,16 @@
 package defender
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hummingbird/metrics"
 	"hummingbird/node"
 	"hummingbird/utils"
 	"math"
 	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"log/slog"
@@ -14,6 +18,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/sync/errgroup"
 
 	"hummingbird/node/contracts"
 	chainOracleContract "hummingbird/node/contracts/ChainOracle.sol"
@@ -25,33 +30,70 @@ const (
 	ErrNotInCorrectState = "challenge is not in the challenger initiated state"
 )
 
+// errAwaitingCommitment is returned when a challenge can't be defended yet
+// as its Celestia height has not been committed to BlobstreamX.
+var errAwaitingCommitment = errors.New("awaiting data commitment")
+
 type Opts struct {
 	Logger      *slog.Logger
 	WorkerDelay time.Duration
+	Subscribe   bool // Subscribe to challenge events over websocket, rather than re-scanning the challenge window.
+	Workers     int  // Number of challenges defended at once, defaults to 4.
 }
 
 type Defender struct {
 	*node.Node
 	Opts *Opts
+
+	mu   sync.Mutex
+	seen map[string]bool // challenges already counted as seen
 }
 
 func NewDefender(node *node.Node, opts *Opts) *Defender {
-	return &Defender{Node: node, Opts: opts}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+
+	return &Defender{Node: node, Opts: opts, seen: make(map[string]bool)}
+}
+
+// challengeSeen counts a pending challenge as seen, once per challenge, as
+// pending challenges are retried until defended.
+func (d *Defender) challengeSeen(challengeType string, key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen[challengeType+key] {
+		return
+	}
+	d.seen[challengeType+key] = true
+	d.Metrics.Challenge(challengeType, metrics.ChallengeSeen)
 }
 
 // Start starts the defender.
-func (d *Defender) Start() error {
-	err := d.startDefender()
+func (d *Defender) Start(ctx context.Context) error {
+	if d.Opts.Subscribe {
+		return d.startWatcher(ctx)
+	}
+
+	err := d.startDefender(ctx)
 	return err
 }
 
 // Starts the main defender loop.
-func (d *Defender) startDefender() error {
+func (d *Defender) startDefender(ctx context.Context) error {
 	ticker := time.NewTicker(d.Opts.WorkerDelay)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		scanRanges, err := d.Ethereum.GetChallengeWindowBlockRanges()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		d.Heartbeat.Beat("scan")
+		scanRanges, err := d.Ethereum.GetChallengeWindowBlockRanges(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to get challenge window block ranges: %w", err)
 		}
@@ -71,26 +113,25 @@ func (d *Defender) startDefender() error {
 			if len(scanRange) != 2 {
 				return fmt.Errorf("invalid block range")
 			}
-			daChallenges, err := d.getDAChallenges(scanRange[0], scanRange[1], contracts.ChallengeDAStatusChallengerInitiated)
+			daChallenges, err := d.getDAChallenges(ctx, scanRange[0], scanRange[1], contracts.ChallengeDAStatusChallengerInitiated)
 			if err != nil {
 				return fmt.Errorf("error getting DA challenges: %w", err)
 			}
-			d.defendDAChallenges(*daChallenges)
+			d.defendDAChallenges(ctx, *daChallenges)
 
-			l2HeaderChallenges, err := d.getL2HeaderChallenges(scanRange[0], scanRange[1], contracts.ChallengeL2HeaderStatusChallengerInitiated)
+			l2HeaderChallenges, err := d.getL2HeaderChallenges(ctx, scanRange[0], scanRange[1], contracts.ChallengeL2HeaderStatusChallengerInitiated)
 			if err != nil {
 				return fmt.Errorf("error getting L2 header challenges: %w", err)
 			}
-			d.defendL2HeaderChallenges(*l2HeaderChallenges)
+			d.defendL2HeaderChallenges(ctx, *l2HeaderChallenges)
 		}
 
 		log.Info("Finished log scan for pending challenges")
 	}
-	return nil
 }
 
 // Gets DA challenge events from Challenge.sol for the given block range and status.
-func (d *Defender) getDAChallenges(startblock, endblock uint64, status uint8) (*challengeContract.ChallengeChallengeDAUpdateIterator, error) {
+func (d *Defender) getDAChallenges(ctx context.Context, startblock, endblock uint64, status uint8) (*challengeContract.ChallengeChallengeDAUpdateIterator, error) {
 	log := d.Opts.Logger.With(
 		"startblock", startblock,
 		"endblock", endblock,
@@ -100,8 +141,9 @@ func (d *Defender) getDAChallenges(startblock, endblock uint64, status uint8) (*
 	log.Debug("Starting log scan for historic pending DA challenges")
 
 	opts := &bind.FilterOpts{
-		Start: startblock,
-		End:   &endblock,
+		Start:   startblock,
+		End:     &endblock,
+		Context: ctx,
 	}
 
 	challenges, err := d.Ethereum.FilterChallengeDAUpdate(opts, nil, nil, []uint8{status})
@@ -115,19 +157,40 @@ func (d *Defender) getDAChallenges(startblock, endblock uint64, status uint8) (*
 
 // Defends multiple DA challenge events by iterating through the given iterator and attempting
 // to defend each challenge.
-func (d *Defender) defendDAChallenges(c challengeContract.ChallengeChallengeDAUpdateIterator) {
+func (d *Defender) defendDAChallenges(ctx context.Context, c challengeContract.ChallengeChallengeDAUpdateIterator) {
+	var challenges []challengeContract.ChallengeChallengeDAUpdate
 	for c.Next() {
-		err := d.defendDAChallenge(*c.Event)
-		if err != nil && err.Error() != ErrNotInCorrectState {
+		challenges = append(challenges, *c.Event)
+	}
+
+	defendConcurrently(d.Opts.Workers, challenges, func(c challengeContract.ChallengeChallengeDAUpdate) {
+		err := d.defendDAChallenge(ctx, c)
+		if err != nil && err.Error() != ErrNotInCorrectState && !errors.Is(err, errAwaitingCommitment) {
 			d.Opts.Logger.Error("error defending DA challenge", "error", err)
 		}
+	})
+}
+
+// defendConcurrently defends up to workers challenges at once, so a slow tx
+// for one challenge does not hold up defending the others before they
+// expire. The ethereum client hands out the nonces, so their txs don't
+// collide.
+func defendConcurrently[T any](workers int, challenges []T, defend func(T)) {
+	var g errgroup.Group
+	g.SetLimit(workers)
+	for _, c := range challenges {
+		g.Go(func() error {
+			defend(c)
+			return nil
+		})
 	}
+	g.Wait()
 }
 
 // Defends a DA challenge event.
-func (d *Defender) defendDAChallenge(c challengeContract.ChallengeChallengeDAUpdate) error {
+func (d *Defender) defendDAChallenge(ctx context.Context, c challengeContract.ChallengeChallengeDAUpdate) error {
 	// ensure the challenge is in the correct status to be defended
-	challengeInfo, err := d.Ethereum.GetDataRootInclusionChallenge(c.BlockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
+	challengeInfo, err := d.Ethereum.GetDataRootInclusionChallenge(ctx, c.BlockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
 	if err != nil {
 		return fmt.Errorf("error getting data root inclusion challenge: %w", err)
 	}
@@ -136,6 +199,7 @@ func (d *Defender) defendDAChallenge(c challengeContract.ChallengeChallengeDAUpd
 	}
 
 	blockHash := common.BytesToHash(c.BlockHash[:])
+	d.challengeSeen(metrics.ChallengeDA, fmt.Sprintf("%s/%d/%d", blockHash.Hex(), c.PointerIndex, c.ShareIndex))
 	statusString := contracts.DAChallengeStatusToString(c.Status)
 
 	log := d.Opts.Logger.With(
@@ -150,33 +214,34 @@ func (d *Defender) defendDAChallenge(c challengeContract.ChallengeChallengeDAUpd
 	log.Info("Attempting to defend pending DA challenge")
 
 	// attempt to defend the challenge by submitting a tx to the Challenge contract
-	tx, err := d.DefendDA(c.BlockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
+	tx, err := d.DefendDA(ctx, c.BlockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
 	if err != nil {
 		if strings.Contains(err.Error(), ErrNoDataCommitment) {
 			log.Info("Pending DA challenge is awaiting data commitment from Celestia validators, will retry later")
-			return nil
+			return errAwaitingCommitment
 		} else {
 			return fmt.Errorf("error defending DA challenge: %w", err)
 		}
 	}
 
-	_, err = d.Ethereum.Wait(tx.Hash())
+	_, err = d.Ethereum.Wait(ctx, tx.Hash())
 	if err != nil {
 		d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 		return err
 	}
 
 	log.Info("Pending DA challenge defended successfully", "tx", tx.Hash().Hex())
+	d.Metrics.Challenge(metrics.ChallengeDA, metrics.ChallengeDefended)
 
 	log.Info("Attempting to claim DA challenge reward")
 
 	// attempt to claim the challenge reward
-	txHash, err := d.ClaimDAChallengeReward(blockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
+	txHash, err := d.ClaimDAChallengeReward(ctx, blockHash, uint8(c.PointerIndex.Uint64()), c.ShareIndex)
 	if err != nil {
 		return fmt.Errorf("error claiming DA challenge reward: %w", err)
 	}
 
-	_, err = d.Ethereum.Wait(*txHash)
+	_, err = d.Ethereum.Wait(ctx, *txHash)
 	if err != nil {
 		d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 		return err
@@ -190,27 +255,27 @@ func (d *Defender) defendDAChallenge(c challengeContract.ChallengeChallengeDAUpd
 // Attempts to defend a DA challenge for the given block hash.
 //
 // Queries Celestia for a proof of data availability and submits a tx to the Challenge contract.
-func (d *Defender) DefendDA(block common.Hash, pointerIndex uint8, shareIndex uint32) (*types.Transaction, error) {
-	key, shareProof, err := d.GetDaProof(block, pointerIndex, shareIndex)
+func (d *Defender) DefendDA(ctx context.Context, block common.Hash, pointerIndex uint8, shareIndex uint32) (*types.Transaction, error) {
+	key, shareProof, err := d.GetDaProof(ctx, block, pointerIndex, shareIndex)
 	if err != nil {
 		return nil, fmt.Errorf("error getting DA proof: %w", err)
 	}
 
-	return d.Ethereum.DefendDataRootInclusion(*key, *shareProof)
+	return d.Ethereum.DefendDataRootInclusion(ctx, *key, *shareProof)
 }
 
 // Claim the data root inclusion challenge reward for the given block hash.
-func (d *Defender) ClaimDAChallengeReward(block common.Hash, pointerIndex uint8, shareIndex uint32) (*common.Hash, error) {
+func (d *Defender) ClaimDAChallengeReward(ctx context.Context, block common.Hash, pointerIndex uint8, shareIndex uint32) (*common.Hash, error) {
 	key, err := d.Ethereum.DataRootInclusionChallengeKey(nil, block, pointerIndex, shareIndex)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get data root inclusion challenge key: %w", err)
 	}
 
-	return d.Ethereum.ClaimDAChallengeReward(key)
+	return d.Ethereum.ClaimDAChallengeReward(ctx, key)
 }
 
-func (d *Defender) GetDaProof(block common.Hash, pointerIndex uint8, shareIndex uint32) (*common.Hash, *challengeContract.SharesProof, error) {
-	header, _, err := d.Node.FetchRollupBlock(block)
+func (d *Defender) GetDaProof(ctx context.Context, block common.Hash, pointerIndex uint8, shareIndex uint32) (*common.Hash, *challengeContract.SharesProof, error) {
+	header, _, err := d.Node.FetchRollupBlock(ctx, block)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error fetching rollup block: %w", err)
 	}
@@ -221,7 +286,11 @@ func (d *Defender) GetDaProof(block common.Hash, pointerIndex uint8, shareIndex
 	}
 
 	// get share index relative to the start of the share range
-	idx := new(big.Int).Sub(header.CelestiaPointers[pointerIndex].ShareStart, new(big.Int).SetUint64(uint64(shareIndex)))
+	idx := new(big.Int).Sub(new(big.Int).SetUint64(uint64(shareIndex)), header.CelestiaPointers[pointerIndex].ShareStart)
+
+	if idx.Sign() < 0 {
+		return nil, nil, fmt.Errorf("share index %d is before the start of the share range", shareIndex)
+	}
 
 	if idx.BitLen() > 64 {
 		return nil, nil, fmt.Errorf("index is too large to convert to uint64")
@@ -233,7 +302,7 @@ func (d *Defender) GetDaProof(block common.Hash, pointerIndex uint8, shareIndex
 		return nil, nil, fmt.Errorf("index is too large to convert to uint32")
 	}
 
-	shareProof, err := d.getSharesProof(block, pointerIndex, uint32(idxUint64))
+	shareProof, err := d.getSharesProof(ctx, block, pointerIndex, uint32(idxUint64))
 	if err != nil {
 		return nil, nil, fmt.Errorf("error getting shares proof: %w", err)
 	}
@@ -243,19 +312,19 @@ func (d *Defender) GetDaProof(block common.Hash, pointerIndex uint8, shareIndex
 
 // Gets the Celestia pointer for the given block hash and queries Celestia for a proof
 // of data availability.
-func (d *Defender) GetAttestationProof(block common.Hash, pointerIndex uint8) (*challengeContract.AttestationProof, error) {
-	pointers, err := d.GetDAPointer(block)
+func (d *Defender) GetAttestationProof(ctx context.Context, block common.Hash, pointerIndex uint8) (*challengeContract.AttestationProof, error) {
+	pointers, err := d.GetDAPointer(ctx, block)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Celestia pointer: %w", err)
 	}
 	if pointers == nil {
 		return nil, fmt.Errorf("no Celestia pointer found")
 	}
-	commit, err := d.Ethereum.GetBlobstreamCommitment(int64(pointers[pointerIndex].Height))
+	commit, err := d.Ethereum.GetBlobstreamCommitment(ctx, int64(pointers[pointerIndex].Height))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blobstream commitment: %w", err)
 	}
-	proof, err := d.Celestia.GetProof(pointers[pointerIndex], commit.StartBlock, commit.EndBlock, *commit.ProofNonce)
+	proof, err := d.Celestia.GetProof(ctx, pointers[pointerIndex], commit.StartBlock, commit.EndBlock, *commit.ProofNonce)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get proof: %w", err)
 	}
@@ -276,19 +345,19 @@ func (d *Defender) GetAttestationProof(block common.Hash, pointerIndex uint8) (*
 	return p, nil
 }
 
-func (d *Defender) getSharesProof(rblockHash common.Hash, pointerIndex uint8, shareIndex uint32) (*challengeContract.SharesProof, error) {
-	attestationProof, err := d.GetAttestationProof(rblockHash, pointerIndex)
+func (d *Defender) getSharesProof(ctx context.Context, rblockHash common.Hash, pointerIndex uint8, shareIndex uint32) (*challengeContract.SharesProof, error) {
+	attestationProof, err := d.GetAttestationProof(ctx, rblockHash, pointerIndex)
 	if err != nil {
 		return nil, fmt.Errorf("error proving data availability: %w", err)
 	}
 
-	pointers, err := d.GetDAPointer(rblockHash)
+	pointers, err := d.GetDAPointer(ctx, rblockHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Celestia pointers: %w", err)
 	}
 
 	pointer := pointers[pointerIndex]
-	proof, err := d.Celestia.GetShareProof(pointer, shareIndex)
+	proof, err := d.Celestia.GetShareProof(ctx, pointer, shareIndex)
 	if err != nil {
 		return nil, fmt.Errorf("error getting share proof: %w", err)
 	}
@@ -313,7 +382,7 @@ func (d *Defender) getSharesProof(rblockHash common.Hash, pointerIndex uint8, sh
 }
 
 // Gets L2 Header challenge events from Challenge.sol for the given block range and status.
-func (d *Defender) getL2HeaderChallenges(startblock, endblock uint64, status uint8) (*challengeContract.ChallengeL2HeaderChallengeUpdateIterator, error) {
+func (d *Defender) getL2HeaderChallenges(ctx context.Context, startblock, endblock uint64, status uint8) (*challengeContract.ChallengeL2HeaderChallengeUpdateIterator, error) {
 	log := d.Opts.Logger.With(
 		"startblock", startblock,
 		"endblock", endblock,
@@ -323,8 +392,9 @@ func (d *Defender) getL2HeaderChallenges(startblock, endblock uint64, status uin
 	log.Debug("Starting log scan for historic pending L2 header challenges")
 
 	opts := &bind.FilterOpts{
-		Start: startblock,
-		End:   &endblock,
+		Start:   startblock,
+		End:     &endblock,
+		Context: ctx,
 	}
 
 	challenges, err := d.Ethereum.FilterL2HeaderChallengeUpdate(opts, nil, nil, []uint8{status})
@@ -338,25 +408,31 @@ func (d *Defender) getL2HeaderChallenges(startblock, endblock uint64, status uin
 
 // Defends multiple L2 header challenge events by iterating through the given iterator and attempting to
 // defend each challenge.
-func (d *Defender) defendL2HeaderChallenges(c challengeContract.ChallengeL2HeaderChallengeUpdateIterator) {
+func (d *Defender) defendL2HeaderChallenges(ctx context.Context, c challengeContract.ChallengeL2HeaderChallengeUpdateIterator) {
+	var challenges []challengeContract.ChallengeL2HeaderChallengeUpdate
 	for c.Next() {
-		err := d.defendL2HeaderChallenge(*c.Event)
-		if err != nil && err.Error() != ErrNotInCorrectState {
+		challenges = append(challenges, *c.Event)
+	}
+
+	defendConcurrently(d.Opts.Workers, challenges, func(c challengeContract.ChallengeL2HeaderChallengeUpdate) {
+		err := d.defendL2HeaderChallenge(ctx, c)
+		if err != nil && err.Error() != ErrNotInCorrectState && !errors.Is(err, errAwaitingCommitment) {
 			d.Opts.Logger.Error("error defending L2 header challenge", "error", err)
 		}
-	}
+	})
 }
 
 // Defends an L2 header challenge event.
-func (d *Defender) defendL2HeaderChallenge(c challengeContract.ChallengeL2HeaderChallengeUpdate) error {
+func (d *Defender) defendL2HeaderChallenge(ctx context.Context, c challengeContract.ChallengeL2HeaderChallengeUpdate) error {
 	// ensure the challenge is in the correct status to be defended
-	challengeInfo, err := d.Ethereum.GetL2HeaderChallenge(c.ChallengeHash)
+	challengeInfo, err := d.Ethereum.GetL2HeaderChallenge(ctx, c.ChallengeHash)
 	if err != nil {
 		return fmt.Errorf("error getting L2 header challenge: %w", err)
 	}
 	if challengeInfo.Status != contracts.ChallengeL2HeaderStatusChallengerInitiated {
 		return fmt.Errorf(ErrNotInCorrectState)
 	}
+	d.challengeSeen(metrics.ChallengeL2Header, common.Hash(c.ChallengeHash).Hex())
 
 	rblock := common.BytesToHash(c.Rblock[:])
 	l2BlockNum := c.L2Number
@@ -369,33 +445,34 @@ func (d *Defender) defendL2HeaderChallenge(c challengeContract.ChallengeL2Header
 	)
 	log.Info("Attempting to defend pending L2 header challenge")
 
-	tx, err := d.DefendL2Header(rblock, l2BlockNum)
+	tx, err := d.DefendL2Header(ctx, rblock, l2BlockNum)
 	if err != nil {
 		if strings.Contains(err.Error(), ErrNoDataCommitment) {
 			log.Info("Pending L2 header challenge is awaiting data commitment from Celestia validators, will retry later")
-			return nil
+			return errAwaitingCommitment
 		} else {
 			return fmt.Errorf("error defending L2 header challenge: %w", err)
 		}
 	}
 
-	_, err = d.Ethereum.Wait(tx.Hash())
+	_, err = d.Ethereum.Wait(ctx, tx.Hash())
 	if err != nil {
 		d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 		return err
 	}
 
 	log.Info("Pending L2 header challenge defended successfully", "tx", tx.Hash().Hex())
+	d.Metrics.Challenge(metrics.ChallengeL2Header, metrics.ChallengeDefended)
 
 	log.Info("Attempting to claim L2 header challenge reward")
 
 	// attempt to claim the challenge reward
-	txHash, err := d.Ethereum.ClaimL2HeaderChallengeReward(c.ChallengeHash)
+	txHash, err := d.Ethereum.ClaimL2HeaderChallengeReward(ctx, c.ChallengeHash)
 	if err != nil {
 		return fmt.Errorf("error claiming L2 header challenge reward: %w", err)
 	}
 
-	_, err = d.Ethereum.Wait(*txHash)
+	_, err = d.Ethereum.Wait(ctx, *txHash)
 	if err != nil {
 		d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 		return err
@@ -407,20 +484,20 @@ func (d *Defender) defendL2HeaderChallenge(c challengeContract.ChallengeL2Header
 }
 
 // Claims the reward for the given L2 header challenge hash.
-func (d *Defender) ClaimL2HeaderChallengeReward(challengeHash common.Hash) (*common.Hash, error) {
-	return d.Ethereum.ClaimL2HeaderChallengeReward(challengeHash)
+func (d *Defender) ClaimL2HeaderChallengeReward(ctx context.Context, challengeHash common.Hash) (*common.Hash, error) {
+	return d.Ethereum.ClaimL2HeaderChallengeReward(ctx, challengeHash)
 }
 
 // Defends an L2 header challenge by attempting to submit a header proof to the Challenge.sol contract.
-func (d *Defender) DefendL2Header(rblock common.Hash, l2BlockNum *big.Int) (*types.Transaction, error) {
+func (d *Defender) DefendL2Header(ctx context.Context, rblock common.Hash, l2BlockNum *big.Int) (*types.Transaction, error) {
 	// 1. Get the challenge key
-	challengeHash, err := d.Ethereum.GetL2HeaderChallengeHash(rblock, l2BlockNum)
+	challengeHash, err := d.Ethereum.GetL2HeaderChallengeHash(ctx, rblock, l2BlockNum)
 	if err != nil {
 		return nil, fmt.Errorf("error getting challenge hash: %w", err)
 	}
 
 	// 2. Get the challenge
-	challenge, err := d.Ethereum.GetL2HeaderChallenge(challengeHash)
+	challenge, err := d.Ethereum.GetL2HeaderChallenge(ctx, challengeHash)
 	if err != nil {
 		return nil, fmt.Errorf("error getting challenge: %w", err)
 	}
@@ -429,41 +506,41 @@ func (d *Defender) DefendL2Header(rblock common.Hash, l2BlockNum *big.Int) (*typ
 	}
 
 	// 3. Get the hashes of the header and previous header
-	l2Block, err := d.LightLink.GetBlock(l2BlockNum.Uint64())
+	l2Block, err := d.LightLink.GetBlock(ctx, l2BlockNum.Uint64())
 	if err != nil {
 		return nil, fmt.Errorf("error getting block from l2: %w", err)
 	}
 	l2BlockHash := utils.HashWithoutExtraData(l2Block)
 
-	l2PrevBlock, err := d.LightLink.GetBlock(l2BlockNum.Uint64() - 1)
+	l2PrevBlock, err := d.LightLink.GetBlock(ctx, l2BlockNum.Uint64()-1)
 	if err != nil {
 		return nil, fmt.Errorf("error getting previous block from l2: %w", err)
 	}
 	l2PrevBlockHash := utils.HashWithoutExtraData(l2PrevBlock)
 
 	// 4. Provide the headers
-	tx, err := d.ProvideL2Header(challenge.Header.Rblock, l2BlockHash, false)
+	tx, err := d.ProvideL2Header(ctx, challenge.Header.Rblock, l2BlockHash, false)
 	if err != nil {
 		return nil, fmt.Errorf("error providing header: %w", err)
 	}
 
 	if tx != nil {
 		d.Opts.Logger.Info("Provided header", "tx", tx.Hash().Hex(), "rblock", rblock.Hex(), "header", l2BlockHash.Hex())
-		_, err = d.Ethereum.Wait(tx.Hash())
+		_, err = d.Ethereum.Wait(ctx, tx.Hash())
 		if err != nil {
 			d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 			return nil, err
 		}
 	}
 
-	tx, err = d.ProvideL2Header(challenge.PrevHeader.Rblock, l2PrevBlockHash, false)
+	tx, err = d.ProvideL2Header(ctx, challenge.PrevHeader.Rblock, l2PrevBlockHash, false)
 	if err != nil {
 		return nil, fmt.Errorf("error providing previous header: %w", err)
 	}
 
 	if tx != nil {
 		d.Opts.Logger.Info("Provided previous header", "tx", tx.Hash().Hex(), "rblock", rblock.Hex(), "header", l2PrevBlockHash.Hex())
-		_, err = d.Ethereum.Wait(tx.Hash())
+		_, err = d.Ethereum.Wait(ctx, tx.Hash())
 		if err != nil {
 			d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
 			return nil, err
@@ -471,7 +548,7 @@ func (d *Defender) DefendL2Header(rblock common.Hash, l2BlockNum *big.Int) (*typ
 	}
 
 	// 5. Defend the challenge
-	tx, err = d.Ethereum.DefendL2Header(challengeHash, l2BlockHash, l2PrevBlockHash)
+	tx, err = d.Ethereum.DefendL2Header(ctx, challengeHash, l2BlockHash, l2PrevBlockHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to defend l2 header challenge: %w", err)
 	}
@@ -479,29 +556,53 @@ func (d *Defender) DefendL2Header(rblock common.Hash, l2BlockNum *big.Int) (*typ
 	return tx, nil
 }
 
-// Loads an L2 header from Celestia into the chainOracle.
-func (d *Defender) ProvideL2Header(rblock common.Hash, l2Block common.Hash, skipShares bool) (*types.Transaction, error) {
-	// check if the header is already provided
-	headerProvided, _ := d.Ethereum.AlreadyProvidedHeader(l2Block)
-	if headerProvided {
-		d.Opts.Logger.Info("Header or previous header already provided", "block", rblock.Hex(), "header", l2Block.Hex())
-		return nil, nil
-	}
+// L2ShareProof is a proof that an L2 header or tx is in the shares of a
+// rollup block, in the form ChainOracle.sol accepts it.
+type L2ShareProof struct {
+	PointerIndex uint8
+	SharesProof  *chainOracleContract.SharesProof
+	Ranges       []chainOracleContract.ChainOracleShareRange // ranges of the header or tx within the shares
+}
+
+// GetL2HeaderShareProof returns a proof the L2 header is in the shares of
+// the rollup block.
+func (d *Defender) GetL2HeaderShareProof(ctx context.Context, rblock common.Hash, l2Block common.Hash) (*L2ShareProof, error) {
+	return d.getL2ShareProof(ctx, rblock, func(bundles []*node.Bundle) (*node.SharePointer, uint8, error) {
+		sharePointer, pointerIndex, err := node.FindHeaderSharesInBundles(bundles, l2Block, d.Namespace())
+		if err != nil {
+			return nil, 0, fmt.Errorf("error finding header shares in the bundle: %w", err)
+		}
+		return sharePointer, pointerIndex, nil
+	})
+}
+
+// GetL2TxShareProof returns a proof the L2 tx is in the shares of the
+// rollup block.
+func (d *Defender) GetL2TxShareProof(ctx context.Context, rblock common.Hash, l2Tx common.Hash) (*L2ShareProof, error) {
+	return d.getL2ShareProof(ctx, rblock, func(bundles []*node.Bundle) (*node.SharePointer, uint8, error) {
+		sharePointer, pointerIndex, err := node.FindTxSharesInBundles(bundles, l2Tx, d.Namespace())
+		if err != nil {
+			return nil, 0, fmt.Errorf("error finding tx shares in the bundle: %w", err)
+		}
+		return sharePointer, pointerIndex, nil
+	})
+}
 
+func (d *Defender) getL2ShareProof(ctx context.Context, rblock common.Hash, find func(bundles []*node.Bundle) (*node.SharePointer, uint8, error)) (*L2ShareProof, error) {
 	// Download the rollup block and bundle from L1 and
 	// Celestia
-	rheader, bundles, err := d.Node.FetchRollupBlock(rblock)
+	rheader, bundles, err := d.Node.FetchRollupBlock(ctx, rblock)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching rollup block: %w", err)
 	}
 
-	sharePointer, pointerIndex, err := node.FindHeaderSharesInBundles(bundles, l2Block, d.Namespace())
+	sharePointer, pointerIndex, err := find(bundles)
 	if err != nil {
-		return nil, fmt.Errorf("error finding header shares in the bundle: %w", err)
+		return nil, err
 	}
 
 	// Get proof the shares are in the bundle
-	shareProof, err := d.Celestia.GetSharesProof(&node.CelestiaPointer{
+	shareProof, err := d.Celestia.GetSharesProof(ctx, &node.CelestiaPointer{
 		Height:     rheader.CelestiaPointers[pointerIndex].Height,
 		ShareStart: rheader.CelestiaPointers[pointerIndex].ShareStart.Uint64(),
 		ShareLen:   uint64(rheader.CelestiaPointers[pointerIndex].ShareLen),
@@ -511,14 +612,11 @@ func (d *Defender) ProvideL2Header(rblock common.Hash, l2Block common.Hash, skip
 	}
 
 	// Get proof the data is available
-	celProof, err := d.GetAttestationProof(rblock, pointerIndex)
+	celProof, err := d.GetAttestationProof(ctx, rblock, pointerIndex)
 	if err != nil {
 		return nil, fmt.Errorf("error proving data availability: %w", err)
 	}
 
-	// check if the shares are already provided
-	provided, _ := d.Ethereum.AlreadyProvidedShares(rblock, shareProof.Data)
-
 	attestationProof := chainOracleContract.AttestationProof{
 		TupleRootNonce: celProof.TupleRootNonce,
 		Tuple: chainOracleContract.DataRootTuple{
@@ -537,21 +635,6 @@ func (d *Defender) ProvideL2Header(rblock common.Hash, l2Block common.Hash, skip
 		return nil, fmt.Errorf("error creating share proof: %w", err)
 	}
 
-	// Provide the shares
-	if !skipShares && !provided {
-		tx, err := d.Ethereum.ProvideShares(rblock, pointerIndex, sp)
-		if err != nil {
-			return nil, fmt.Errorf("error providing shares: %w", err)
-		}
-		d.Opts.Logger.Info("Provided shares", "tx", tx.Hash().Hex(), "block", rblock.Hex(), "shares", len(shareProof.Data))
-
-		_, err = d.Ethereum.Wait(tx.Hash())
-		if err != nil {
-			d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
-			return nil, err
-		}
-	}
-
 	ranges := make([]chainOracleContract.ChainOracleShareRange, len(sharePointer.Ranges))
 	for i, r := range sharePointer.Ranges {
 		ranges[i] = chainOracleContract.ChainOracleShareRange{
@@ -560,82 +643,68 @@ func (d *Defender) ProvideL2Header(rblock common.Hash, l2Block common.Hash, skip
 		}
 	}
 
-	// Finally, provide the header
-	return d.Ethereum.ProvideHeader(rblock, shareProof.Data, ranges)
+	return &L2ShareProof{PointerIndex: pointerIndex, SharesProof: sp, Ranges: ranges}, nil
 }
 
-func (d *Defender) ProvideL2Tx(rblock common.Hash, l2Tx common.Hash, skipShares bool) (*types.Transaction, error) {
-	// Download the rollup block and bundle from L1 and
-	// Celestia
-	rheader, bundles, err := d.Node.FetchRollupBlock(rblock)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching rollup block: %w", err)
+// Loads an L2 header from Celestia into the chainOracle.
+func (d *Defender) ProvideL2Header(ctx context.Context, rblock common.Hash, l2Block common.Hash, skipShares bool) (*types.Transaction, error) {
+	// check if the header is already provided
+	headerProvided, _ := d.Ethereum.AlreadyProvidedHeader(ctx, l2Block)
+	if headerProvided {
+		d.Opts.Logger.Info("Header or previous header already provided", "block", rblock.Hex(), "header", l2Block.Hex())
+		return nil, nil
 	}
 
-	// Find the transaction in the bundle
-	sharePointer, pointerIndex, err := node.FindTxSharesInBundles(bundles, l2Tx, d.Namespace())
+	proof, err := d.GetL2HeaderShareProof(ctx, rblock, l2Block)
 	if err != nil {
-		return nil, fmt.Errorf("error finding tx shares in the bundle: %w", err)
+		return nil, err
 	}
 
-	// Get proof the shares are in the bundle
-	shareProof, err := d.Celestia.GetSharesProof(&node.CelestiaPointer{
-		Height:     rheader.CelestiaPointers[pointerIndex].Height,
-		ShareStart: rheader.CelestiaPointers[pointerIndex].ShareStart.Uint64(),
-		ShareLen:   uint64(rheader.CelestiaPointers[pointerIndex].ShareLen),
-	}, sharePointer)
-	if err != nil {
-		return nil, fmt.Errorf("error getting share proof: %w", err)
-	}
+	// check if the shares are already provided
+	provided, _ := d.Ethereum.AlreadyProvidedShares(ctx, rblock, proof.SharesProof.Data)
 
-	// Get proof the data is available
-	celProof, err := d.GetAttestationProof(rblock, pointerIndex)
-	if err != nil {
-		return nil, fmt.Errorf("error proving data availability: %w", err)
+	// Provide the shares
+	if !skipShares && !provided {
+		if err := d.provideShares(ctx, rblock, proof); err != nil {
+			return nil, err
+		}
 	}
 
-	attestationProof := chainOracleContract.AttestationProof{
-		TupleRootNonce: celProof.TupleRootNonce,
-		Tuple: chainOracleContract.DataRootTuple{
-			Height:   celProof.Tuple.Height,
-			DataRoot: celProof.Tuple.DataRoot,
-		},
-		Proof: chainOracleContract.BinaryMerkleProof{
-			SideNodes: celProof.Proof.SideNodes,
-			Key:       celProof.Proof.Key,
-			NumLeaves: celProof.Proof.NumLeaves,
-		},
-	}
+	// Finally, provide the header
+	return d.Ethereum.ProvideHeader(ctx, rblock, proof.SharesProof.Data, proof.Ranges)
+}
 
-	sp, err := contracts.NewShareProof(shareProof, attestationProof)
+func (d *Defender) ProvideL2Tx(ctx context.Context, rblock common.Hash, l2Tx common.Hash, skipShares bool) (*types.Transaction, error) {
+	proof, err := d.GetL2TxShareProof(ctx, rblock, l2Tx)
 	if err != nil {
-		return nil, fmt.Errorf("error creating share proof: %w", err)
+		return nil, err
 	}
 
 	// Provide the shares
 	if !skipShares {
-
-		tx, err := d.Ethereum.ProvideShares(rblock, pointerIndex, sp)
-		if err != nil {
-			return nil, fmt.Errorf("error providing shares: %w", err)
-		}
-		d.Opts.Logger.Info("Provided shares", "tx", tx.Hash().Hex(), "block", rblock.Hex(), "shares", len(shareProof.Data))
-
-		_, err = d.Ethereum.Wait(tx.Hash())
-		if err != nil {
-			d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
+		if err := d.provideShares(ctx, rblock, proof); err != nil {
 			return nil, err
 		}
 	}
 
-	ranges := make([]chainOracleContract.ChainOracleShareRange, len(sharePointer.Ranges))
-	for i, r := range sharePointer.Ranges {
-		ranges[i] = chainOracleContract.ChainOracleShareRange{
-			Start: big.NewInt(int64(r.Start)),
-			End:   big.NewInt(int64(r.End)),
-		}
+	// Finally, provide the transaction
+	return d.Ethereum.ProvideLegacyTx(ctx, rblock, proof.SharesProof.Data, proof.Ranges)
+}
+
+// provideShares provides the shares of the proof to the chainOracle and
+// waits for the tx to be mined.
+func (d *Defender) provideShares(ctx context.Context, rblock common.Hash, proof *L2ShareProof) error {
+	tx, err := d.Ethereum.ProvideShares(ctx, rblock, proof.PointerIndex, proof.SharesProof)
+	if err != nil {
+		return fmt.Errorf("error providing shares: %w", err)
 	}
+	d.Opts.Logger.Info("Provided shares", "tx", tx.Hash().Hex(), "block", rblock.Hex(), "shares", len(proof.SharesProof.Data))
 
-	// Finally, provide the transaction
-	return d.Ethereum.ProvideLegacyTx(rblock, shareProof.Data, ranges)
+	_, err = d.Ethereum.Wait(ctx, tx.Hash())
+	if err != nil {
+		d.Opts.Logger.Error("error waiting for tx", "tx", tx.Hash().Hex(), "error", err)
+		return err
+	}
+
+	return nil
 }
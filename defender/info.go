@@ -1,11 +1,12 @@
 package defender
 
 import (
+	"context"
 	"hummingbird/node/contracts"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
-func (d *Defender) InfoDA(block common.Hash, pointer uint8, share uint32) (contracts.ChallengeDaInfo, error) {
-	return d.Ethereum.GetDataRootInclusionChallenge(block, pointer, share)
+func (d *Defender) InfoDA(ctx context.Context, block common.Hash, pointer uint8, share uint32) (contracts.ChallengeDaInfo, error) {
+	return d.Ethereum.GetDataRootInclusionChallenge(ctx, block, pointer, share)
 }
@@ -0,0 +1,375 @@
+package defender
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+
+	challengeContract "hummingbird/node/contracts/Challenge.sol"
+)
+
+// lastScannedKey is the store key for the last L1 block scanned for challenges.
+var lastScannedKey = []byte("defender_last_scanned")
+
+// maxScanRange is the max number of L1 blocks to filter logs for at once, to
+// avoid hitting eth_getLogs range limits.
+const maxScanRange = uint64(10000)
+
+// daChallengeID identifies a DA challenge.
+type daChallengeID struct {
+	blockHash    [32]byte
+	pointerIndex uint64
+	shareIndex   uint32
+}
+
+// watcher holds the state of the event-driven defender loop.
+//
+// Pending challenges are only held in memory, so the last scanned block is
+// persisted no further than just before the oldest unresolved challenge. A
+// restarted defender then scans for it again.
+type watcher struct {
+	lastScanned  uint64
+	mu           sync.Mutex // guards the pending challenges, defended concurrently
+	daPending    map[daChallengeID]challengeContract.ChallengeChallengeDAUpdate
+	l2Pending    map[common.Hash]challengeContract.ChallengeL2HeaderChallengeUpdate
+	defending    map[any]bool // keys of the challenges being defended right now
+	workers      sync.WaitGroup
+	daEvents     chan *challengeContract.ChallengeChallengeDAUpdate
+	l2Events     chan *challengeContract.ChallengeL2HeaderChallengeUpdate
+	subscription event.Subscription
+}
+
+// begin marks the challenge as pending and being defended. It returns false
+// if the challenge is already being defended.
+func (w *watcher) begin(key any, pending func()) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.defending[key] {
+		return false
+	}
+	w.defending[key] = true
+	pending()
+	return true
+}
+
+// checkpoint returns the last scanned block that is safe to persist, which is
+// the block before the oldest unresolved challenge if that is earlier.
+func (w *watcher) checkpoint() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	checkpoint := w.lastScanned
+	unresolved := func(block uint64) {
+		if block > 0 && block-1 < checkpoint {
+			checkpoint = block - 1
+		}
+	}
+	for _, c := range w.daPending {
+		unresolved(c.Raw.BlockNumber)
+	}
+	for _, c := range w.l2Pending {
+		unresolved(c.Raw.BlockNumber)
+	}
+	return checkpoint
+}
+
+// spawn runs the defence on a worker goroutine, so a slow defence does not
+// hold up receiving other challenges.
+func (w *watcher) spawn(defend func()) {
+	w.workers.Add(1)
+	go func() {
+		defer w.workers.Done()
+		defend()
+	}()
+}
+
+// Starts the event-driven defender loop.
+//
+// New challenges are received over a websocket subscription and defended as
+// soon as they are seen. Every WorkerDelay the logs since the last scanned
+// L1 block are also filtered, which backfills any challenges opened while
+// the defender was offline, and keeps the defender working by polling if the
+// subscription drops. Challenges that can't be defended yet are retried on
+// each pass.
+func (d *Defender) startWatcher(ctx context.Context) error {
+	w := &watcher{
+		daPending: make(map[daChallengeID]challengeContract.ChallengeChallengeDAUpdate),
+		l2Pending: make(map[common.Hash]challengeContract.ChallengeL2HeaderChallengeUpdate),
+		defending: make(map[any]bool),
+		daEvents:  make(chan *challengeContract.ChallengeChallengeDAUpdate, 32),
+		l2Events:  make(chan *challengeContract.ChallengeL2HeaderChallengeUpdate, 32),
+	}
+
+	// defend challenges received over the subscription as they arrive, apart
+	// from the scan so the subscription does not back up while it runs
+	stop := make(chan struct{})
+	var receiving sync.WaitGroup
+	receiving.Add(1)
+	go func() {
+		defer receiving.Done()
+		d.receiveChallenges(ctx, w, stop)
+	}()
+
+	defer func() {
+		if w.subscription != nil {
+			w.subscription.Unsubscribe()
+		}
+		close(stop)
+		receiving.Wait()
+		w.workers.Wait()
+	}()
+
+	var err error
+	w.lastScanned, err = d.loadLastScanned(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load last scanned block: %w", err)
+	}
+
+	ticker := time.NewTicker(d.Opts.WorkerDelay)
+	defer ticker.Stop()
+
+	for {
+		// 1. subscribe to new challenges, before scanning so none are missed
+		if w.subscription == nil {
+			w.subscription, err = d.watchChallenges(ctx, w)
+			if err != nil {
+				d.Opts.Logger.Warn("Failed to subscribe to challenge events, polling instead", "error", err, "retry_in", d.Opts.WorkerDelay)
+			} else {
+				d.Opts.Logger.Info("Subscribed to challenge events")
+			}
+		}
+
+		// 2. scan for challenges opened since the last scanned block
+		d.Heartbeat.Beat("scan")
+		if err := d.scanSinceLastScanned(ctx, w); err != nil {
+			return err
+		}
+
+		// 3. retry challenges that could not be defended yet
+		d.retryPending(ctx, w)
+		d.saveCheckpoint(ctx, w)
+
+		// 4. wait for the next pass, new challenges are handled as they
+		// arrive by receiveChallenges
+		var subErr <-chan error
+		if w.subscription != nil {
+			subErr = w.subscription.Err()
+		}
+
+	wait:
+		for {
+			select {
+			case err := <-subErr:
+				d.Opts.Logger.Warn("Challenge event subscription dropped, polling instead", "error", err, "retry_in", d.Opts.WorkerDelay)
+				w.subscription.Unsubscribe()
+				w.subscription = nil
+				subErr = nil
+			case <-ticker.C:
+				break wait
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// receiveChallenges defends each challenge received over the subscription on
+// a worker goroutine, until stop is closed.
+func (d *Defender) receiveChallenges(ctx context.Context, w *watcher, stop <-chan struct{}) {
+	for {
+		select {
+		case ev := <-w.daEvents:
+			w.spawn(func() { d.handleDAChallenge(ctx, w, *ev) })
+		case ev := <-w.l2Events:
+			w.spawn(func() { d.handleL2HeaderChallenge(ctx, w, *ev) })
+		case <-stop:
+			return
+		}
+	}
+}
+
+// watchChallenges subscribes to new DA and L2 header challenges.
+func (d *Defender) watchChallenges(ctx context.Context, w *watcher) (event.Subscription, error) {
+	daSub, err := d.Ethereum.WatchChallengeDAUpdate(&bind.WatchOpts{Context: ctx}, w.daEvents, nil, nil, []uint8{contracts.ChallengeDAStatusChallengerInitiated})
+	if err != nil {
+		return nil, err
+	}
+
+	l2Sub, err := d.Ethereum.WatchL2HeaderChallengeUpdate(&bind.WatchOpts{Context: ctx}, w.l2Events, nil, nil, []uint8{contracts.ChallengeL2HeaderStatusChallengerInitiated})
+	if err != nil {
+		daSub.Unsubscribe()
+		return nil, err
+	}
+
+	return event.JoinSubscriptions(daSub, l2Sub), nil
+}
+
+// scanSinceLastScanned filters the logs from the last scanned L1 block, or
+// the start of the challenge window if that is later, up to the current
+// block and handles any challenges found.
+func (d *Defender) scanSinceLastScanned(ctx context.Context, w *watcher) error {
+	scanRanges, err := d.Ethereum.GetChallengeWindowBlockRanges(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get challenge window block ranges: %w", err)
+	}
+
+	start := scanRanges[0][0]
+	end := scanRanges[len(scanRanges)-1][1]
+	if w.lastScanned >= start {
+		start = w.lastScanned + 1
+	}
+	if start > end {
+		return nil
+	}
+
+	log := d.Opts.Logger.With("startBlock", start, "endBlock", end, "totalBlocks", end-start+1)
+	log.Debug("Scanning logs for pending challenges since last scanned block")
+
+	for from := start; from <= end; from += maxScanRange {
+		to := min(from+maxScanRange-1, end)
+
+		// the range is only marked scanned if every challenge in it was read
+		daChallenges, err := d.getDAChallenges(ctx, from, to, contracts.ChallengeDAStatusChallengerInitiated)
+		if err != nil {
+			return fmt.Errorf("error getting DA challenges: %w", err)
+		}
+		var das []challengeContract.ChallengeChallengeDAUpdate
+		for daChallenges.Next() {
+			das = append(das, *daChallenges.Event)
+		}
+		err = daChallenges.Error()
+		daChallenges.Close()
+		if err != nil {
+			return fmt.Errorf("error getting DA challenges: %w", err)
+		}
+		defendConcurrently(d.Opts.Workers, das, func(c challengeContract.ChallengeChallengeDAUpdate) { d.handleDAChallenge(ctx, w, c) })
+
+		l2HeaderChallenges, err := d.getL2HeaderChallenges(ctx, from, to, contracts.ChallengeL2HeaderStatusChallengerInitiated)
+		if err != nil {
+			return fmt.Errorf("error getting L2 header challenges: %w", err)
+		}
+		var l2s []challengeContract.ChallengeL2HeaderChallengeUpdate
+		for l2HeaderChallenges.Next() {
+			l2s = append(l2s, *l2HeaderChallenges.Event)
+		}
+		err = l2HeaderChallenges.Error()
+		l2HeaderChallenges.Close()
+		if err != nil {
+			return fmt.Errorf("error getting L2 header challenges: %w", err)
+		}
+		defendConcurrently(d.Opts.Workers, l2s, func(c challengeContract.ChallengeL2HeaderChallengeUpdate) { d.handleL2HeaderChallenge(ctx, w, c) })
+
+		w.lastScanned = to
+		d.saveCheckpoint(ctx, w)
+	}
+
+	return nil
+}
+
+// saveCheckpoint persists the last scanned block, held back to before the
+// oldest unresolved challenge.
+func (d *Defender) saveCheckpoint(ctx context.Context, w *watcher) {
+	if err := d.saveLastScanned(ctx, w.checkpoint()); err != nil {
+		d.Opts.Logger.Warn("Failed to save last scanned block", "error", err)
+	}
+}
+
+// handleDAChallenge defends the challenge, which stays pending to be retried
+// if it could not be defended.
+func (d *Defender) handleDAChallenge(ctx context.Context, w *watcher, c challengeContract.ChallengeChallengeDAUpdate) {
+	key := daChallengeID{c.BlockHash, c.PointerIndex.Uint64(), c.ShareIndex}
+	if !w.begin(key, func() { w.daPending[key] = c }) {
+		return
+	}
+
+	err := d.defendDAChallenge(ctx, c)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.defending, key)
+	switch {
+	case err == nil, err.Error() == ErrNotInCorrectState:
+		delete(w.daPending, key)
+	case !errors.Is(err, errAwaitingCommitment):
+		d.Opts.Logger.Error("error defending DA challenge, will retry later", "error", err)
+	}
+}
+
+// handleL2HeaderChallenge defends the challenge, which stays pending to be
+// retried if it could not be defended.
+func (d *Defender) handleL2HeaderChallenge(ctx context.Context, w *watcher, c challengeContract.ChallengeL2HeaderChallengeUpdate) {
+	key := common.Hash(c.ChallengeHash)
+	if !w.begin(key, func() { w.l2Pending[key] = c }) {
+		return
+	}
+
+	err := d.defendL2HeaderChallenge(ctx, c)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.defending, key)
+	switch {
+	case err == nil, err.Error() == ErrNotInCorrectState:
+		delete(w.l2Pending, key)
+	case !errors.Is(err, errAwaitingCommitment):
+		d.Opts.Logger.Error("error defending L2 header challenge, will retry later", "error", err)
+	}
+}
+
+// retryPending retries defending every queued challenge.
+func (d *Defender) retryPending(ctx context.Context, w *watcher) {
+	w.mu.Lock()
+	das := slices.Collect(maps.Values(w.daPending))
+	l2s := slices.Collect(maps.Values(w.l2Pending))
+	w.mu.Unlock()
+
+	if len(das)+len(l2s) > 0 {
+		d.Opts.Logger.Info("Retrying pending challenges", "da", len(das), "l2Header", len(l2s))
+	}
+
+	defendConcurrently(d.Opts.Workers, das, func(c challengeContract.ChallengeChallengeDAUpdate) { d.handleDAChallenge(ctx, w, c) })
+	defendConcurrently(d.Opts.Workers, l2s, func(c challengeContract.ChallengeL2HeaderChallengeUpdate) { d.handleL2HeaderChallenge(ctx, w, c) })
+}
+
+// loadLastScanned returns the last L1 block scanned for challenges, or 0 if
+// there is no store or the defender has not run before.
+func (d *Defender) loadLastScanned(ctx context.Context) (uint64, error) {
+	if d.Store == nil {
+		return 0, nil
+	}
+
+	buf, err := d.Store.Get(ctx, lastScannedKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("invalid last scanned block")
+	}
+
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// saveLastScanned persists the last L1 block scanned for challenges.
+func (d *Defender) saveLastScanned(ctx context.Context, block uint64) error {
+	if d.Store == nil {
+		return nil
+	}
+
+	return d.Store.Put(ctx, lastScannedKey, binary.BigEndian.AppendUint64(nil, block))
+}
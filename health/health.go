@@ -0,0 +1,226 @@
+// Package health serves /healthz and /readyz for orchestrators. /healthz
+// reports whether the main loop is still making progress, and /readyz
+// whether every backend the node depends on is reachable.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"hummingbird/node"
+	"hummingbird/node/signer"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// probeKey is read from the store to check it is open.
+var probeKey = []byte("health_probe")
+
+type Opts struct {
+	Logger          *slog.Logger
+	MaxHeartbeatAge time.Duration // /healthz fails once the main loop has not made progress for this long. If 0, only liveness is checked.
+	ProbeTimeout    time.Duration // Max time to wait for each /readyz probe. Defaults to 5s.
+	Signer          signer.Signer // Signer to report the publisher and defender roles of. If nil, roles are not reported.
+}
+
+type Checker struct {
+	*node.Node
+	Opts *Opts
+}
+
+func NewChecker(n *node.Node, opts *Opts) *Checker {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.ProbeTimeout == 0 {
+		opts.ProbeTimeout = 5 * time.Second
+	}
+
+	return &Checker{Node: n, Opts: opts}
+}
+
+// Register adds the /healthz and /readyz handlers to the mux.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		status := c.Health()
+		writeJSON(w, status.Healthy, status)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := c.Ready(r.Context())
+		writeJSON(w, status.Ready, status)
+	})
+}
+
+// HealthStatus is the /healthz response.
+type HealthStatus struct {
+	Healthy       bool
+	Stage         string    `json:",omitempty"` // stage the main loop last entered
+	LastHeartbeat time.Time `json:",omitempty"`
+	SinceLast     string    `json:",omitempty"`
+	Error         string    `json:",omitempty"`
+}
+
+// Health reports whether the main loop has made progress within
+// MaxHeartbeatAge.
+func (c *Checker) Health() HealthStatus {
+	if c.Heartbeat == nil || c.Opts.MaxHeartbeatAge == 0 {
+		return HealthStatus{Healthy: true}
+	}
+
+	last, stage := c.Heartbeat.Last()
+	since := time.Since(last)
+	status := HealthStatus{
+		Healthy:       since <= c.Opts.MaxHeartbeatAge,
+		Stage:         stage,
+		LastHeartbeat: last,
+		SinceLast:     since.Round(time.Second).String(),
+	}
+	if !status.Healthy {
+		status.Error = fmt.Sprintf("main loop stuck in %s for %s", stage, status.SinceLast)
+	}
+	return status
+}
+
+// CheckResult is the result of a single /readyz probe.
+type CheckResult struct {
+	OK       bool
+	Duration string
+	Error    string `json:",omitempty"`
+}
+
+// RoleStatus reports the roles held by the signer.
+type RoleStatus struct {
+	Address     common.Address
+	Publisher   common.Address
+	IsPublisher bool
+	Defender    common.Address
+	IsDefender  bool
+	Error       string `json:",omitempty"`
+}
+
+// ReadyStatus is the /readyz response.
+type ReadyStatus struct {
+	Ready  bool
+	Checks map[string]CheckResult
+	Roles  *RoleStatus `json:",omitempty"`
+}
+
+// Ready probes every backend. Roles are reported but do not affect
+// readiness, as only the publisher and defender need them.
+func (c *Checker) Ready(ctx context.Context) ReadyStatus {
+	probes := map[string]func(context.Context) error{
+		"ethereum": func(ctx context.Context) error {
+			_, err := c.Ethereum.ChainID(ctx)
+			return err
+		},
+		"celestia":   c.Celestia.PingNode,
+		"tendermint": c.Celestia.PingTendermint,
+		"lightlink": func(ctx context.Context) error {
+			_, err := c.LightLink.GetChainId(ctx)
+			return err
+		},
+		"store": c.probeStore,
+	}
+
+	// run the probes at once, so a slow backend does not hold up the others
+	status := ReadyStatus{Ready: true, Checks: make(map[string]CheckResult)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := c.probe(ctx, probe)
+			if !res.OK {
+				c.Opts.Logger.Warn("Readiness probe failed", "probe", name, "error", res.Error)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			status.Ready = status.Ready && res.OK
+			status.Checks[name] = res
+		}()
+	}
+	wg.Wait()
+
+	if c.Opts.Signer != nil {
+		status.Roles = c.roles(ctx)
+	}
+
+	return status
+}
+
+// probe runs the probe, failing it if it takes longer than ProbeTimeout.
+func (c *Checker) probe(ctx context.Context, probe func(context.Context) error) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.Opts.ProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- probe(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.Opts.ProbeTimeout)
+	}
+
+	res := CheckResult{OK: err == nil, Duration: time.Since(start).Round(time.Millisecond).String()}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// probeStore reads from the store to check it is open. If the store is
+// disabled there is nothing to check.
+func (c *Checker) probeStore(ctx context.Context) error {
+	if c.Store == nil {
+		return nil
+	}
+
+	_, err := c.Store.Get(ctx, probeKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// roles reports whether the signer is the publisher in
+// CanonicalStateChain.sol and the defender in Challenge.sol.
+func (c *Checker) roles(ctx context.Context) *RoleStatus {
+	roles := &RoleStatus{Address: c.Opts.Signer.Address()}
+
+	publisher, err := c.Ethereum.GetPublisher(ctx)
+	if err != nil {
+		roles.Error = fmt.Sprintf("failed to get publisher: %s", err)
+		return roles
+	}
+	roles.Publisher = publisher
+	roles.IsPublisher = publisher == roles.Address
+
+	defender, err := c.Ethereum.GetDefender(ctx)
+	if err != nil {
+		roles.Error = fmt.Sprintf("failed to get defender: %s", err)
+		return roles
+	}
+	roles.Defender = defender
+	roles.IsDefender = defender == roles.Address
+
+	return roles
+}
+
+func writeJSON(w http.ResponseWriter, ok bool, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,268 @@
+// Package metrics records Prometheus metrics for the rollup, defender and
+// the ethereum, celestia and lightlink clients. A nil *Metrics records
+// nothing, so metrics are optional wherever they are used.
+package metrics
+
+import (
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "hb"
+
+// Backends RPC errors are counted for.
+const (
+	BackendEthereum  = "ethereum"
+	BackendCelestia  = "celestia"
+	BackendLightLink = "lightlink"
+)
+
+// Challenge types, matching the settler challenge kinds.
+const (
+	ChallengeDA       = "da"
+	ChallengeL2Header = "l2-header"
+)
+
+// Challenge outcomes.
+const (
+	ChallengeSeen     = "seen"     // a pending challenge was found
+	ChallengeDefended = "defended" // a challenge was defended by us
+	ChallengeLost     = "lost"     // a challenge was settled in favour of the challenger
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	l2Lag          prometheus.Gauge
+	publishLatency prometheus.Histogram
+	publishRetries prometheus.Counter
+	blobSize       prometheus.Histogram
+	daFees         prometheus.Counter
+	rollupGasUsed  prometheus.Histogram
+	rollupFees     prometheus.Counter
+	waitDuration   prometheus.Histogram
+	txReplaced     prometheus.Counter
+	nonceGaps      prometheus.Counter
+	challenges     *prometheus.CounterVec
+	rpcErrors      *prometheus.CounterVec
+}
+
+// New returns metrics registered to a new registry, along with the go
+// runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		l2Lag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "l2_lag_blocks",
+			Help:      "LightLink height minus the L2 height of the rollup head.",
+		}),
+		publishLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "celestia_publish_seconds",
+			Help:      "Time taken to publish a bundle to Celestia, including retries.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		publishRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "celestia_publish_retries_total",
+			Help:      "Number of retries publishing bundles to Celestia.",
+		}),
+		blobSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "celestia_blob_bytes",
+			Help:      "Size of the blobs published to Celestia.",
+			Buckets:   prometheus.ExponentialBuckets(16*1024, 2, 10),
+		}),
+		daFees: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "celestia_fees_tia_total",
+			Help:      "Celestia fees in TIA spent publishing bundles.",
+		}),
+		rollupGasUsed: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rollup_push_gas_used",
+			Help:      "L1 gas used by each rollup block pushed to CanonicalStateChain.",
+			Buckets:   prometheus.ExponentialBuckets(50_000, 2, 8),
+		}),
+		rollupFees: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rollup_push_fees_eth_total",
+			Help:      "L1 fees in ETH spent pushing rollup blocks to CanonicalStateChain.",
+		}),
+		waitDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ethereum_wait_seconds",
+			Help:      "Time taken waiting for L1 txs to be mined.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		txReplaced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ethereum_tx_replacements_total",
+			Help:      "Number of pending L1 txs replaced with a higher fee.",
+		}),
+		nonceGaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ethereum_nonce_gaps_total",
+			Help:      "Number of gaps found in the nonces of sent L1 txs.",
+		}),
+		challenges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "challenges_total",
+			Help:      "Number of challenges seen, defended and lost, by challenge type.",
+		}, []string{"type", "outcome"}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_errors_total",
+			Help:      "Number of failed RPC calls, by backend.",
+		}, []string{"backend"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.l2Lag,
+		m.publishLatency,
+		m.publishRetries,
+		m.blobSize,
+		m.daFees,
+		m.rollupGasUsed,
+		m.rollupFees,
+		m.waitDuration,
+		m.txReplaced,
+		m.nonceGaps,
+		m.challenges,
+		m.rpcErrors,
+	)
+
+	// start every backend at 0, so rates can be computed from the first error
+	for _, backend := range []string{BackendEthereum, BackendCelestia, BackendLightLink} {
+		m.rpcErrors.WithLabelValues(backend)
+	}
+
+	return m
+}
+
+// Handler returns the http handler serving the metrics in the Prometheus
+// text format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// SetL2Lag records the number of LightLink blocks not yet rolled up.
+func (m *Metrics) SetL2Lag(llHeight, rollupL2Height uint64) {
+	if m == nil {
+		return
+	}
+	if llHeight < rollupL2Height {
+		m.l2Lag.Set(0)
+		return
+	}
+	m.l2Lag.Set(float64(llHeight - rollupL2Height))
+}
+
+// ObservePublish records a bundle published to Celestia.
+func (m *Metrics) ObservePublish(d time.Duration, retries int, blobSize int) {
+	if m == nil {
+		return
+	}
+	m.publishLatency.Observe(d.Seconds())
+	m.publishRetries.Add(float64(retries))
+	m.blobSize.Observe(float64(blobSize))
+}
+
+// ObserveDAFee records the fee in TIA paid to publish a bundle to Celestia.
+func (m *Metrics) ObserveDAFee(tia float64) {
+	if m == nil {
+		return
+	}
+	m.daFees.Add(tia)
+}
+
+// ObserveRollupPush records the L1 gas spent by a rollup block push tx.
+func (m *Metrics) ObserveRollupPush(receipt *types.Receipt) {
+	if m == nil || receipt == nil {
+		return
+	}
+	m.rollupGasUsed.Observe(float64(receipt.GasUsed))
+	if receipt.EffectiveGasPrice != nil {
+		fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+		eth, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), big.NewFloat(params.Ether)).Float64()
+		m.rollupFees.Add(eth)
+	}
+}
+
+// ObserveWait records the time taken waiting for an L1 tx to be mined.
+func (m *Metrics) ObserveWait(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.waitDuration.Observe(d.Seconds())
+}
+
+// TxReplaced records a pending L1 tx being replaced with a higher fee.
+func (m *Metrics) TxReplaced() {
+	if m == nil {
+		return
+	}
+	m.txReplaced.Inc()
+}
+
+// NonceGap records a gap found in the nonces of sent L1 txs.
+func (m *Metrics) NonceGap() {
+	if m == nil {
+		return
+	}
+	m.nonceGaps.Inc()
+}
+
+// Challenge records a challenge outcome for the challenge type.
+func (m *Metrics) Challenge(challengeType, outcome string) {
+	if m == nil {
+		return
+	}
+	m.challenges.WithLabelValues(challengeType, outcome).Inc()
+}
+
+// RPCError records a failed RPC call to the backend.
+func (m *Metrics) RPCError(backend string) {
+	if m == nil {
+		return
+	}
+	m.rpcErrors.WithLabelValues(backend).Inc()
+}
+
+// Transport wraps the http transport to count failed requests to the
+// backend, either transport errors or error status codes. If base is nil,
+// http.DefaultTransport is used.
+func (m *Metrics) Transport(backend string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if err != nil || resp.StatusCode >= 400 {
+			m.RPCError(backend)
+		}
+		return resp, err
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1,29 +1,60 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	l2tol1messagepasser "hummingbird/node/contracts/L2toL1MessagePasser.sol"
 	lightlinkportal "hummingbird/node/contracts/LightLinkPortal.sol"
+	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-func (n *Node) GenOutputProofV0(rblockHash common.Hash) (*lightlinkportal.TypesOutputRootProof, error) {
-	rollupHeader, err := n.Ethereum.GetRollupHeaderByHash(rblockHash)
+// ErrNotRolledUp is returned when an L2 block is not yet included in any
+// rollup block.
+var ErrNotRolledUp = errors.New("L2 block is not yet included in a rollup block")
+
+// Withdrawal is a withdrawal initiated on LightLink, read from a
+// MessagePassed event emitted by L2ToL1MessagePasser.sol.
+type Withdrawal struct {
+	Tx       lightlinkportal.TypesWithdrawalTransaction
+	Hash     common.Hash // withdrawal hash, as stored in L2ToL1MessagePasser.sol
+	L2TxHash common.Hash
+	L2Height uint64 // L2 block the withdrawal was initiated in
+}
+
+// WithdrawalStatus is the progress of a withdrawal through LightLinkPortal.sol.
+type WithdrawalStatus struct {
+	Hash            common.Hash
+	RolledUp        bool      // true once a rollup block includes the withdrawal
+	RollupIndex     uint64    // first rollup block including the withdrawal
+	Proven          bool      // true once proven in LightLinkPortal.sol
+	ProvenAt        time.Time // time the withdrawal was proven
+	L2OutputIndex   uint64    // rollup block the withdrawal was proven against
+	ReadyToFinalize bool      // true once proven and the rollup block is past the challenge window
+	Finalized       bool
+}
+
+func (n *Node) GenOutputProofV0(ctx context.Context, rblockHash common.Hash) (*lightlinkportal.TypesOutputRootProof, error) {
+	rollupHeader, err := n.Ethereum.GetRollupHeaderByHash(ctx, rblockHash)
 	if err != nil {
 		return nil, err
 	}
 
-	lastBlock, err := n.LightLink.GetBlock(rollupHeader.L2Height)
+	lastBlock, err := n.LightLink.GetBlock(ctx, rollupHeader.L2Height)
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := n.LightLink.GetOutputV0(lastBlock.Header())
+	output, err := n.LightLink.GetOutputV0(ctx, lastBlock.Header())
 	if err != nil {
 		return nil, err
 	}
@@ -36,22 +67,25 @@ func (n *Node) GenOutputProofV0(rblockHash common.Hash) (*lightlinkportal.TypesO
 	}, nil
 }
 
-func (n *Node) GetWithdrawalProof(rblockHash, withdrawalRoot, withdrawalHash common.Hash) ([][]byte, error) {
+func (n *Node) GetWithdrawalProof(ctx context.Context, rblockHash, withdrawalRoot, withdrawalHash common.Hash) ([][]byte, error) {
 	slot, err := getSlot(withdrawalHash)
 	if err != nil {
 		return nil, err
 	}
 
-	rollupHeader, err := n.Ethereum.GetRollupHeaderByHash(rblockHash)
+	rollupHeader, err := n.Ethereum.GetRollupHeaderByHash(ctx, rblockHash)
 	if err != nil {
 		return nil, err
 	}
 
 	l2Height := rollupHeader.L2Height
-	rawProof, err := n.LightLink.GetProof(n.LightLink.WithdrawalAddress(l2Height), []string{slot.Hex()}, l2Height)
+	rawProof, err := n.LightLink.GetProof(ctx, n.LightLink.WithdrawalAddress(l2Height), []string{slot.Hex()}, l2Height)
 	if err != nil {
 		return nil, err
 	}
+	if len(rawProof.StorageProof) == 0 {
+		return nil, fmt.Errorf("no storage proof returned for withdrawal %s", withdrawalHash.Hex())
+	}
 
 	proof := [][]byte{}
 	for _, p := range rawProof.StorageProof[0].Proof {
@@ -62,18 +96,234 @@ func (n *Node) GetWithdrawalProof(rblockHash, withdrawalRoot, withdrawalHash com
 	return proof, nil
 }
 
+// GetWithdrawals returns the withdrawals initiated by the given L2 tx.
+func (n *Node) GetWithdrawals(ctx context.Context, l2TxHash common.Hash) ([]*Withdrawal, error) {
+	receipt, err := n.LightLink.GetReceipt(ctx, l2TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("tx %s failed on LightLink", l2TxHash.Hex())
+	}
+
+	logs := []ethtypes.Log{}
+	for _, log := range receipt.Logs {
+		logs = append(logs, *log)
+	}
+
+	return n.parseWithdrawals(receipt.BlockNumber.Uint64(), logs)
+}
+
+// GetWithdrawalsInRange returns the withdrawals initiated in the given L2
+// block range, inclusive.
+func (n *Node) GetWithdrawalsInRange(ctx context.Context, start, end uint64) ([]*Withdrawal, error) {
+	passerABI, err := l2tol1messagepasser.L2ToL1MessagePasserMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	topics := [][]common.Hash{{passerABI.Events["MessagePassed"].ID}}
+	logs, err := n.LightLink.GetLogs(ctx, start, end, n.LightLink.WithdrawalAddress(end), topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MessagePassed events: %w", err)
+	}
+
+	return n.parseWithdrawals(end, logs)
+}
+
+// parseWithdrawals returns the withdrawals initiated by the MessagePassed
+// events in logs, ignoring any other logs.
+func (n *Node) parseWithdrawals(l2Height uint64, logs []ethtypes.Log) ([]*Withdrawal, error) {
+	passer := n.LightLink.WithdrawalAddress(l2Height)
+	filterer, err := l2tol1messagepasser.NewL2ToL1MessagePasserFilterer(passer, nil)
+	if err != nil {
+		return nil, err
+	}
+	passerABI, err := l2tol1messagepasser.L2ToL1MessagePasserMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals := []*Withdrawal{}
+	for _, log := range logs {
+		if log.Address != passer || len(log.Topics) == 0 || log.Topics[0] != passerABI.Events["MessagePassed"].ID {
+			continue
+		}
+
+		ev, err := filterer.ParseMessagePassed(log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MessagePassed event: %w", err)
+		}
+		withdrawals = append(withdrawals, NewWithdrawal(ev))
+	}
+
+	return withdrawals, nil
+}
+
+// NewWithdrawal returns the withdrawal initiated by a MessagePassed event.
+func NewWithdrawal(ev *l2tol1messagepasser.L2ToL1MessagePasserMessagePassed) *Withdrawal {
+	return &Withdrawal{
+		Tx: lightlinkportal.TypesWithdrawalTransaction{
+			Nonce:    ev.Nonce,
+			Sender:   ev.Sender,
+			Target:   ev.Target,
+			Value:    ev.Value,
+			GasLimit: ev.GasLimit,
+			Data:     ev.Data,
+		},
+		Hash:     ev.WithdrawalHash,
+		L2TxHash: ev.Raw.TxHash,
+		L2Height: ev.Raw.BlockNumber,
+	}
+}
+
+// FindRollupBlock returns the index of the first rollup block that includes
+// the given L2 block, or ErrNotRolledUp if no rollup block does yet.
+func (n *Node) FindRollupBlock(ctx context.Context, l2Height uint64) (uint64, error) {
+	height, err := n.Ethereum.GetRollupHeight(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rollup height: %w", err)
+	}
+	head, err := n.Ethereum.GetRollupHeader(ctx, height)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rollup head: %w", err)
+	}
+	if height == 0 || head.L2Height < l2Height {
+		return 0, ErrNotRolledUp
+	}
+
+	// binary search for the first rollup block with L2Height >= l2Height,
+	// skipping the genesis block
+	lo, hi := uint64(1), height
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header, err := n.Ethereum.GetRollupHeader(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rollup block %d: %w", mid, err)
+		}
+		if header.L2Height < l2Height {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// ProveWithdrawal proves the withdrawal in LightLinkPortal.sol against the
+// first rollup block that includes it.
+func (n *Node) ProveWithdrawal(ctx context.Context, w *Withdrawal) (*ethtypes.Transaction, error) {
+	// 1. find the first rollup block that includes the withdrawal
+	index, err := n.FindRollupBlock(ctx, w.L2Height)
+	if err != nil {
+		return nil, err
+	}
+	header, err := n.Ethereum.GetRollupHeader(ctx, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup block %d: %w", index, err)
+	}
+	rblockHash, err := n.Ethereum.HashHeader(ctx, &header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash rollup block %d: %w", index, err)
+	}
+
+	// 2. prove the output root of the rollup block, and the withdrawal is
+	// stored in L2ToL1MessagePasser.sol at that output
+	outputProof, err := n.GenOutputProofV0(ctx, rblockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate output root proof: %w", err)
+	}
+	withdrawalProof, err := n.GetWithdrawalProof(ctx, rblockHash, outputProof.MessagePasserStorageRoot, w.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate withdrawal proof: %w", err)
+	}
+
+	// 3. submit the proof
+	return n.Ethereum.ProveWithdrawalTransaction(ctx, w.Tx, index, *outputProof, withdrawalProof)
+}
+
+// FinalizeWithdrawal finalizes a proven withdrawal in LightLinkPortal.sol,
+// once the rollup block it was proven against is past the challenge window.
+func (n *Node) FinalizeWithdrawal(ctx context.Context, w *Withdrawal) (*ethtypes.Transaction, error) {
+	status, err := n.GetWithdrawalStatus(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	if status.Finalized {
+		return nil, fmt.Errorf("withdrawal %s is already finalized", w.Hash.Hex())
+	}
+	if !status.Proven {
+		return nil, fmt.Errorf("withdrawal %s has not been proven", w.Hash.Hex())
+	}
+	if !status.ReadyToFinalize {
+		return nil, fmt.Errorf("withdrawal %s is proven against rollup block %d, which is still in the challenge window", w.Hash.Hex(), status.L2OutputIndex)
+	}
+
+	return n.Ethereum.FinalizeWithdrawalTransaction(ctx, w.Tx)
+}
+
+// GetWithdrawalStatus returns the progress of the withdrawal through
+// LightLinkPortal.sol.
+func (n *Node) GetWithdrawalStatus(ctx context.Context, w *Withdrawal) (*WithdrawalStatus, error) {
+	status := &WithdrawalStatus{Hash: w.Hash}
+
+	index, err := n.FindRollupBlock(ctx, w.L2Height)
+	if err != nil && !errors.Is(err, ErrNotRolledUp) {
+		return nil, err
+	}
+	if err == nil {
+		status.RolledUp = true
+		status.RollupIndex = index
+	}
+
+	proven, err := n.Ethereum.GetProvenWithdrawal(ctx, w.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if proven.Timestamp != nil && proven.Timestamp.Sign() > 0 {
+		status.Proven = true
+		status.ProvenAt = time.Unix(proven.Timestamp.Int64(), 0)
+		status.L2OutputIndex = proven.L2OutputIndex.Uint64()
+
+		status.ReadyToFinalize, err = n.Ethereum.IsOutputFinalized(ctx, status.L2OutputIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check output %d is finalized: %w", status.L2OutputIndex, err)
+		}
+	}
+
+	status.Finalized, err = n.Ethereum.IsWithdrawalFinalized(ctx, w.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check withdrawal is finalized: %w", err)
+	}
+
+	return status, nil
+}
+
+// HashWithdrawal returns the withdrawal hash, as L2ToL1MessagePasser.sol
+// computes it, keccak256(abi.encode(nonce, sender, target, value, gasLimit, data)).
+func HashWithdrawal(tx lightlinkportal.TypesWithdrawalTransaction) (common.Hash, error) {
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	arguments := abi.Arguments{{Type: uint256Type}, {Type: addressType}, {Type: addressType}, {Type: uint256Type}, {Type: uint256Type}, {Type: bytesType}}
+
+	encodedData, err := arguments.Pack(tx.Nonce, tx.Sender, tx.Target, tx.Value, tx.GasLimit, tx.Data)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode withdrawal: %w", err)
+	}
+
+	return crypto.Keccak256Hash(encodedData), nil
+}
+
 // see https://github.com/ethereum-optimism/optimism/blob/f8143c8cbc4cc0c83922c53f17a1e47280673485/packages/sdk/src/utils/message-utils.ts#L42
 func getSlot(messageHash common.Hash) (common.Hash, error) {
 	bytes32Type, _ := abi.NewType("bytes32", "", nil)
 	uint256Type, _ := abi.NewType("uint256", "", nil)
 	arguments := abi.Arguments{{Type: bytes32Type}, {Type: uint256Type}}
 
-	// Convert messageHash to bytes32
-	msgHashBytes := messageHash.Bytes()    // key
-	zeroHashBytes := common.Hash{}.Bytes() // 0 slot
-
-	// Encode the arguments
-	encodedData, err := arguments.Pack(msgHashBytes, zeroHashBytes)
+	// Encode the arguments, the key and the 0 slot
+	encodedData, err := arguments.Pack([32]byte(messageHash), new(big.Int))
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to encode data: %w", err)
 	}
@@ -2,6 +2,7 @@ package node
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"hummingbird/node/lightlink/types"
 	"hummingbird/utils"
@@ -17,6 +18,10 @@ import (
 // TxSizeLimit is the maximum size of a Celestia tx in bytes
 const TxSizeLimit = uint64(1962441)
 
+// BundleSizeLimit is the maximum encoded size of a bundle in bytes, leaving
+// 196245 bytes (10%) of the Celestia tx size limit for tx overhead.
+const BundleSizeLimit = TxSizeLimit - 196245
+
 // Bundle is a collection of layer2 blocks which will be submitted to the
 // data availability layer (Celestia).
 type Bundle struct {
@@ -97,11 +102,62 @@ func (b *Bundle) IsUnderTxLimit() (bool, uint64, uint64, error) {
 		return false, 0, 0, err
 	}
 	bundleEncodedSize := uint64(len(bundleEncoded))
-	bundleSizeLimit := TxSizeLimit - 196245
-	if bundleEncodedSize > bundleSizeLimit {
-		return false, bundleSizeLimit, bundleEncodedSize, nil
+	if bundleEncodedSize > BundleSizeLimit {
+		return false, BundleSizeLimit, bundleEncodedSize, nil
+	}
+	return true, BundleSizeLimit, bundleEncodedSize, nil
+}
+
+// bundleSizer tracks the encoded size of a bundle as blocks are added, so
+// the size limit can be checked without encoding the whole bundle again.
+type bundleSizer struct {
+	limit      uint64 // max encoded size of the bundle, defaults to BundleSizeLimit
+	blocksSize uint64 // total size of the encoded blocks
+}
+
+// add returns the encoded size of the bundle with the block added, and
+// whether it is under the size limit. The block is only counted if it is.
+func (s *bundleSizer) add(block *types.Block) (uint64, bool, error) {
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return 0, false, err
+	}
+
+	limit := s.limit
+	if limit == 0 {
+		limit = BundleSizeLimit
+	}
+
+	size := rlp.ListSize(s.blocksSize + uint64(len(enc)))
+	if size > limit {
+		return size, false, nil
+	}
+	s.blocksSize += uint64(len(enc))
+	return size, true, nil
+}
+
+// PackBundle returns a bundle of the longest run of the blocks, from the
+// first, that encodes to at most maxSize bytes. It returns an error if there
+// are no blocks, or the first block alone is over maxSize.
+func PackBundle(blocks []*types.Block, maxSize uint64) (*Bundle, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks to pack")
+	}
+
+	sizer := &bundleSizer{limit: maxSize}
+	for i, block := range blocks {
+		size, ok, err := sizer.add(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bundle size: %w", err)
+		}
+		if !ok {
+			if i == 0 {
+				return nil, fmt.Errorf("block %d alone encodes to %d bytes, over the bundle size limit of %d bytes", block.NumberU64(), size, maxSize)
+			}
+			return &Bundle{Blocks: blocks[:i]}, nil
+		}
 	}
-	return true, bundleSizeLimit, bundleEncodedSize, nil
+	return &Bundle{Blocks: blocks}, nil
 }
 
 func (b *Bundle) Blob(namespace string) (*blob.Blob, error) {
@@ -101,6 +101,39 @@ func TestBundle_DecodeRLP(t *testing.T) {
 	assert.Equal(t, b.Blocks[4].Hash(), decoded.Blocks[4].Hash())
 }
 
+func TestPackBundle(t *testing.T) {
+	b := newRandomBundle(10, true)
+	encoded, err := b.EncodeRLP()
+	assert.NoError(t, err)
+
+	// 1. all the blocks are packed if they fit
+	packed, err := PackBundle(b.Blocks, uint64(len(encoded)))
+	assert.NoError(t, err)
+	assert.Len(t, packed.Blocks, 10)
+
+	// 2. the blocks are cut at the last one that fits
+	limit := uint64(len(encoded)) / 2
+	packed, err = PackBundle(b.Blocks, limit)
+	assert.NoError(t, err)
+	n := len(packed.Blocks)
+	assert.True(t, n > 0 && n < 10)
+
+	encoded, err = packed.EncodeRLP()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, uint64(len(encoded)), limit)
+	encoded, err = (&Bundle{Blocks: b.Blocks[:n+1]}).EncodeRLP()
+	assert.NoError(t, err)
+	assert.Greater(t, uint64(len(encoded)), limit)
+
+	// 3. a first block over the limit is an error
+	_, err = PackBundle(b.Blocks, 10)
+	assert.Error(t, err)
+
+	// 4. no blocks is an error
+	_, err = PackBundle(nil, limit)
+	assert.Error(t, err)
+}
+
 func TestBundleTxInclusion(t *testing.T) {
 	b := newRandomBundle(2, true)
 	tx := b.Blocks[0].Transactions()[0]
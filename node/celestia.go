@@ -3,13 +3,17 @@ package node
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/celestiaorg/celestia-node/api/rpc/client"
 	"github.com/celestiaorg/celestia-node/blob"
+	"github.com/celestiaorg/celestia-node/share/shwap"
 	"github.com/celestiaorg/celestia-node/state"
 
 	// "github.com/celestiaorg/celestia-node/share"
@@ -23,6 +27,7 @@ import (
 
 	"github.com/celestiaorg/celestia-app/v6/pkg/appconsts"
 
+	"hummingbird/metrics"
 	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
 	challengeContract "hummingbird/node/contracts/Challenge.sol"
 	"hummingbird/utils"
@@ -35,8 +40,10 @@ type CelestiaPointer struct {
 	ShareLen   uint64
 
 	// Extra data Only present if the pointer is stored in the local database.
-	Commitment common.Hash
+	Commitment common.Hash // Share commitment of the blob.
+	DataRoot   common.Hash // Data root of the Celestia block the blob is in.
 	TxHash     common.Hash
+	RollupHash common.Hash // Hash of the rollup block the pointer is in, once built.
 }
 
 type CelestiaProof struct {
@@ -45,16 +52,22 @@ type CelestiaProof struct {
 	WrappedProof *challengeContract.BinaryMerkleProof
 }
 
+// ErrSharesMissing is returned by GetSharesByNamespace when Celestia shows
+// the pointers shares are not there, as opposed to failing to fetch them.
+var ErrSharesMissing = errors.New("shares missing from Celestia")
+
 // Celestia is the interface for interacting with the Celestia node
 type Celestia interface {
 	Namespace() string
-	PublishBundle(blocks Bundle) (*CelestiaPointer, float64, error)
-	GetProof(pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error)
-	GetSharesByNamespace(pointer *CelestiaPointer) ([]share.Share, error)
-	GetSharesByPointer(pointer *CelestiaPointer) ([]share.Share, error)
-	GetShareProof(celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error)
-	GetSharesProof(celestiaPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error)
-	GetPointer(txHash common.Hash) (*CelestiaPointer, error)
+	PublishBundles(ctx context.Context, bundles []Bundle) ([]*CelestiaPointer, *CelestiaFee, error) // PublishBundles publishes as many of the bundles as fit in one tx, returning their pointers and the fee paid.
+	GetProof(ctx context.Context, pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error)
+	GetSharesByNamespace(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error) // GetSharesByNamespace returns the pointers shares from the DA network.
+	GetSharesByPointer(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error)   // GetSharesByPointer returns the pointers shares from the consensus node.
+	GetShareProof(ctx context.Context, celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error)
+	GetSharesProof(ctx context.Context, celestiaPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error)
+	GetPointer(ctx context.Context, txHash common.Hash) (*CelestiaPointer, error)
+	PingNode(ctx context.Context) error       // PingNode returns an error if the celestia node is unreachable.
+	PingTendermint(ctx context.Context) error // PingTendermint returns an error if the tendermint rpc is unreachable.
 }
 
 type CelestiaClientOpts struct {
@@ -63,11 +76,15 @@ type CelestiaClientOpts struct {
 	TendermintRPC           string
 	Namespace               string
 	Logger                  *slog.Logger
-	GasPrice                float64
-	GasPriceIncreasePercent *big.Int
-	GasAPI                  string
+	GasPrice                float64  // Gas price in utia, used if the gas API and recent blocks give no price.
+	GasPriceIncreasePercent *big.Int // Percent the gas price is increased by.
+	MaxGasPrice             float64  // optional, max gas price in utia
+	RetryIncreasePercent    int      // Percent the gas price is increased by on each retry.
+	GasAPI                  string   // optional, endpoint to get the gas price from
 	Retries                 int
 	RetryDelay              time.Duration
+	InclusionTimeout        time.Duration    // Time to wait for a submitted blob to be included and retrievable, defaults to 1 minute.
+	Metrics                 *metrics.Metrics // optional
 }
 
 var _ Celestia = &CelestiaClient{}
@@ -80,22 +97,30 @@ type CelestiaClient struct {
 	logger                  *slog.Logger
 	gasPrice                float64
 	gasPriceIncreasePercent *big.Int
+	maxGasPrice             float64
+	retryIncreasePercent    int
 	gasAPI                  string
+	httpClient              *http.Client
 	retries                 int
 	retryDelay              time.Duration
+	inclusionTimeout        time.Duration
+	metrics                 *metrics.Metrics
 }
 
-func NewCelestiaClient(opts CelestiaClientOpts) (*CelestiaClient, error) {
+func NewCelestiaClient(ctx context.Context, opts CelestiaClientOpts) (*CelestiaClient, error) {
 	if opts.Logger == nil {
 		opts.Logger = slog.Default()
 	}
+	if opts.InclusionTimeout == 0 {
+		opts.InclusionTimeout = time.Minute
+	}
 
-	c, err := client.NewClient(context.Background(), opts.Endpoint, opts.Token)
+	c, err := client.NewClient(ctx, opts.Endpoint, opts.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Celestia: %w", err)
 	}
 
-	openrpcClient, err := openclient.NewClient(context.Background(), opts.Endpoint, opts.Token)
+	openrpcClient, err := openclient.NewClient(ctx, opts.Endpoint, opts.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Celestia OpenRPC: %w", err)
 	}
@@ -118,9 +143,14 @@ func NewCelestiaClient(opts CelestiaClientOpts) (*CelestiaClient, error) {
 		logger:                  opts.Logger,
 		gasPrice:                opts.GasPrice,
 		gasPriceIncreasePercent: opts.GasPriceIncreasePercent,
+		maxGasPrice:             opts.MaxGasPrice,
+		retryIncreasePercent:    opts.RetryIncreasePercent,
 		gasAPI:                  opts.GasAPI,
+		httpClient:              &http.Client{Timeout: 10 * time.Second, Transport: opts.Metrics.Transport(metrics.BackendCelestia, nil)},
 		retries:                 opts.Retries,
 		retryDelay:              opts.RetryDelay,
+		inclusionTimeout:        opts.InclusionTimeout,
+		metrics:                 opts.Metrics,
 	}, nil
 }
 
@@ -128,53 +158,117 @@ func (c *CelestiaClient) Namespace() string {
 	return c.namespace
 }
 
-func (c *CelestiaClient) PublishBundle(blocks Bundle) (*CelestiaPointer, float64, error) {
+// maxPayForBlobsSize is the max total size of the blobs packed into one pay
+// for blobs tx, leaving room in the max tx size for the rest of the tx.
+const maxPayForBlobsSize = appconsts.MaxTxSize - 64*1024
+
+// PublishBundles publishes as many of the bundles as fit in a single pay for
+// blobs tx, in order, so they land at the same Celestia height. It returns
+// a pointer for each bundle published and the fee paid, leaving the rest to
+// be published in another tx. A submitted tx is waited for until its blobs
+// are available, and only submitted again if it failed or was dropped, so
+// the blobs are not paid for twice.
+func (c *CelestiaClient) PublishBundles(ctx context.Context, bundles []Bundle) ([]*CelestiaPointer, *CelestiaFee, error) {
+	if len(bundles) == 0 {
+		return nil, nil, fmt.Errorf("no bundles to publish")
+	}
+
 	// get the namespace
 	ns, err := share.NewV0Namespace([]byte(c.Namespace()))
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
-	// encode the blocks
-	enc, err := blocks.EncodeRLP()
-	if err != nil {
-		return nil, 0, err
-	}
+	// create a blob for each bundle that fits, always including the first
+	blobs := make([]*blob.Blob, 0, len(bundles))
+	size := 0
+	for _, bundle := range bundles {
+		enc, err := bundle.EncodeRLP()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(blobs) > 0 && size+len(enc) > maxPayForBlobsSize {
+			break
+		}
 
-	// create blob to submit
-	b, err := blob.NewBlobV0(ns, []byte(enc))
-	if err != nil {
-		panic(err)
+		b, err := blob.NewBlobV0(ns, enc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create blob: %w", err)
+		}
+		blobs = append(blobs, b)
+		size += len(enc)
 	}
 
-	var pointer *CelestiaPointer
+	var pointers []*CelestiaPointer
+	var fee *CelestiaFee
+	var sub *blobSubmission
+	var gasPrice float64
 
+	start := time.Now()
 	i := 0
 	for {
-		// post the blob
-		pointer, err = c.submitBlob(context.Background(), []*blob.Blob{b})
-		if err == nil || i >= c.retries {
+		// post the blobs, escalating the gas price on each retry. A submission
+		// in flight is not cancelled with ctx, so the pointers to paid for
+		// blobs are returned and can be checkpointed.
+		if sub == nil {
+			gasPrice = c.suggestGasPrice(ctx, i)
+			sub, err = c.submitBlobs(context.WithoutCancel(ctx), blobs, gasPrice)
+		}
+
+		// once the tx is submitted, keep waiting for it rather than paying
+		// for the blobs again, unless it is known to have failed or been
+		// dropped, or is still unknown after the grace period
+		if sub != nil {
+			pointers, fee, err = c.confirmBlobs(context.WithoutCancel(ctx), blobs, sub)
+			if errors.Is(err, errInclusionTimeout) && ctx.Err() == nil {
+				sub.timeouts++
+				if sub.timeouts < inclusionGraceTimeouts || !c.isTxUnknown(ctx, sub.txHash) {
+					c.logger.Warn("Submitted blobs not confirmed yet, still waiting", "tx_hash", sub.txHash.Hex(), "timeouts", sub.timeouts, "error", err)
+					continue
+				}
+				err = fmt.Errorf("%w: tx %s still unknown after %d inclusion timeouts: %w", errBlobTxDropped, sub.txHash.Hex(), sub.timeouts, err)
+			}
+			if !errors.Is(err, errBlobTxDropped) {
+				break
+			}
+			sub = nil
+		}
+		if i >= c.retries || ctx.Err() != nil {
 			break
 		}
 
-		c.logger.Warn("Failed to submit blob, retrying after delay", "delay", c.retryDelay, "attempt", i+1, "error", err)
+		c.logger.Warn("Failed to submit blobs, retrying after delay", "delay", c.retryDelay, "attempt", i+1, "gasPrice", gasPrice, "error", err)
 
 		i++
 
 		// Delay between publishing bundles to Celestia to mitigate 'incorrect account sequence' errors
-		time.Sleep(c.retryDelay)
+		if err = utils.Sleep(ctx, c.retryDelay); err != nil {
+			break
+		}
 	}
+	c.metrics.ObservePublish(time.Since(start), i, size)
 
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
+	c.metrics.ObserveDAFee(fee.TIA())
 
-	return pointer, 0, nil
+	return pointers, fee, nil
 }
 
-// PostData submits a new transaction with the provided data to the Celestia node.
-func (c *CelestiaClient) submitBlob(ctx context.Context, blobs []*blob.Blob) (*CelestiaPointer, error) {
-	c.logger.Debug("Submitting blob to Celestia",
+// blobSubmission is a pay for blobs tx submitted to the Celestia node.
+type blobSubmission struct {
+	txHash   common.Hash
+	height   int64 // height the tx was included at, if known
+	gasPrice float64
+	gasUsed  int64
+	timeouts int // inclusion timeouts waited through so far
+}
+
+// submitBlobs submits a pay for blobs tx with the blobs to the Celestia
+// node, at the given gas price, or a price estimated by the node if 0.
+func (c *CelestiaClient) submitBlobs(ctx context.Context, blobs []*blob.Blob, gasPrice float64) (*blobSubmission, error) {
+	c.logger.Debug("Submitting blobs to Celestia",
 		"blob_count", len(blobs),
 		"blob_sizes", func() []int {
 			sizes := make([]int, len(blobs))
@@ -184,14 +278,23 @@ func (c *CelestiaClient) submitBlob(ctx context.Context, blobs []*blob.Blob) (*C
 			return sizes
 		}())
 
-	txConfig := state.NewTxConfig()
+	var txOpts []state.ConfigOption
+	if gasPrice > 0 {
+		txOpts = append(txOpts, state.WithGasPrice(gasPrice))
+	}
+	if c.maxGasPrice > 0 {
+		txOpts = append(txOpts, state.WithMaxGasPrice(c.maxGasPrice))
+	}
+	txConfig := state.NewTxConfig(txOpts...)
 
 	c.logger.Debug("Calling SubmitPayForBlob",
 		"endpoint", "State.SubmitPayForBlob",
+		"gas_price", gasPrice,
 		"tx_config", fmt.Sprintf("%+v", txConfig))
 
 	response, err := c.client.State.SubmitPayForBlob(ctx, blob.ToLibBlobs(blobs...), txConfig)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		c.logger.Error("SubmitPayForBlob failed",
 			"error", err,
 			"error_type", fmt.Sprintf("%T", err))
@@ -208,32 +311,74 @@ func (c *CelestiaClient) submitBlob(ctx context.Context, blobs []*blob.Blob) (*C
 		return nil, err
 	}
 
-	// Delay here before getting the block to ensure the tx is included
-	time.Sleep(5 * time.Second)
+	return &blobSubmission{
+		txHash:   common.BytesToHash(txHash),
+		height:   response.Height,
+		gasPrice: gasPrice,
+		gasUsed:  response.GasUsed,
+	}, nil
+}
 
-	// Get the block that contains the tx
-	pointer, err := c.GetPointer(common.BytesToHash(txHash))
+// confirmBlobs waits for the submitted pay for blobs tx to be included and
+// its blobs to be available. It returns a pointer to each blob, in order,
+// and the fee paid.
+func (c *CelestiaClient) confirmBlobs(ctx context.Context, blobs []*blob.Blob, sub *blobSubmission) ([]*CelestiaPointer, *CelestiaFee, error) {
+	// Wait for the tx to be included
+	block, index, err := c.waitForInclusion(ctx, sub.txHash, sub.height)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Get the share range of each blob in the block that contains the tx
+	pointers, err := c.blobPointers(block, index, sub.txHash, len(blobs))
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, pointer := range pointers {
+		pointer.Commitment = common.BytesToHash(blobs[i].Commitment)
+	}
+
+	// Wait for the blobs to be retrievable where the pointers say they are
+	if err := c.waitForBlobs(ctx, block.Data.SquareSize, pointers, blobs); err != nil {
+		return nil, nil, err
 	}
 
-	return pointer, err
+	// Get the fee actually paid, as the node may have picked the gas price
+	fee, err := c.getTxFee(ctx, sub.txHash)
+	if err != nil {
+		c.logger.Warn("Failed to get fee paid for blobs", "tx_hash", sub.txHash.Hex(), "error", err)
+		fee = &CelestiaFee{GasPrice: sub.gasPrice}
+	}
+	fee.GasUsed = uint64(sub.gasUsed)
+
+	return pointers, fee, nil
 }
 
-func (c *CelestiaClient) GetProof(pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error) {
-	ctx := context.Background()
+// getTxFee returns the fee paid by the Celestia tx with the given hash.
+func (c *CelestiaClient) getTxFee(ctx context.Context, txHash common.Hash) (*CelestiaFee, error) {
+	tx, err := c.trpc.Tx(ctx, txHash.Bytes(), false)
+	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
+		return nil, err
+	}
+	return decodeTxFee(tx.Tx)
+}
+
+func (c *CelestiaClient) GetProof(ctx context.Context, pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error) {
 
 	blockHeight := int64(pointer.Height)
 
 	// Get the block that contains the tx
-	blockRes, err := c.trpc.Block(context.Background(), &blockHeight)
+	blockRes, err := c.trpc.Block(ctx, &blockHeight)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
 	// Get the shares proof
 	sharesProofs, err := c.trpc.ProveShares(ctx, pointer.Height, pointer.ShareStart, pointer.ShareStart+pointer.ShareLen)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
@@ -245,6 +390,7 @@ func (c *CelestiaClient) GetProof(pointer *CelestiaPointer, startBlock uint64, e
 	// Get the data root inclusion proof
 	dcProof, err := c.trpc.DataRootInclusionProof(ctx, uint64(blockHeight), startBlock, endBlock)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
@@ -273,13 +419,24 @@ func (c *CelestiaClient) GetProof(pointer *CelestiaPointer, startBlock uint64, e
 }
 
 // GetPointer returns the pointer to the Celestia header that contains the tx with the given hash
-func (c *CelestiaClient) GetPointer(txHash common.Hash) (*CelestiaPointer, error) {
+func (c *CelestiaClient) GetPointer(ctx context.Context, txHash common.Hash) (*CelestiaPointer, error) {
+	pointers, err := c.getPointers(ctx, txHash, 1)
+	if err != nil {
+		return nil, err
+	}
+	return pointers[0], nil
+}
+
+// getPointers returns the pointers to the first n blobs of the pay for
+// blobs tx with the given hash.
+func (c *CelestiaClient) getPointers(ctx context.Context, txHash common.Hash, n int) ([]*CelestiaPointer, error) {
 	c.logger.Debug("GetPointer: Fetching transaction details",
 		"tx_hash", txHash.Hex(),
 		"tendermint_rpc_endpoint", c.trpc.Remote())
 
-	tx, err := c.trpc.Tx(context.Background(), txHash.Bytes(), true)
+	tx, err := c.trpc.Tx(ctx, txHash.Bytes(), true)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		c.logger.Error("GetPointer: Failed to get transaction from Tendermint RPC",
 			"tx_hash", txHash.Hex(),
 			"error", err,
@@ -292,71 +449,90 @@ func (c *CelestiaClient) GetPointer(txHash common.Hash) (*CelestiaPointer, error
 		"height", tx.Height,
 		"index", tx.Index)
 	// Get the block that contains the tx
-	blockRes, err := c.trpc.Block(context.Background(), &tx.Height)
+	blockRes, err := c.trpc.Block(ctx, &tx.Height)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
-	// Get the blob share range inside the block, using square instead
 
+	return c.blobPointers(blockRes.Block, tx.Index, txHash, n)
+}
+
+// blobPointers returns the pointers to the first n blobs of the pay for
+// blobs tx at the given index in the block.
+func (c *CelestiaClient) blobPointers(block *types.Block, index uint32, txHash common.Hash, n int) ([]*CelestiaPointer, error) {
+	// Get the share range of each blob inside the block, using square instead
 	maxSquareSize := appconsts.SquareSizeUpperBound
 	subtreeRootThreshold := appconsts.SubtreeRootThreshold
-	blobShareRange, err := gosquare.BlobShareRange(blockRes.Block.Txs.ToSliceOfBytes(), int(tx.Index), int(0), maxSquareSize, subtreeRootThreshold)
-	if err != nil {
-		c.logger.Error("GetPointer: Failed to get blob share range",
-			"error", err,
-			"error_type", fmt.Sprintf("%T", err))
-		return nil, err
+	pointers := make([]*CelestiaPointer, n)
+	for i := range pointers {
+		blobShareRange, err := gosquare.BlobShareRange(block.Txs.ToSliceOfBytes(), int(index), i, maxSquareSize, subtreeRootThreshold)
+		if err != nil {
+			c.logger.Error("GetPointer: Failed to get blob share range",
+				"blob_index", i,
+				"error", err,
+				"error_type", fmt.Sprintf("%T", err))
+			return nil, err
+		}
+		pointers[i] = &CelestiaPointer{
+			Height:     uint64(block.Height),
+			DataRoot:   common.BytesToHash(block.DataHash),
+			ShareStart: uint64(blobShareRange.Start),
+			ShareLen:   uint64(blobShareRange.End - blobShareRange.Start),
+			TxHash:     txHash,
+		}
 	}
-	return &CelestiaPointer{
-		Height:     uint64(tx.Height),
-		Commitment: common.BytesToHash(blockRes.Block.DataHash),
-		ShareStart: uint64(blobShareRange.Start),
-		ShareLen:   uint64(blobShareRange.End - blobShareRange.Start),
-		TxHash:     txHash,
-	}, nil
+	return pointers, nil
 }
 
-func (c *CelestiaClient) GetSharesByNamespace(pointer *CelestiaPointer) ([]share.Share, error) {
-	ctx := context.Background()
-
-	// 1. Namespace
-	// ns, err := openshare.NewBlobNamespaceV0([]byte(c.Namespace()))
-	// if err != nil {
-	// 	return nil, fmt.Errorf("GetShares: failed to get namespace: %w", err)
-	// }
+// GetSharesByNamespace returns the shares in the pointers range from the
+// Celestia DA network, checking they are in the namespace. Several bundles
+// may be published at the same height, so all the namespace data can't be
+// used.
+func (c *CelestiaClient) GetSharesByNamespace(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error) {
 	ns, err := share.NewV0Namespace([]byte(c.Namespace()))
 	if err != nil {
 		return nil, fmt.Errorf("GetShares: failed to get namespace: %w", err)
 	}
 
-	// 3. Get the shares
-	//s, err := c.client.Share.GetSharesByNamespace(ctx, h, ns)
-	nsData, err := c.client.Share.GetNamespaceData(ctx, pointer.Height, ns)
+	res, err := c.client.Share.GetRange(ctx, pointer.Height, int(pointer.ShareStart), int(pointer.ShareStart+pointer.ShareLen))
 	if err != nil {
-		return nil, fmt.Errorf("GetShares: failed to get namespace data: %w", err)
+		if isSharesMissing(err) {
+			return nil, fmt.Errorf("GetShares: %w: %w", ErrSharesMissing, err)
+		}
+		c.metrics.RPCError(metrics.BackendCelestia)
+		return nil, fmt.Errorf("GetShares: failed to get share range: %w", err)
 	}
 
-	// s, err := c.openrpcClient.Share.GetSharesByNamespace(ctx, h, ns)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("GetShares: failed to get shares: %w", err)
-	// }
+	for _, s := range res.Shares {
+		if !s.Namespace().Equals(ns) {
+			return nil, fmt.Errorf("GetShares: %w: share in namespace %x, expected %x", ErrSharesMissing, s.Namespace().ID(), ns.ID())
+		}
+	}
 
-	return utils.NSSharesToShares(nsData.Flatten()), nil
+	return res.Shares, nil
 }
 
-func (c *CelestiaClient) GetSharesByPointer(pointer *CelestiaPointer) ([]share.Share, error) {
-	ctx := context.Background()
+// isSharesMissing returns true if the error from the Celestia node says the
+// requested shares do not exist. Errors lose their type over the RPC, so
+// they are matched by message.
+func isSharesMissing(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, shwap.ErrNotFound.Error()) || strings.Contains(msg, shwap.ErrOutOfBounds.Error())
+}
+
+func (c *CelestiaClient) GetSharesByPointer(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error) {
 
 	proof, err := c.trpc.ProveShares(ctx, pointer.Height, pointer.ShareStart, pointer.ShareStart+pointer.ShareLen)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
 	return utils.BytesToShares(proof.Data)
 }
 
-func (c *CelestiaClient) GetShareProof(celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error) {
-	ctx := context.Background()
+func (c *CelestiaClient) GetShareProof(ctx context.Context, celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error) {
 
 	shareStart := celestiaPointer.ShareStart + uint64(shareIndex)
 	shareEnd := celestiaPointer.ShareStart + uint64(shareIndex+1)
@@ -364,6 +540,7 @@ func (c *CelestiaClient) GetShareProof(celestiaPointer *CelestiaPointer, shareIn
 	// Get the shares proof
 	sharesProofs, err := c.trpc.ProveShares(ctx, celestiaPointer.Height, shareStart, shareEnd)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
@@ -375,8 +552,7 @@ func (c *CelestiaClient) GetShareProof(celestiaPointer *CelestiaPointer, shareIn
 	return &sharesProofs, nil
 }
 
-func (c *CelestiaClient) GetSharesProof(celPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error) {
-	ctx := context.Background()
+func (c *CelestiaClient) GetSharesProof(ctx context.Context, celPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error) {
 
 	shareStart := celPointer.ShareStart + uint64(sharePointer.StartShare)
 	shareEnd := celPointer.ShareStart + uint64(sharePointer.EndShare()+1)
@@ -384,6 +560,7 @@ func (c *CelestiaClient) GetSharesProof(celPointer *CelestiaPointer, sharePointe
 	// Get the shares proof
 	sharesProofs, err := c.trpc.ProveShares(ctx, celPointer.Height, shareStart, shareEnd)
 	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
 		return nil, err
 	}
 
@@ -395,6 +572,22 @@ func (c *CelestiaClient) GetSharesProof(celPointer *CelestiaPointer, sharePointe
 	return &sharesProofs, nil
 }
 
+func (c *CelestiaClient) PingNode(ctx context.Context) error {
+	if _, err := c.client.Header.LocalHead(ctx); err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
+		return err
+	}
+	return nil
+}
+
+func (c *CelestiaClient) PingTendermint(ctx context.Context) error {
+	if _, err := c.trpc.Status(ctx); err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
+		return err
+	}
+	return nil
+}
+
 // MOCK CLINT FOR TESTING
 
 var _ Celestia = &celestiaMock{}
@@ -424,25 +617,29 @@ func (c *celestiaMock) Namespace() string {
 	return c.namespace
 }
 
-func (c *celestiaMock) PublishBundle(blocks Bundle) (*CelestiaPointer, float64, error) {
+func (c *celestiaMock) PublishBundles(ctx context.Context, bundles []Bundle) ([]*CelestiaPointer, *CelestiaFee, error) {
 	c.height++
 
-	// use the first block's hash as the data root
-	hash := blocks.Blocks[0].Hash()
-	c.blocks[hash] = blocks
-
-	c.pointers[hash] = &CelestiaPointer{
-		Height:     c.height,
-		ShareStart: 0,
-		ShareLen:   uint64(len(blocks.Blocks)),
-		TxHash:     hash,
+	pointers := make([]*CelestiaPointer, len(bundles))
+	for i, blocks := range bundles {
+		// use the first block's hash as the data root
+		hash := blocks.Blocks[0].Hash()
+		c.blocks[hash] = blocks
+
+		c.pointers[hash] = &CelestiaPointer{
+			Height:     c.height,
+			ShareStart: 0,
+			ShareLen:   uint64(len(blocks.Blocks)),
+			TxHash:     hash,
+		}
+		pointers[i] = c.pointers[hash]
 	}
 
-	return c.pointers[hash], 0, nil
+	return pointers, &CelestiaFee{}, nil
 }
 
 // returns a mock proof, cannot be used for verification
-func (c *celestiaMock) GetProof(pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error) {
+func (c *celestiaMock) GetProof(ctx context.Context, pointer *CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*CelestiaProof, error) {
 	if !c.fakeProof {
 		return nil, fmt.Errorf("failed")
 	}
@@ -450,7 +647,7 @@ func (c *celestiaMock) GetProof(pointer *CelestiaPointer, startBlock uint64, end
 		Nonce: big.NewInt(0),
 		Tuple: &blobstreamXContract.DataRootTuple{
 			Height:   new(big.Int).SetUint64(pointer.Height),
-			DataRoot: pointer.Commitment,
+			DataRoot: pointer.DataRoot,
 		},
 		WrappedProof: &challengeContract.BinaryMerkleProof{
 			SideNodes: make([][32]byte, 0),
@@ -460,22 +657,30 @@ func (c *celestiaMock) GetProof(pointer *CelestiaPointer, startBlock uint64, end
 	}, nil
 }
 
-func (c *celestiaMock) GetSharesByNamespace(pointer *CelestiaPointer) ([]share.Share, error) {
+func (c *celestiaMock) GetSharesByNamespace(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error) {
 	return nil, nil
 }
 
-func (c *celestiaMock) GetSharesProof(celestiaPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error) {
+func (c *celestiaMock) GetSharesProof(ctx context.Context, celestiaPointer *CelestiaPointer, sharePointer *SharePointer) (*types.ShareProof, error) {
 	return nil, nil
 }
 
-func (c *celestiaMock) GetShareProof(celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error) {
+func (c *celestiaMock) GetShareProof(ctx context.Context, celestiaPointer *CelestiaPointer, shareIndex uint32) (*types.ShareProof, error) {
 	return nil, nil
 }
 
-func (c *celestiaMock) GetPointer(txHash common.Hash) (*CelestiaPointer, error) {
+func (c *celestiaMock) GetPointer(ctx context.Context, txHash common.Hash) (*CelestiaPointer, error) {
 	return c.pointers[txHash], nil
 }
 
-func (c *celestiaMock) GetSharesByPointer(pointer *CelestiaPointer) ([]share.Share, error) {
+func (c *celestiaMock) PingNode(ctx context.Context) error {
+	return nil
+}
+
+func (c *celestiaMock) PingTendermint(ctx context.Context) error {
+	return nil
+}
+
+func (c *celestiaMock) GetSharesByPointer(ctx context.Context, pointer *CelestiaPointer) ([]share.Share, error) {
 	return nil, nil
 }
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	blobtx "github.com/celestiaorg/go-square/v3/tx"
+	sdktx "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+const (
+	celestiaDenom         = "utia"
+	celestiaFeeHistory    = 5 // Number of recent blocks the gas price is estimated from.
+	celestiaMicroTIAInTIA = 1e6
+)
+
+// CelestiaFee is the fee paid for a pay for blob tx.
+type CelestiaFee struct {
+	GasPrice float64 // Gas price in utia.
+	GasLimit uint64
+	GasUsed  uint64
+	Amount   uint64 // Fee paid in utia, Celestia charges the full gas limit.
+}
+
+// TIA returns the fee paid in TIA.
+func (f *CelestiaFee) TIA() float64 {
+	if f == nil {
+		return 0
+	}
+	return float64(f.Amount) / celestiaMicroTIAInTIA
+}
+
+// suggestGasPrice returns the gas price to submit a blob with on the given
+// attempt, counting from 0. The price is taken from the gas API if set, or
+// else estimated from the fees paid in recent blocks, falling back to the
+// configured gas price. It is increased by GasPriceIncreasePercent, then by
+// RetryIncreasePercent on each retry, and capped at MaxGasPrice. A price of
+// 0 leaves it to the celestia node to estimate.
+func (c *CelestiaClient) suggestGasPrice(ctx context.Context, attempt int) float64 {
+	price, source := 0.0, "none"
+
+	// 1. query the gas API
+	if c.gasAPI != "" {
+		p, err := c.queryGasAPI(ctx)
+		if err != nil {
+			c.logger.Warn("Failed to query gas API, estimating gas price from recent blocks", "gasAPI", c.gasAPI, "error", err)
+		} else {
+			price, source = p, "gasAPI"
+		}
+	}
+
+	// 2. estimate from recent blocks
+	if price == 0 {
+		p, err := c.estimateGasPrice(ctx)
+		if err != nil {
+			c.logger.Debug("Failed to estimate gas price from recent blocks", "error", err)
+		} else {
+			price, source = p, "blocks"
+		}
+	}
+
+	// 3. fall back to the configured gas price
+	if price == 0 {
+		price, source = c.gasPrice, "config"
+	}
+	if price == 0 {
+		return 0
+	}
+
+	// 4. apply the increase, escalating on each retry
+	if c.gasPriceIncreasePercent != nil {
+		price *= 1 + float64(c.gasPriceIncreasePercent.Int64())/100
+	}
+	price *= math.Pow(1+float64(c.retryIncreasePercent)/100, float64(attempt))
+
+	// 5. cap at the max gas price
+	if c.maxGasPrice > 0 && price > c.maxGasPrice {
+		c.logger.Warn("Gas price over max gas price, capping", "gasPrice", price, "maxGasPrice", c.maxGasPrice)
+		price = c.maxGasPrice
+	}
+
+	c.logger.Debug("Picked Celestia gas price", "gasPrice", price, "source", source, "attempt", attempt)
+	return price
+}
+
+// queryGasAPI returns the gas price from the gas API. The API may return the
+// price as a JSON number or string, or as an object with a median price, as
+// the celenium gas price API does.
+func (c *CelestiaClient) queryGasAPI(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.gasAPI, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode gas API response: %w", err)
+	}
+
+	var obj struct {
+		Median json.RawMessage `json:"median"`
+	}
+	if err := json.Unmarshal(body, &obj); err == nil && obj.Median != nil {
+		body = obj.Median
+	}
+
+	var s string
+	if err := json.Unmarshal(body, &s); err == nil {
+		body = json.RawMessage(s)
+	}
+	price, err := strconv.ParseFloat(string(body), 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("unexpected gas API response: %s", body)
+	}
+
+	return price, nil
+}
+
+// estimateGasPrice returns the median gas price paid by the txs in the
+// recent blocks.
+func (c *CelestiaClient) estimateGasPrice(ctx context.Context) (float64, error) {
+	status, err := c.trpc.Status(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	var prices []float64
+	for height := status.SyncInfo.LatestBlockHeight; height > max(0, status.SyncInfo.LatestBlockHeight-celestiaFeeHistory); height-- {
+		block, err := c.trpc.Block(ctx, &height)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get block %d: %w", height, err)
+		}
+		for _, raw := range block.Block.Txs {
+			fee, err := decodeTxFee(raw)
+			if err != nil || fee.GasLimit == 0 {
+				continue
+			}
+			prices = append(prices, fee.GasPrice)
+		}
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("no txs in the last %d blocks", celestiaFeeHistory)
+	}
+
+	slices.Sort(prices)
+	return prices[len(prices)/2], nil
+}
+
+// decodeTxFee returns the fee set by a raw Celestia tx, unwrapping blob txs.
+func decodeTxFee(raw []byte) (*CelestiaFee, error) {
+	if btx, isBlob, err := blobtx.UnmarshalBlobTx(raw); isBlob && err == nil {
+		raw = btx.Tx
+	}
+
+	var tx sdktx.Tx
+	if err := tx.Unmarshal(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode tx: %w", err)
+	}
+	if tx.AuthInfo == nil || tx.AuthInfo.Fee == nil {
+		return nil, fmt.Errorf("tx has no fee")
+	}
+
+	fee := &CelestiaFee{
+		GasLimit: tx.AuthInfo.Fee.GasLimit,
+		Amount:   tx.AuthInfo.Fee.Amount.AmountOf(celestiaDenom).Uint64(),
+	}
+	if fee.GasLimit > 0 {
+		fee.GasPrice = float64(fee.Amount) / float64(fee.GasLimit)
+	}
+	return fee, nil
+}
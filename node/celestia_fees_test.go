@@ -0,0 +1,41 @@
+package node
+
+import (
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestGasPrice(t *testing.T) {
+	body := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := &CelestiaClient{
+		logger:                  slog.Default(),
+		gasAPI:                  srv.URL,
+		httpClient:              srv.Client(),
+		gasPriceIncreasePercent: big.NewInt(10),
+		retryIncreasePercent:    50,
+		maxGasPrice:             0.004,
+	}
+
+	// 1. the gas API may return a number, a string or a celenium style object
+	for _, body = range []string{`0.002`, `"0.002"`, `{"slow":"0.001","median":"0.002","fast":"0.003"}`} {
+		price, err := c.queryGasAPI(t.Context())
+		require.NoError(t, err, body)
+		assert.InDelta(t, 0.002, price, 1e-9, body)
+	}
+
+	// 2. the increase is applied, and escalated on each retry up to the max
+	assert.InDelta(t, 0.0022, c.suggestGasPrice(t.Context(), 0), 1e-9)
+	assert.InDelta(t, 0.0033, c.suggestGasPrice(t.Context(), 1), 1e-9)
+	assert.InDelta(t, 0.004, c.suggestGasPrice(t.Context(), 2), 1e-9)
+}
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/celestiaorg/celestia-node/blob"
+	"github.com/celestiaorg/go-square/v3/share"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"hummingbird/metrics"
+	"hummingbird/utils"
+)
+
+const (
+	inclusionPollMin = time.Second     // First delay between inclusion polls.
+	inclusionPollMax = 8 * time.Second // Max delay between inclusion polls, doubling from the first.
+
+	// inclusionGraceTimeouts is the number of inclusion timeouts a submitted
+	// tx is waited for, after which it is treated as dropped if the node
+	// does not know it.
+	inclusionGraceTimeouts = 5
+)
+
+// Statuses of a Celestia tx, as returned by the tx_status endpoint.
+const (
+	txStatusUnknown   = "UNKNOWN"
+	txStatusCommitted = "COMMITTED"
+	txStatusEvicted   = "EVICTED"
+	txStatusRejected  = "REJECTED"
+)
+
+var (
+	// errInclusionTimeout is returned when polling for a tx or blob times out.
+	errInclusionTimeout = errors.New("timed out")
+	// errBlobTxDropped is returned when a pay for blobs tx is known to have
+	// failed or been dropped, so the blobs must be submitted again.
+	errBlobTxDropped = errors.New("pay for blobs tx failed or was dropped")
+)
+
+// pollInclusion calls poll with backoff until it returns true, or the
+// inclusion timeout passes. An error returned with true is not retried.
+func (c *CelestiaClient) pollInclusion(ctx context.Context, what string, poll func(ctx context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, c.inclusionTimeout)
+	defer cancel()
+
+	delay := inclusionPollMin
+	for attempt := 1; ; attempt++ {
+		done, err := poll(ctx)
+		if done {
+			return err
+		}
+		c.logger.Debug("Waiting for "+what, "attempt", attempt, "delay", delay, "error", err)
+
+		if ctx.Err() != nil || utils.Sleep(ctx, delay) != nil {
+			return fmt.Errorf("%w after %s waiting for %s: %w", errInclusionTimeout, c.inclusionTimeout, what, err)
+		}
+		delay = min(2*delay, inclusionPollMax)
+	}
+}
+
+// waitForInclusion waits for the pay for blobs tx to be included, returning
+// the block it is in and its index in the block. If the height is known
+// from the submission response the block is read directly, otherwise the
+// tx is looked up in the Tendermint indexer, which may lag behind. If the
+// tx status says it failed or was dropped, errBlobTxDropped is returned.
+func (c *CelestiaClient) waitForInclusion(ctx context.Context, txHash common.Hash, height int64) (*types.Block, uint32, error) {
+	var block *types.Block
+	var index uint32
+
+	err := c.pollInclusion(ctx, "tx inclusion", func(ctx context.Context) (bool, error) {
+		// 0. stop if the tx failed or was dropped, the status is best effort
+		// as not every node serves it
+		if status, err := c.trpc.TxStatus(ctx, txHash.Bytes()); err == nil {
+			if err := checkTxStatus(status); err != nil {
+				return true, err
+			}
+			if status.Status == txStatusCommitted && height == 0 {
+				height = status.Height
+			}
+		}
+
+		// 1. find the height of the tx, if not known
+		if height == 0 {
+			tx, err := c.trpc.Tx(ctx, txHash.Bytes(), false)
+			if err != nil {
+				return false, fmt.Errorf("failed to get tx: %w", err)
+			}
+			height = tx.Height
+		}
+
+		// 2. find the tx in the block at that height
+		res, err := c.trpc.Block(ctx, &height)
+		if err != nil {
+			return false, fmt.Errorf("failed to get block %d: %w", height, err)
+		}
+		for i, tx := range res.Block.Txs {
+			if bytes.Equal(tx.Hash(), txHash.Bytes()) {
+				block, index = res.Block, uint32(i)
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("tx not in block %d", height)
+	})
+	if err != nil {
+		c.metrics.RPCError(metrics.BackendCelestia)
+		return nil, 0, err
+	}
+
+	c.logger.Debug("Blob tx included", "tx_hash", txHash.Hex(), "height", block.Height, "index", index)
+	return block, index, nil
+}
+
+// checkTxStatus returns errBlobTxDropped if the tx status says the tx was
+// evicted from or rejected by the mempool, or was committed but failed.
+func checkTxStatus(status *ctypes.ResultTxStatus) error {
+	switch {
+	case status.Status == txStatusEvicted, status.Status == txStatusRejected:
+		return fmt.Errorf("%w: tx %s: %s", errBlobTxDropped, status.Status, status.Error)
+	case status.Status == txStatusCommitted && status.ExecutionCode != 0:
+		return fmt.Errorf("%w: tx failed with code %d: %s", errBlobTxDropped, status.ExecutionCode, status.Error)
+	}
+	return nil
+}
+
+// isTxUnknown returns true if the tx status says the node does not know the
+// tx, or the status can't be read, so nothing shows it may still be
+// included.
+func (c *CelestiaClient) isTxUnknown(ctx context.Context, txHash common.Hash) bool {
+	status, err := c.trpc.TxStatus(ctx, txHash.Bytes())
+	return err != nil || status.Status == txStatusUnknown
+}
+
+// waitForBlobs waits for every blob to be retrievable by its commitment
+// from the DA network, and checks it is where its pointer says it is, so a
+// pointer is only returned for data that can be read back.
+func (c *CelestiaClient) waitForBlobs(ctx context.Context, squareSize uint64, pointers []*CelestiaPointer, blobs []*blob.Blob) error {
+	ns, err := share.NewV0Namespace([]byte(c.Namespace()))
+	if err != nil {
+		return err
+	}
+
+	for i, b := range blobs {
+		pointer := pointers[i]
+		err := c.pollInclusion(ctx, "blob availability", func(ctx context.Context) (bool, error) {
+			got, err := c.client.Blob.Get(ctx, pointer.Height, ns, b.Commitment)
+			if err != nil {
+				return false, fmt.Errorf("failed to get blob %d: %w", i, err)
+			}
+			return true, verifyBlob(got, b, pointer, squareSize)
+		})
+		if err != nil {
+			c.metrics.RPCError(metrics.BackendCelestia)
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyBlob checks the blob got from the Celestia node is the blob that
+// was published, starting at the pointers share. The node gives the index of
+// the first share in the extended square, with rows twice as wide as the
+// original square the pointer indexes.
+func verifyBlob(got, want *blob.Blob, pointer *CelestiaPointer, squareSize uint64) error {
+	if !bytes.Equal(got.Commitment, want.Commitment) {
+		return fmt.Errorf("blob commitment %x, expected %x", got.Commitment, want.Commitment)
+	}
+	if !bytes.Equal(got.Data(), want.Data()) {
+		return fmt.Errorf("blob %x data does not match the published data", want.Commitment)
+	}
+
+	if got.Index() < 0 || squareSize == 0 {
+		return nil
+	}
+	row, col := uint64(got.Index())/(2*squareSize), uint64(got.Index())%(2*squareSize)
+	if start := row*squareSize + col; start != pointer.ShareStart {
+		return fmt.Errorf("blob %x starts at share %d, expected %d", want.Commitment, start, pointer.ShareStart)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollInclusion(t *testing.T) {
+	c := &CelestiaClient{logger: slog.Default(), inclusionTimeout: 5 * time.Second}
+	errNotFound := errors.New("tx not found")
+
+	// 1. polling is retried until the tx is found
+	attempts := 0
+	err := c.pollInclusion(t.Context(), "tx", func(ctx context.Context) (bool, error) {
+		attempts++
+		if attempts < 2 {
+			return false, errNotFound
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// 2. an error returned with true is not retried
+	errMismatch := errors.New("blob mismatch")
+	attempts = 0
+	err = c.pollInclusion(t.Context(), "blob", func(ctx context.Context) (bool, error) {
+		attempts++
+		return true, errMismatch
+	})
+	require.ErrorIs(t, err, errMismatch)
+	assert.Equal(t, 1, attempts)
+
+	// 3. polling gives up at the timeout with the last error
+	c.inclusionTimeout = 100 * time.Millisecond
+	err = c.pollInclusion(t.Context(), "tx", func(ctx context.Context) (bool, error) {
+		return false, errNotFound
+	})
+	require.ErrorIs(t, err, errNotFound)
+	require.ErrorIs(t, err, errInclusionTimeout)
+}
+
+func TestCheckTxStatus(t *testing.T) {
+	// 1. a pending or successfully committed tx is waited for
+	assert.NoError(t, checkTxStatus(&ctypes.ResultTxStatus{Status: "PENDING"}))
+	assert.NoError(t, checkTxStatus(&ctypes.ResultTxStatus{Status: txStatusUnknown}))
+	assert.NoError(t, checkTxStatus(&ctypes.ResultTxStatus{Status: txStatusCommitted, Height: 10}))
+
+	// 2. a dropped or failed tx is submitted again
+	assert.ErrorIs(t, checkTxStatus(&ctypes.ResultTxStatus{Status: txStatusEvicted}), errBlobTxDropped)
+	assert.ErrorIs(t, checkTxStatus(&ctypes.ResultTxStatus{Status: txStatusRejected, Error: "insufficient fee"}), errBlobTxDropped)
+	assert.ErrorIs(t, checkTxStatus(&ctypes.ResultTxStatus{Status: txStatusCommitted, ExecutionCode: 11}), errBlobTxDropped)
+}
@@ -30,6 +30,7 @@ type ChallengeDaInfo struct {
 	Challenger string   `pretty:"Challenger"`
 	Expiry     *big.Int `pretty:"Expiry"`
 	Status     uint8    `pretty:"Status"`
+	Claimed    bool     `pretty:"Claimed"`
 }
 
 // Helper to convert challenge status enum to string
@@ -54,4 +55,12 @@ type L2HeaderChallengeInfo struct {
 	ChallengeEnd *big.Int
 	Challenger   common.Address
 	Status       uint8
+	Claimed      bool
+}
+
+// ProvenWithdrawal is a withdrawal proven in LightLinkPortal.sol
+type ProvenWithdrawal struct {
+	OutputRoot    common.Hash
+	Timestamp     *big.Int
+	L2OutputIndex *big.Int
 }
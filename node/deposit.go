@@ -0,0 +1,235 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"hummingbird/node/lightlink/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DepositState is the progress of a deposit from L1 to LightLink.
+type DepositState string
+
+const (
+	DepositPending  DepositState = "pending"  // not found on LightLink yet, within the timeout
+	DepositIncluded DepositState = "included" // found on LightLink
+	DepositMissing  DepositState = "missing"  // not found on LightLink after the timeout
+)
+
+// Deposit is a deposit made on L1, read from a TransactionDeposited event
+// emitted by LightLinkPortal.sol.
+type Deposit struct {
+	SourceHash common.Hash // source hash of the L2 deposit tx
+	From       common.Address
+	To         common.Address
+	Mint       *big.Int
+	Value      *big.Int
+	Gas        uint64
+	IsCreation bool
+	Data       []byte
+
+	L1TxHash    common.Hash
+	L1BlockHash common.Hash
+	L1Height    uint64
+	L1Time      time.Time
+	LogIndex    uint
+
+	// set by TrackDeposits
+	State    DepositState
+	L2TxHash common.Hash
+	L2Height uint64
+}
+
+// GetDeposits returns the deposits made in LightLinkPortal.sol in the given
+// L1 block range, inclusive.
+func (n *Node) GetDeposits(ctx context.Context, start, end uint64) ([]*Deposit, error) {
+	events, err := n.Ethereum.FilterTransactionDeposited(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter TransactionDeposited events: %w", err)
+	}
+	defer events.Close()
+
+	deposits := []*Deposit{}
+	times := map[uint64]time.Time{}
+	for events.Next() {
+		ev := events.Event
+		if ev.Version.Sign() != 0 {
+			return nil, fmt.Errorf("unsupported deposit version %d in tx %s", ev.Version, ev.Raw.TxHash.Hex())
+		}
+
+		// opaqueData is abi.encodePacked(mint, value, gasLimit, isCreation, data)
+		opaque := ev.OpaqueData
+		if len(opaque) < 73 {
+			return nil, fmt.Errorf("invalid deposit data in tx %s", ev.Raw.TxHash.Hex())
+		}
+
+		l1Time, ok := times[ev.Raw.BlockNumber]
+		if !ok {
+			l1Time, err = n.Ethereum.GetBlockTime(ctx, ev.Raw.BlockNumber)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get time of L1 block %d: %w", ev.Raw.BlockNumber, err)
+			}
+			times[ev.Raw.BlockNumber] = l1Time
+		}
+
+		deposits = append(deposits, &Deposit{
+			SourceHash:  DepositSourceHash(ev.Raw.BlockHash, ev.Raw.Index),
+			From:        ev.From,
+			To:          ev.To,
+			Mint:        new(big.Int).SetBytes(opaque[0:32]),
+			Value:       new(big.Int).SetBytes(opaque[32:64]),
+			Gas:         binary.BigEndian.Uint64(opaque[64:72]),
+			IsCreation:  opaque[72] != 0,
+			Data:        common.CopyBytes(opaque[73:]),
+			L1TxHash:    ev.Raw.TxHash,
+			L1BlockHash: ev.Raw.BlockHash,
+			L1Height:    ev.Raw.BlockNumber,
+			L1Time:      l1Time,
+			LogIndex:    ev.Raw.Index,
+			State:       DepositPending,
+		})
+	}
+	if err := events.Error(); err != nil {
+		return nil, fmt.Errorf("failed to filter TransactionDeposited events: %w", err)
+	}
+
+	return deposits, nil
+}
+
+// depositWindow is the number of L2 blocks fetched at once while searching
+// for deposit txs.
+const depositWindow = uint64(100)
+
+// TrackDeposits searches LightLink for the deposit tx of each deposit, from
+// the first L2 block after the earliest deposit, and sets its state. A
+// deposit not found once LightLink is timeout past the deposit is missing.
+//
+// Deposit txs are matched by source hash. Legacy deposit txs carry no
+// source hash, so are matched by to, value, gas and data instead.
+func (n *Node) TrackDeposits(ctx context.Context, deposits []*Deposit, timeout time.Duration) error {
+	if len(deposits) == 0 {
+		return nil
+	}
+
+	// 1. find the range of L2 blocks the deposits can be in
+	earliest, latest := deposits[0].L1Time, deposits[0].L1Time
+	for _, d := range deposits {
+		if d.L1Time.Before(earliest) {
+			earliest = d.L1Time
+		}
+		if d.L1Time.After(latest) {
+			latest = d.L1Time
+		}
+	}
+
+	height, err := n.LightLink.GetHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get L2 height: %w", err)
+	}
+	start, err := n.findL2BlockByTime(ctx, earliest, height)
+	if err != nil {
+		return err
+	}
+
+	// 2. search the L2 blocks for the deposit txs, until every deposit is
+	// found or LightLink is past the timeout of the latest deposit
+	pending := map[*Deposit]bool{}
+	for _, d := range deposits {
+		d.State = DepositPending
+		pending[d] = true
+	}
+
+	var l2Time time.Time
+scan:
+	for from := start; from <= height && len(pending) > 0; from += depositWindow {
+		to := min(from+depositWindow-1, height)
+		blocks, err := n.LightLink.GetBlocks(ctx, from, to, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get L2 blocks %d to %d: %w", from, to, err)
+		}
+
+		for _, block := range blocks {
+			for _, tx := range block.Transactions() {
+				if !tx.IsDepositTx() && !tx.IsDepositTxV2() {
+					continue
+				}
+				for d := range pending {
+					if !matchDeposit(d, tx) {
+						continue
+					}
+					d.State = DepositIncluded
+					d.L2TxHash = tx.Hash()
+					d.L2Height = block.NumberU64()
+					delete(pending, d)
+					break
+				}
+			}
+
+			l2Time = time.Unix(int64(block.Time()), 0)
+			if len(pending) == 0 || l2Time.After(latest.Add(timeout)) {
+				break scan
+			}
+		}
+	}
+
+	// 3. deposits not found once LightLink is past their timeout are missing
+	for d := range pending {
+		if l2Time.After(d.L1Time.Add(timeout)) {
+			d.State = DepositMissing
+		}
+	}
+
+	return nil
+}
+
+// findL2BlockByTime returns the first L2 block at or after the given time,
+// or height+1 if there is none yet.
+func (n *Node) findL2BlockByTime(ctx context.Context, t time.Time, height uint64) (uint64, error) {
+	lo, hi := uint64(0), height+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		block, err := n.LightLink.GetBlock(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get L2 block %d: %w", mid, err)
+		}
+		if time.Unix(int64(block.Time()), 0).Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// matchDeposit returns true if tx is the L2 deposit tx of the deposit.
+func matchDeposit(d *Deposit, tx *types.Transaction) bool {
+	if tx.IsDepositTxV2() {
+		return tx.SourceHash() == d.SourceHash
+	}
+
+	// legacy deposit txs carry no source hash
+	if d.IsCreation != (tx.To() == nil) || (tx.To() != nil && *tx.To() != d.To) {
+		return false
+	}
+	return tx.Value().Cmp(d.Value) == 0 && tx.Gas() == d.Gas && bytes.Equal(tx.Data(), d.Data)
+}
+
+// DepositSourceHash returns the source hash of the L2 deposit tx for a user
+// deposit, keccak256(bytes32(0) ++ keccak256(l1BlockHash ++ bytes32(logIndex))).
+func DepositSourceHash(l1BlockHash common.Hash, logIndex uint) common.Hash {
+	var index common.Hash
+	binary.BigEndian.PutUint64(index[24:], uint64(logIndex))
+	depositID := crypto.Keccak256Hash(l1BlockHash[:], index[:])
+
+	var domain common.Hash // user deposits are domain 0
+	return crypto.Keccak256Hash(domain[:], depositID[:])
+}
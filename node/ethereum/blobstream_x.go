@@ -12,22 +12,22 @@ import (
 
 type BlobstreamX interface {
 	FilterDataCommitmentStored(opts *bind.FilterOpts, startBlock []uint64, endBlock []uint64, dataCommitment [][32]byte) (*blobstreamXContract.BlobstreamXDataCommitmentStoredIterator, error)
-	DAVerify(proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error)
-	GetBlobstreamCommitment(height int64) (*blobstreamXContract.BlobstreamXDataCommitmentStored, error)
+	DAVerify(ctx context.Context, proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error)
+	GetBlobstreamCommitment(ctx context.Context, height int64) (*blobstreamXContract.BlobstreamXDataCommitmentStored, error)
 }
 
 func (c *Client) FilterDataCommitmentStored(opts *bind.FilterOpts, startBlock []uint64, endBlock []uint64, dataCommitment [][32]byte) (*blobstreamXContract.BlobstreamXDataCommitmentStoredIterator, error) {
 	return c.blobstreamX.FilterDataCommitmentStored(opts, startBlock, endBlock, dataCommitment)
 }
 
-func (c *Client) DAVerify(proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error) {
-	return c.blobstreamX.VerifyAttestation(nil, proofNonce, tuple, proof)
+func (c *Client) DAVerify(ctx context.Context, proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error) {
+	return c.blobstreamX.VerifyAttestation(callOpts(ctx), proofNonce, tuple, proof)
 }
 
 // GetBlobstreamCommitment returns the commitment for the given celestia height.
 // see https://docs.celestia.org/developers/blobstream-proof-queries
-func (c *Client) GetBlobstreamCommitment(height int64) (*blobstreamXContract.BlobstreamXDataCommitmentStored, error) {
-	scanRanges, err := c.GetChallengeWindowBlockRanges()
+func (c *Client) GetBlobstreamCommitment(ctx context.Context, height int64) (*blobstreamXContract.BlobstreamXDataCommitmentStored, error) {
+	scanRanges, err := c.GetChallengeWindowBlockRanges(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get challenge window block ranges: %w", err)
 	}
@@ -40,7 +40,7 @@ func (c *Client) GetBlobstreamCommitment(height int64) (*blobstreamXContract.Blo
 
 		// get all events
 		events, err := c.blobstreamX.FilterDataCommitmentStored(&bind.FilterOpts{
-			Context: context.Background(),
+			Context: ctx,
 			Start:   scanRange[0],
 			End:     &scanRange[1],
 		}, nil, nil, nil)
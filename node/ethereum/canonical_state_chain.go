@@ -2,30 +2,37 @@ package ethereum
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	canonicalStateChainContract "hummingbird/node/contracts/CanonicalStateChain.sol"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type CanonicalStateChain interface {
-	GetRollupHeight() (uint64, error)                                                                         // Get the current rollup block height.
-	GetHeight() (uint64, error)                                                                               // Get the current block height of the Ethereum network.
-	GetRollupHead() (canonicalStateChainContract.CanonicalStateChainHeader, error)                            // Get the latest rollup block header in the CanonicalStateChain.sol contract.
-	PushRollupHead(header *canonicalStateChainContract.CanonicalStateChainHeader) (*types.Transaction, error) // Push a new rollup block header to the CanonicalStateChain.sol contract.
-	GetRollupHeader(index uint64) (canonicalStateChainContract.CanonicalStateChainHeader, error)              // Get the rollup block header at the given index from the CanonicalStateChain.sol contract.
-	GetRollupHeaderByHash(hash common.Hash) (canonicalStateChainContract.CanonicalStateChainHeader, error)    // Get the rollup block header with the given hash from the CanonicalStateChain.sol contract.
-	Wait(txHash common.Hash) (*types.Receipt, error)                                                          // Wait for a transaction to be mined.
-	GetPublisher() (common.Address, error)                                                                    // Get the address of the publisher of the CanonicalStateChain.sol contract.
-	HashHeader(header *canonicalStateChainContract.CanonicalStateChainHeader) (common.Hash, error)            // Hash a rollup block header.
+	GetRollupHeight(ctx context.Context) (uint64, error)                                                                           // Get the current rollup block height.
+	GetHeight(ctx context.Context) (uint64, error)                                                                                 // Get the current block height of the Ethereum network.
+	GetBlockTime(ctx context.Context, height uint64) (time.Time, error)                                                            // Get the timestamp of the Ethereum block at the given height.
+	GetRollupHead(ctx context.Context) (canonicalStateChainContract.CanonicalStateChainHeader, error)                              // Get the latest rollup block header in the CanonicalStateChain.sol contract.
+	PushRollupHead(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (*types.Transaction, error) // Push a new rollup block header to the CanonicalStateChain.sol contract.
+	GetRollupHeader(ctx context.Context, index uint64) (canonicalStateChainContract.CanonicalStateChainHeader, error)              // Get the rollup block header at the given index from the CanonicalStateChain.sol contract.
+	GetRollupHeaderByHash(ctx context.Context, hash common.Hash) (canonicalStateChainContract.CanonicalStateChainHeader, error)    // Get the rollup block header with the given hash from the CanonicalStateChain.sol contract.
+	Wait(ctx context.Context, txHash common.Hash) (*types.Receipt, error)                                                          // Wait for a transaction to be mined.
+	WaitTx(ctx context.Context, nonce uint64, hashes []common.Hash, replaced func(common.Hash)) (*types.Receipt, error)            // Wait for a transaction sent before, e.g. before a restart, by its nonce or any of its hashes.
+	GetPublisher(ctx context.Context) (common.Address, error)                                                                      // Get the address of the publisher of the CanonicalStateChain.sol contract.
+	GetMaxPointers(ctx context.Context) (uint8, error)                                                                             // Get the max number of Celestia pointers in a rollup block header.
+	HashHeader(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (common.Hash, error)            // Hash a rollup block header.
+	// Filter the BlockAdded events emitted when a rollup block is pushed.
+	FilterBlockAdded(opts *bind.FilterOpts, blockNumber []*big.Int) (*canonicalStateChainContract.CanonicalStateChainBlockAddedIterator, error)
 }
 
 // GetRollupHeight returns the current rollup block height.
-func (c *Client) GetRollupHeight() (uint64, error) {
-	h, err := c.canonicalStateChain.ChainHead(nil)
+func (c *Client) GetRollupHeight(ctx context.Context) (uint64, error) {
+	h, err := c.canonicalStateChain.ChainHead(callOpts(ctx))
 	if err != nil {
 		return 0, err
 	}
@@ -33,60 +40,138 @@ func (c *Client) GetRollupHeight() (uint64, error) {
 	return h.Uint64(), nil
 }
 
-func (c *Client) GetHeight() (uint64, error) {
-	return c.client.BlockNumber(context.Background())
+func (c *Client) GetHeight(ctx context.Context) (uint64, error) {
+	return c.client.BlockNumber(ctx)
+}
+
+func (c *Client) GetBlockTime(ctx context.Context, height uint64) (time.Time, error) {
+	header, err := c.client.HeaderByNumber(ctx, new(big.Int).SetUint64(height))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(int64(header.Time), 0), nil
 }
 
 // GetRollupHead returns the latest rollup block header.
-func (c *Client) GetRollupHead() (canonicalStateChainContract.CanonicalStateChainHeader, error) {
-	return c.canonicalStateChain.GetHead(nil)
+func (c *Client) GetRollupHead(ctx context.Context) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	return c.canonicalStateChain.GetHead(callOpts(ctx))
 }
 
 // PushRollupHead pushes a new rollup block header.
-func (c *Client) PushRollupHead(header *canonicalStateChainContract.CanonicalStateChainHeader) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) PushRollupHead(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
-	return c.canonicalStateChain.PushBlock(transactor, *header)
+	return c.manage(c.canonicalStateChain.PushBlock(transactor, *header))
 }
 
 // GetRollupHeader returns the rollup block header at the given index.
-func (c *Client) GetRollupHeader(index uint64) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
-	return c.canonicalStateChain.GetHeaderByNum(nil, big.NewInt(int64(index)))
+func (c *Client) GetRollupHeader(ctx context.Context, index uint64) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	return c.canonicalStateChain.GetHeaderByNum(callOpts(ctx), big.NewInt(int64(index)))
 }
 
 // GetRollupHeaderByHash returns the rollup block header with the given hash.
-func (c *Client) GetRollupHeaderByHash(hash common.Hash) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
-	return c.canonicalStateChain.GetHeaderByHash(nil, hash)
+func (c *Client) GetRollupHeaderByHash(ctx context.Context, hash common.Hash) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	return c.canonicalStateChain.GetHeaderByHash(callOpts(ctx), hash)
+}
+
+// Wait waits for a transaction to be mined and returns the receipt. Rollup,
+// defence and shares txs still pending after ReplaceAfter are replaced with
+// a higher fee, and the receipt of whichever of them is mined is returned.
+func (c *Client) Wait(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return c.wait(ctx, txHash, nil)
 }
 
-// Wait waits for a transaction to be mined and returns the receipt
-func (c *Client) Wait(txHash common.Hash) (*types.Receipt, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+// WaitTx waits for a rollup, defence or shares tx sent before, by its nonce
+// and the hashes of the original tx and its replacements, e.g. as persisted
+// before a restart. The tx is replaced like in Wait, and replaced is called
+// with the hash of each replacement sent. If the nonce is used by a tx that
+// is none of them, ErrNonceUsed is returned.
+func (c *Client) WaitTx(ctx context.Context, nonce uint64, hashes []common.Hash, replaced func(common.Hash)) (*types.Receipt, error) {
+	if len(hashes) == 0 {
+		return nil, errors.New("no transaction hashes to wait for")
+	}
+	if _, ok := c.txs.get(hashes[0]); !ok && !c.opts.DryRun {
+		c.txs.adopt(nonce, hashes, c.lastKnownTx(ctx, hashes))
+	}
+	return c.wait(ctx, hashes[0], replaced)
+}
+
+func (c *Client) wait(ctx context.Context, txHash common.Hash, replaced func(common.Hash)) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
 	defer cancel()
 
+	start := time.Now()
+	defer func() { c.opts.Metrics.ObserveWait(time.Since(start)) }()
+
 	for {
 		c.logger.Debug("Waiting for transaction to be mined...", "txHash", txHash.Hex())
-		// try to get the receipt
-		receipt, err := c.client.TransactionReceipt(ctx, txHash)
-		if err != nil {
-			// if the receipt is not found, keep checking
-			if err.Error() == "not found" {
-				time.Sleep(10 * time.Second)
-				continue
+
+		rec, managed := c.txs.get(txHash)
+		hashes := []common.Hash{txHash}
+		if managed {
+			hashes = rec.hashes()
+		}
+
+		// check the nonce before the receipts, so a receipt of one of the
+		// txs is found if it was that tx that used the nonce
+		nonceUsed := managed && c.nonceUsed(ctx, rec.Nonce)
+
+		// try to get the receipt of the tx or any of its replacements
+		for _, hash := range hashes {
+			receipt, err := c.client.TransactionReceipt(ctx, hash)
+			if err != nil {
+				// if the receipt is not found, keep checking
+				if err.Error() == "not found" {
+					continue
+				}
+				return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+			}
+			if managed {
+				c.txs.mined(txHash, receipt)
+			}
+			c.logger.Debug("Transaction mined successfully!", "txHash", txHash.Hex(), "minedTxHash", hash.Hex())
+			return receipt, nil
+		}
+
+		if nonceUsed {
+			return nil, fmt.Errorf("transaction %s: %w", txHash.Hex(), ErrNonceUsed)
+		}
+
+		if managed && c.opts.ReplaceAfter > 0 && time.Since(rec.sentAt) >= c.opts.ReplaceAfter {
+			tx, err := c.replace(ctx, rec)
+			if err != nil {
+				c.logger.Warn("Failed to replace pending transaction", "txHash", txHash.Hex(), "error", err)
+			} else if replaced != nil {
+				replaced(tx.Hash())
 			}
-			return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
 		}
-		c.logger.Debug("Transaction mined successfully!", "txHash", txHash.Hex())
-		return receipt, nil
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("stopped waiting for transaction %s: %w", txHash.Hex(), ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
 	}
 }
 
-func (c *Client) GetPublisher() (common.Address, error) {
-	return c.canonicalStateChain.Publisher(nil)
+func (c *Client) GetPublisher(ctx context.Context) (common.Address, error) {
+	return c.canonicalStateChain.Publisher(callOpts(ctx))
+}
+
+// GetMaxPointers returns the max number of Celestia pointers, and so
+// bundles, a rollup block header can hold.
+func (c *Client) GetMaxPointers(ctx context.Context) (uint8, error) {
+	return c.canonicalStateChain.MaxPointers(callOpts(ctx))
+}
+
+func (c *Client) HashHeader(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (common.Hash, error) {
+	return c.canonicalStateChain.CalculateHeaderHash(callOpts(ctx), *header)
 }
 
-func (c *Client) HashHeader(header *canonicalStateChainContract.CanonicalStateChainHeader) (common.Hash, error) {
-	return c.canonicalStateChain.CalculateHeaderHash(nil, *header)
+// FilterBlockAdded returns the BlockAdded events in the given L1 block range.
+func (c *Client) FilterBlockAdded(opts *bind.FilterOpts, blockNumber []*big.Int) (*canonicalStateChainContract.CanonicalStateChainBlockAddedIterator, error) {
+	return c.canonicalStateChain.FilterBlockAdded(opts, blockNumber)
 }
@@ -1,6 +1,7 @@
 package ethereum
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 
@@ -11,34 +12,34 @@ import (
 )
 
 type ChainOracle interface {
-	ProvideShares(rblock common.Hash, pointerIndex uint8, shareProof *chainOracleContract.SharesProof) (*types.Transaction, error)
-	ProvideHeader(rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error)
-	ProvideLegacyTx(rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error)
-	AlreadyProvidedShares(rblock common.Hash, shareData [][]byte) (bool, error)
-	AlreadyProvidedHeader(l2Hash common.Hash) (bool, error)
+	ProvideShares(ctx context.Context, rblock common.Hash, pointerIndex uint8, shareProof *chainOracleContract.SharesProof) (*types.Transaction, error)
+	ProvideHeader(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error)
+	ProvideLegacyTx(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error)
+	AlreadyProvidedShares(ctx context.Context, rblock common.Hash, shareData [][]byte) (bool, error)
+	AlreadyProvidedHeader(ctx context.Context, l2Hash common.Hash) (bool, error)
 }
 
-func (c *Client) ProvideShares(rblock common.Hash, pointerIndex uint8, shareProof *chainOracleContract.SharesProof) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) ProvideShares(ctx context.Context, rblock common.Hash, pointerIndex uint8, shareProof *chainOracleContract.SharesProof) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
-	return c.chainLoader.ProvideShares(transactor, rblock, pointerIndex, *shareProof)
+	return c.manage(c.chainLoader.ProvideShares(transactor, rblock, pointerIndex, *shareProof))
 }
 
-func (c *Client) ProvideHeader(rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) ProvideHeader(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
 
-	sharekey, err := c.chainLoader.ShareKey(nil, rblock, shareData)
+	sharekey, err := c.chainLoader.ShareKey(callOpts(ctx), rblock, shareData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get share key: %w", err)
 	}
 
 	// check shares are found
-	s, err := c.chainLoader.Shares(nil, sharekey, big.NewInt(0))
+	s, err := c.chainLoader.Shares(callOpts(ctx), sharekey, big.NewInt(0))
 	if err != nil {
 		return nil, fmt.Errorf("failed checking shares were deployed: %w", err)
 	}
@@ -50,19 +51,19 @@ func (c *Client) ProvideHeader(rblock common.Hash, shareData [][]byte, ranges []
 	return c.chainLoader.ProvideHeader(transactor, sharekey, ranges)
 }
 
-func (c *Client) ProvideLegacyTx(rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) ProvideLegacyTx(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
 
-	sharekey, err := c.chainLoader.ShareKey(nil, rblock, shareData)
+	sharekey, err := c.chainLoader.ShareKey(callOpts(ctx), rblock, shareData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get share key: %w", err)
 	}
 
 	// check shares are found
-	s, err := c.chainLoader.Shares(nil, sharekey, big.NewInt(0))
+	s, err := c.chainLoader.Shares(callOpts(ctx), sharekey, big.NewInt(0))
 	if err != nil {
 		return nil, fmt.Errorf("failed checking shares were deployed: %w", err)
 	}
@@ -74,14 +75,14 @@ func (c *Client) ProvideLegacyTx(rblock common.Hash, shareData [][]byte, ranges
 	return c.chainLoader.ProvideLegacyTx(transactor, sharekey, ranges)
 }
 
-func (c *Client) AlreadyProvidedShares(rblock common.Hash, shareData [][]byte) (bool, error) {
-	sharekey, err := c.chainLoader.ShareKey(nil, rblock, shareData)
+func (c *Client) AlreadyProvidedShares(ctx context.Context, rblock common.Hash, shareData [][]byte) (bool, error) {
+	sharekey, err := c.chainLoader.ShareKey(callOpts(ctx), rblock, shareData)
 	if err != nil {
 		return false, fmt.Errorf("failed to get share key: %w", err)
 	}
 
 	// check shares are found
-	s, err := c.chainLoader.Shares(nil, sharekey, big.NewInt(0))
+	s, err := c.chainLoader.Shares(callOpts(ctx), sharekey, big.NewInt(0))
 	if err != nil {
 		return false, fmt.Errorf("failed checking shares were deployed: %w", err)
 	}
@@ -89,8 +90,8 @@ func (c *Client) AlreadyProvidedShares(rblock common.Hash, shareData [][]byte) (
 	return len(s) > 0, nil
 }
 
-func (c *Client) AlreadyProvidedHeader(l2Hash common.Hash) (bool, error) {
-	h, err := c.chainLoader.GetHeader(nil, l2Hash)
+func (c *Client) AlreadyProvidedHeader(ctx context.Context, l2Hash common.Hash) (bool, error) {
+	h, err := c.chainLoader.GetHeader(callOpts(ctx), l2Hash)
 	if err != nil {
 		return false, fmt.Errorf("failed to get header: %w", err)
 	}
@@ -1,6 +1,7 @@
 package ethereum
 
 import (
+	"context"
 	"fmt"
 	"hummingbird/node/contracts"
 	challengeContract "hummingbird/node/contracts/Challenge.sol"
@@ -9,39 +10,65 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 )
 
 type Challenge interface {
-	GetChallengeFee() (*big.Int, error)
-	GetDataRootInclusionChallenge(block common.Hash, pointerIndex uint8, shareIndex uint32) (contracts.ChallengeDaInfo, error)
-	ChallengeDataRootInclusion(index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error)
-	DefendDataRootInclusion(common.Hash, challengeContract.SharesProof) (*types.Transaction, error)
-	SettleDataRootInclusion(common.Hash) (*types.Transaction, error)
+	GetChallengeFee(ctx context.Context) (*big.Int, error)
+	GetDataRootInclusionChallenge(ctx context.Context, block common.Hash, pointerIndex uint8, shareIndex uint32) (contracts.ChallengeDaInfo, error)
+	ChallengeDataRootInclusion(ctx context.Context, index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error)
+	DefendDataRootInclusion(ctx context.Context, blockHash common.Hash, proof challengeContract.SharesProof) (*types.Transaction, error)
+	SettleDataRootInclusion(ctx context.Context, blockHash common.Hash) (*types.Transaction, error)
 	FilterChallengeDAUpdate(opts *bind.FilterOpts, _blockHash [][32]byte, _blockIndex []*big.Int, _status []uint8) (*challengeContract.ChallengeChallengeDAUpdateIterator, error)
-	DefendL2Header(common.Hash, common.Hash, common.Hash) (*types.Transaction, error)
-	GetL2HeaderChallengeHash(common.Hash, *big.Int) (common.Hash, error)
-	GetL2HeaderChallenge(common.Hash) (contracts.L2HeaderChallengeInfo, error)
+	ChallengeL2Header(ctx context.Context, rblockNum *big.Int, l2Num *big.Int) (*types.Transaction, error)
+	DefendL2Header(ctx context.Context, blockHash, rootHash, headerHash common.Hash) (*types.Transaction, error)
+	SettleL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (*types.Transaction, error)
+	InvalidateHeader(ctx context.Context, index uint64) (*types.Transaction, error)
+	GetL2HeaderChallengeHash(ctx context.Context, rblockHash common.Hash, l2Num *big.Int) (common.Hash, error)
+	GetL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (contracts.L2HeaderChallengeInfo, error)
 	FilterL2HeaderChallengeUpdate(opts *bind.FilterOpts, _blockHash [][32]byte, _blockIndex []*big.Int, _status []uint8) (*challengeContract.ChallengeL2HeaderChallengeUpdateIterator, error)
-	GetChallengeWindow() (*big.Int, error)
-	GetChallengeWindowBlockRanges() ([][]uint64, error)
+	WatchChallengeDAUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeChallengeDAUpdate, _blockHash [][32]byte, _pointerIndex []*big.Int, _status []uint8) (event.Subscription, error)
+	WatchL2HeaderChallengeUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeL2HeaderChallengeUpdate, challengeHash [][32]byte, l2Number []*big.Int, status []uint8) (event.Subscription, error)
+	GetChallengeWindow(ctx context.Context) (*big.Int, error)
+	GetChallengeWindowBlockRanges(ctx context.Context) ([][]uint64, error)
 	DataRootInclusionChallengeKey(opts *bind.CallOpts, blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (common.Hash, error)
-	ClaimDAChallengeReward(key common.Hash) (*common.Hash, error)
-	ClaimL2HeaderChallengeReward(key common.Hash) (*common.Hash, error)
+	ClaimDAChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error)
+	ClaimL2HeaderChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error)
+	GetDefender(ctx context.Context) (common.Address, error)
+	GetMaxBundleSize(ctx context.Context) (uint64, error)
 }
 
 var _ Challenge = &Client{} // Ensure Client implements Challenge
 
-func (c *Client) GetChallengeFee() (*big.Int, error) {
-	return c.challenge.ChallengeFee(nil)
+func (c *Client) GetChallengeFee(ctx context.Context) (*big.Int, error) {
+	return c.challenge.ChallengeFee(callOpts(ctx))
 }
 
-func (c *Client) GetDataRootInclusionChallenge(blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (contracts.ChallengeDaInfo, error) {
-	key, err := c.challenge.DataRootInclusionChallengeKey(nil, blockHash, pointerIndex, shareIndex)
+// GetDefender returns the address of the defender set in Challenge.sol.
+func (c *Client) GetDefender(ctx context.Context) (common.Address, error) {
+	return c.challenge.Defender(callOpts(ctx))
+}
+
+// GetMaxBundleSize returns the max number of L2 blocks a rollup block can
+// hold, set in Challenge.sol.
+func (c *Client) GetMaxBundleSize(ctx context.Context) (uint64, error) {
+	size, err := c.challenge.MaxBundleSize(callOpts(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if !size.IsUint64() {
+		return 0, fmt.Errorf("max bundle size %s overflows uint64", size)
+	}
+	return size.Uint64(), nil
+}
+
+func (c *Client) GetDataRootInclusionChallenge(ctx context.Context, blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (contracts.ChallengeDaInfo, error) {
+	key, err := c.challenge.DataRootInclusionChallengeKey(callOpts(ctx), blockHash, pointerIndex, shareIndex)
 	if err != nil {
 		return contracts.ChallengeDaInfo{}, fmt.Errorf("failed to get data root inclusion challenge key: %w", err)
 	}
 
-	res, err := c.challenge.DaChallenges(nil, key)
+	res, err := c.challenge.DaChallenges(callOpts(ctx), key)
 	if err != nil {
 		return contracts.ChallengeDaInfo{}, fmt.Errorf("failed to get data root inclusion challenge: %w", err)
 	}
@@ -51,24 +78,25 @@ func (c *Client) GetDataRootInclusionChallenge(blockHash common.Hash, pointerInd
 		Challenger: res.Challenger.Hex(),
 		Expiry:     res.Expiry,
 		Status:     res.Status,
+		Claimed:    res.Claimed,
 	}, nil
 }
 
-func (c *Client) ChallengeDataRootInclusion(index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error) {
-	transactor, err := c.transactor()
+func (c *Client) ChallengeDataRootInclusion(ctx context.Context, index uint64, pointerIndex uint8, shareIndex uint32) (*types.Transaction, common.Hash, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, common.Hash{}, fmt.Errorf("failed to create transactor: %w", err)
 	}
 
 	// set transactions fee
-	fee, err := c.GetChallengeFee()
+	fee, err := c.GetChallengeFee(ctx)
 	if err != nil {
 		return nil, common.Hash{}, fmt.Errorf("failed to get challenge fee: %w", err)
 	}
 	transactor.Value = fee
 
 	// get index hash
-	blockHash, err := c.canonicalStateChain.Chain(nil, big.NewInt(int64(index)))
+	blockHash, err := c.canonicalStateChain.Chain(callOpts(ctx), big.NewInt(int64(index)))
 	if err != nil {
 		return nil, common.Hash{}, fmt.Errorf("failed to get hash for block %d: %w", index, err)
 	}
@@ -81,8 +109,8 @@ func (c *Client) ChallengeDataRootInclusion(index uint64, pointerIndex uint8, sh
 	return tx, blockHash, nil
 }
 
-func (c *Client) DefendDataRootInclusion(blockHash common.Hash, proof challengeContract.SharesProof) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) DefendDataRootInclusion(ctx context.Context, blockHash common.Hash, proof challengeContract.SharesProof) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
@@ -92,11 +120,11 @@ func (c *Client) DefendDataRootInclusion(blockHash common.Hash, proof challengeC
 		return nil, fmt.Errorf("failed to defend data root inclusion: %w", err)
 	}
 
-	return tx, nil
+	return c.manage(tx, nil)
 }
 
-func (c *Client) SettleDataRootInclusion(blockHash common.Hash) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+func (c *Client) SettleDataRootInclusion(ctx context.Context, blockHash common.Hash) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
@@ -113,8 +141,53 @@ func (c *Client) FilterChallengeDAUpdate(opts *bind.FilterOpts, _blockHash [][32
 	return c.challenge.FilterChallengeDAUpdate(opts, _blockHash, _blockIndex, _status)
 }
 
-func (c *Client) DefendL2Header(blockHash, rootHash, headerHash common.Hash) (*types.Transaction, error) {
-	transactor, err := c.transactor()
+// WatchChallengeDAUpdate subscribes to DA challenge events over the
+// websocket endpoint. Each subscription uses its own connection, which is
+// closed when the subscription ends.
+func (c *Client) WatchChallengeDAUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeChallengeDAUpdate, _blockHash [][32]byte, _pointerIndex []*big.Int, _status []uint8) (event.Subscription, error) {
+	ws, err := c.dialWS()
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := challengeContract.NewChallengeFilterer(c.opts.ChallengeAddress, ws)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to connect to Challenge: %w", err)
+	}
+
+	sub, err := filterer.WatchChallengeDAUpdate(opts, sink, _blockHash, _pointerIndex, _status)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to watch DA challenges: %w", err)
+	}
+
+	return closeOnExit(sub, ws), nil
+}
+
+func (c *Client) ChallengeL2Header(ctx context.Context, rblockNum *big.Int, l2Num *big.Int) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	// set transactions fee
+	fee, err := c.GetChallengeFee(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge fee: %w", err)
+	}
+	transactor.Value = fee
+
+	tx, err := c.challenge.ChallengeL2Header(transactor, rblockNum, l2Num)
+	if err != nil {
+		return nil, fmt.Errorf("failed to challenge L2 header: %w", err)
+	}
+
+	return tx, nil
+}
+
+func (c *Client) DefendL2Header(ctx context.Context, blockHash, rootHash, headerHash common.Hash) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
@@ -122,12 +195,42 @@ func (c *Client) DefendL2Header(blockHash, rootHash, headerHash common.Hash) (*t
 	return c.challenge.DefendL2Header(transactor, blockHash, rootHash, headerHash)
 }
 
-func (c *Client) GetL2HeaderChallengeHash(rblockHash common.Hash, l2Num *big.Int) (common.Hash, error) {
-	return c.challenge.L2HeaderChallengeHash(nil, rblockHash, l2Num)
+func (c *Client) SettleL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	tx, err := c.challenge.SettleL2HeaderChallenge(transactor, challengeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to settle L2 header challenge: %w", err)
+	}
+
+	return tx, nil
+}
+
+// InvalidateHeader rolls back the rollup block at the given index if its
+// header is invalid, e.g. it does not extend the previous block.
+func (c *Client) InvalidateHeader(ctx context.Context, index uint64) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	tx, err := c.challenge.InvalidateHeader(transactor, new(big.Int).SetUint64(index))
+	if err != nil {
+		return nil, fmt.Errorf("failed to invalidate header: %w", err)
+	}
+
+	return tx, nil
+}
+
+func (c *Client) GetL2HeaderChallengeHash(ctx context.Context, rblockHash common.Hash, l2Num *big.Int) (common.Hash, error) {
+	return c.challenge.L2HeaderChallengeHash(callOpts(ctx), rblockHash, l2Num)
 }
 
-func (c *Client) GetL2HeaderChallenge(challengeHash common.Hash) (contracts.L2HeaderChallengeInfo, error) {
-	res, err := c.challenge.L2HeaderChallenges(nil, challengeHash)
+func (c *Client) GetL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (contracts.L2HeaderChallengeInfo, error) {
+	res, err := c.challenge.L2HeaderChallenges(callOpts(ctx), challengeHash)
 	if err != nil {
 		return contracts.L2HeaderChallengeInfo{}, fmt.Errorf("failed to get L2 header challenge: %w", err)
 	}
@@ -138,6 +241,7 @@ func (c *Client) GetL2HeaderChallenge(challengeHash common.Hash) (contracts.L2He
 		ChallengeEnd: res.ChallengeEnd,
 		Challenger:   res.Challenger,
 		Status:       res.Status,
+		Claimed:      res.Claimed,
 	}, nil
 }
 
@@ -145,8 +249,32 @@ func (c *Client) FilterL2HeaderChallengeUpdate(opts *bind.FilterOpts, _blockHash
 	return c.challenge.FilterL2HeaderChallengeUpdate(opts, _blockHash, _blockIndex, _status)
 }
 
-func (c *Client) GetChallengeWindow() (*big.Int, error) {
-	return c.challenge.ChallengeWindow(nil)
+// WatchL2HeaderChallengeUpdate subscribes to L2 header challenge events over
+// the websocket endpoint. Each subscription uses its own connection, which
+// is closed when the subscription ends.
+func (c *Client) WatchL2HeaderChallengeUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeL2HeaderChallengeUpdate, challengeHash [][32]byte, l2Number []*big.Int, status []uint8) (event.Subscription, error) {
+	ws, err := c.dialWS()
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := challengeContract.NewChallengeFilterer(c.opts.ChallengeAddress, ws)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to connect to Challenge: %w", err)
+	}
+
+	sub, err := filterer.WatchL2HeaderChallengeUpdate(opts, sink, challengeHash, l2Number, status)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to watch L2 header challenges: %w", err)
+	}
+
+	return closeOnExit(sub, ws), nil
+}
+
+func (c *Client) GetChallengeWindow(ctx context.Context) (*big.Int, error) {
+	return c.challenge.ChallengeWindow(callOpts(ctx))
 }
 
 func (c *Client) DataRootInclusionChallengeKey(opts *bind.CallOpts, blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (common.Hash, error) {
@@ -161,8 +289,8 @@ func (c *Client) DataRootInclusionChallengeKey(opts *bind.CallOpts, blockHash co
 // Useful for scanning logs for pending challenges due to eth_getLogs
 // range limitations. Ranges are split into 10k block chunks to avoid
 // hitting the eth_getLogs limit.
-func (c *Client) GetChallengeWindowBlockRanges() ([][]uint64, error) {
-	window, err := c.GetChallengeWindow() // seconds
+func (c *Client) GetChallengeWindowBlockRanges(ctx context.Context) ([][]uint64, error) {
+	window, err := c.GetChallengeWindow(ctx) // seconds
 	if err != nil {
 		return nil, fmt.Errorf("failed to get challenge window: %w", err)
 	}
@@ -173,7 +301,7 @@ func (c *Client) GetChallengeWindowBlockRanges() ([][]uint64, error) {
 	numBlocksToScan := window.Div(windowsMs, big.NewInt(int64(c.opts.BlockTime)))
 
 	// get the current block number
-	currentBlock, err := c.GetHeight()
+	currentBlock, err := c.GetHeight(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current block number: %w", err)
 	}
@@ -195,8 +323,8 @@ func (c *Client) GetChallengeWindowBlockRanges() ([][]uint64, error) {
 	return blockRanges, nil
 }
 
-func (c *Client) ClaimDAChallengeReward(key common.Hash) (*common.Hash, error) {
-	transactor, err := c.transactor()
+func (c *Client) ClaimDAChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
@@ -211,8 +339,8 @@ func (c *Client) ClaimDAChallengeReward(key common.Hash) (*common.Hash, error) {
 	return &hash, nil
 }
 
-func (c *Client) ClaimL2HeaderChallengeReward(key common.Hash) (*common.Hash, error) {
-	transactor, err := c.transactor()
+func (c *Client) ClaimL2HeaderChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error) {
+	transactor, err := c.transactor(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
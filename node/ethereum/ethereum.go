@@ -2,21 +2,27 @@ package ethereum
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
+	"hummingbird/metrics"
+	"hummingbird/node/signer"
 	"hummingbird/utils"
 	"log/slog"
 	"math/big"
+	"net/http"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
 	canonicalStateChainContract "hummingbird/node/contracts/CanonicalStateChain.sol"
 	chainOracleContract "hummingbird/node/contracts/ChainOracle.sol"
 	challengeContract "hummingbird/node/contracts/Challenge.sol"
+	lightLinkPortalContract "hummingbird/node/contracts/LightLinkPortal.sol"
 )
 
 // Ethereum is an ethereum client.
@@ -26,71 +32,118 @@ import (
 // - Challenge.sol With methods for challenging data availability etc
 // - ChainOracle.sol With methods for providing shares and headers
 // - BlobstreamX.sol With methods for verifying data availability
+// - LightLinkPortal.sol With methods for proving and finalizing withdrawals
 type Ethereum interface {
 	CanonicalStateChain
 	Challenge
 	ChainOracle
 	BlobstreamX
+	LightLinkPortal
+
+	ChainID(ctx context.Context) (*big.Int, error) // Get the chain id of the Ethereum network.
 }
 
 type Client struct {
-	signer              *ecdsa.PrivateKey
+	signer              signer.Signer
 	client              *ethclient.Client
 	chainId             *big.Int
 	canonicalStateChain *canonicalStateChainContract.CanonicalStateChain
 	challenge           *challengeContract.Challenge
 	chainLoader         *chainOracleContract.ChainOracle
 	blobstreamX         *blobstreamXContract.BlobstreamX
+	lightLinkPortal     *lightLinkPortalContract.LightLinkPortal
+	backend             bind.ContractBackend // sends txs through the nonce manager, if any
+	txs                 *txManager
+	nonces              *nonceManager // nil in dry run, when txs are not sent
 	logger              *slog.Logger
 	opts                *ClientOpts
 }
 
 type ClientOpts struct {
-	Signer                     *ecdsa.PrivateKey
+	Signer                     signer.Signer // Signs L1 txs, see the signer package for the backends.
 	Endpoint                   string
+	WSEndpoint                 string // optional, required to watch for contract events
 	CanonicalStateChainAddress common.Address
 	ChallengeAddress           common.Address
 	ChainOracleAddress         common.Address
 	BlobstreamXAddress         common.Address
+	LightLinkPortalAddress     common.Address
 	Logger                     *slog.Logger
 	DryRun                     bool
 	GasPriceIncreasePercent    *big.Int
+	MaxFeePerGas               *big.Int      // optional, max fee per gas in wei any tx may pay
+	ReplaceAfter               time.Duration // optional, time a rollup, defence or shares tx may stay pending before it is replaced
+	FeeBumpPercent             int           // Percent fees are increased by when replacing a tx, at least 10.
 	BlockTime                  int
 	Timeout                    time.Duration
+	NonceStore                 NonceStore       // optional, persists the next nonce across restarts
+	Metrics                    *metrics.Metrics // optional
+}
+
+// ChainID returns the chain id of the network, or an error if it no longer
+// matches the chain id the client connected to.
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	chainId, err := c.client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if chainId.Cmp(c.chainId) != 0 {
+		return nil, fmt.Errorf("chain id changed from %s to %s", c.chainId, chainId)
+	}
+	return chainId, nil
 }
 
 // NewEthereumRPC returns a new EthereumRPC client over HTTP.
-func NewClient(opts ClientOpts) (*Client, error) {
+func NewClient(ctx context.Context, opts ClientOpts) (*Client, error) {
 	if opts.Logger == nil {
 		opts.Logger = slog.Default()
 	}
 
-	client, err := ethclient.Dial(opts.Endpoint)
+	// count failed requests through the http transport, as the contract
+	// bindings call the client directly
+	rpcClient, err := rpc.DialOptions(ctx, opts.Endpoint, rpc.WithHTTPClient(&http.Client{
+		Transport: opts.Metrics.Transport(metrics.BackendEthereum, nil),
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Ethereum: %w", err)
 	}
+	client := ethclient.NewClient(rpcClient)
+
+	// hand out nonces locally, so txs can be sent without waiting for the
+	// previous one to be mined
+	var backend bind.ContractBackend = client
+	var nonces *nonceManager
+	if opts.Signer != nil && !opts.DryRun {
+		nonces = newNonceManager(client, opts.Signer.Address(), opts.NonceStore, opts.Logger, opts.Metrics)
+		backend = &nonceBackend{Client: client, nonces: nonces}
+	}
 
-	canonicalStateChain, err := canonicalStateChainContract.NewCanonicalStateChain(opts.CanonicalStateChainAddress, client)
+	canonicalStateChain, err := canonicalStateChainContract.NewCanonicalStateChain(opts.CanonicalStateChainAddress, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to CanonicalStateChain: %w", err)
 	}
 
-	challenge, err := challengeContract.NewChallenge(opts.ChallengeAddress, client)
+	challenge, err := challengeContract.NewChallenge(opts.ChallengeAddress, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Challenge: %w", err)
 	}
 
-	chainLoader, err := chainOracleContract.NewChainOracle(opts.ChainOracleAddress, client)
+	chainLoader, err := chainOracleContract.NewChainOracle(opts.ChainOracleAddress, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to ChainOracle: %w", err)
 	}
 
-	blobstreamX, err := blobstreamXContract.NewBlobstreamX(opts.BlobstreamXAddress, client)
+	blobstreamX, err := blobstreamXContract.NewBlobstreamX(opts.BlobstreamXAddress, backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to BlobstreamX: %w", err)
 	}
 
-	chainId, err := client.ChainID(context.TODO())
+	lightLinkPortal, err := lightLinkPortalContract.NewLightLinkPortal(opts.LightLinkPortalAddress, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LightLinkPortal: %w", err)
+	}
+
+	chainId, err := client.ChainID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chainId: %w", err)
 	}
@@ -110,6 +163,9 @@ func NewClient(opts ClientOpts) (*Client, error) {
 	if ok, _ := utils.IsContract(client, opts.BlobstreamXAddress); !ok {
 		opts.Logger.Warn("contract not found for BlobstreamX at given Address", "address", opts.BlobstreamXAddress.Hex(), "endpoint", opts.Endpoint)
 	}
+	if ok, _ := utils.IsContract(client, opts.LightLinkPortalAddress); !ok {
+		opts.Logger.Warn("contract not found for LightLinkPortal at given Address", "address", opts.LightLinkPortalAddress.Hex(), "endpoint", opts.Endpoint)
+	}
 
 	return &Client{
 		signer:              opts.Signer,
@@ -119,27 +175,41 @@ func NewClient(opts ClientOpts) (*Client, error) {
 		challenge:           challenge,
 		chainLoader:         chainLoader,
 		blobstreamX:         blobstreamX,
+		lightLinkPortal:     lightLinkPortal,
+		backend:             backend,
+		txs:                 newTxManager(),
+		nonces:              nonces,
 		logger:              opts.Logger,
 		opts:                &opts,
 	}, nil
 }
 
-func (e *Client) transactor() (*bind.TransactOpts, error) {
-	opts, err := bind.NewKeyedTransactorWithChainID(e.signer, e.chainId)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
+func (e *Client) transactor(ctx context.Context) (*bind.TransactOpts, error) {
+	from := e.signer.Address()
+	opts := &bind.TransactOpts{
+		From: from,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			signed, err := e.signer.SignTx(ctx, tx, e.chainId)
+			if err != nil {
+				if e.nonces != nil {
+					e.nonces.release(tx.Nonce())
+				}
+				return nil, fmt.Errorf("failed to sign tx: %w", err)
+			}
+			return signed, nil
+		},
+		Context: ctx,
 	}
 
-	gasPrice, err := e.client.SuggestGasPrice(context.Background())
+	tip, feeCap, err := e.suggestFees(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get gas price: %w", err)
-	}
-	opts.GasPrice = gasPrice
-
-	// If gas price increase percent is set, increase the gas price by the given percent.
-	if e.opts.GasPriceIncreasePercent != nil && e.opts.GasPriceIncreasePercent.Cmp(big.NewInt(0)) > 0 {
-		opts.GasPrice = gasPrice.Add(gasPrice, new(big.Int).Div(new(big.Int).Mul(gasPrice, e.opts.GasPriceIncreasePercent), big.NewInt(100)))
+		return nil, err
 	}
+	opts.GasTipCap = tip
+	opts.GasFeeCap = feeCap
 
 	// If dry run is enabled, don't send the transaction.
 	if e.opts.DryRun {
@@ -149,3 +219,38 @@ func (e *Client) transactor() (*bind.TransactOpts, error) {
 
 	return opts, nil
 }
+
+// callOpts returns the opts for a contract call bound to ctx.
+func callOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{Context: ctx}
+}
+
+// dialWS opens a new websocket connection to Ethereum for watching events.
+func (e *Client) dialWS() (*ethclient.Client, error) {
+	if e.opts.WSEndpoint == "" {
+		return nil, fmt.Errorf("no websocket endpoint set")
+	}
+
+	client, err := ethclient.Dial(e.opts.WSEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ethereum websocket: %w", err)
+	}
+
+	return client, nil
+}
+
+// closeOnExit wraps the subscription so the given connection is closed
+// when the subscription ends, either by error or by unsubscribing.
+func closeOnExit(sub event.Subscription, client *ethclient.Client) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer client.Close()
+		defer sub.Unsubscribe()
+
+		select {
+		case err := <-sub.Err():
+			return err
+		case <-quit:
+			return nil
+		}
+	})
+}
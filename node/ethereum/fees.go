@@ -0,0 +1,118 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+const (
+	feeHistoryBlocks     = 10 // Number of recent blocks the tip is suggested from.
+	feeHistoryPercentile = 50 // Percentile of the tips paid in each block.
+	minFeeBumpPercent    = 10 // Min fee bump nodes accept to replace a pending tx.
+)
+
+// ErrMaxFee is returned when the base fee alone is over the max fee per gas,
+// so no tx can be sent until it falls.
+var ErrMaxFee = errors.New("base fee over max fee per gas")
+
+// suggestFees returns the tip and fee cap for a dynamic fee tx. The tip is
+// the median tip paid over recent blocks, and the fee cap covers the tip
+// plus twice the next base fee, so the tx stays valid through a few full
+// blocks. Both are increased by GasPriceIncreasePercent and capped at
+// MaxFeePerGas.
+func (e *Client) suggestFees(ctx context.Context) (tip *big.Int, feeCap *big.Int, err error) {
+	history, err := e.client.FeeHistory(ctx, feeHistoryBlocks, nil, []float64{feeHistoryPercentile})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get fee history: %w", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, nil, fmt.Errorf("fee history has no base fee")
+	}
+
+	// the last base fee is the one for the next block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	tips := make([]*big.Int, 0, len(history.Reward))
+	for _, reward := range history.Reward {
+		if len(reward) > 0 {
+			tips = append(tips, reward[0])
+		}
+	}
+	if len(tips) > 0 {
+		slices.SortFunc(tips, func(a, b *big.Int) int { return a.Cmp(b) })
+		tip = new(big.Int).Set(tips[len(tips)/2])
+	} else {
+		// no txs in the recent blocks to take a tip from
+		tip, err = e.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get gas tip cap: %w", err)
+		}
+	}
+
+	tip = increasePercent(tip, e.opts.GasPriceIncreasePercent)
+	feeCap = new(big.Int).Add(increasePercent(new(big.Int).Mul(baseFee, big.NewInt(2)), e.opts.GasPriceIncreasePercent), tip)
+
+	return capFees(baseFee, tip, feeCap, e.opts.MaxFeePerGas)
+}
+
+// capFees caps the fee cap at maxFee and the tip at the fee cap, failing
+// with ErrMaxFee if the base fee alone is over maxFee. A nil maxFee is no cap.
+func capFees(baseFee, tip, feeCap, maxFee *big.Int) (*big.Int, *big.Int, error) {
+	if maxFee == nil || maxFee.Sign() <= 0 {
+		return tip, feeCap, nil
+	}
+	if baseFee.Cmp(maxFee) > 0 {
+		return nil, nil, fmt.Errorf("%w: base fee %s, max fee %s", ErrMaxFee, baseFee, maxFee)
+	}
+	if feeCap.Cmp(maxFee) > 0 {
+		feeCap = new(big.Int).Set(maxFee)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	return tip, feeCap, nil
+}
+
+// bumpFees returns the tip and fee cap to replace a pending tx with, each
+// increased by bumpPercent and at least by the minimum nodes accept, and
+// capped at maxFee. It fails if the fee cap is already at maxFee.
+func bumpFees(tip, feeCap *big.Int, bumpPercent int, maxFee *big.Int) (*big.Int, *big.Int, error) {
+	percent := big.NewInt(int64(max(bumpPercent, minFeeBumpPercent)))
+
+	// nodes only replace a tx with strictly higher fees, so always add at
+	// least 1 wei for fees too small for the percent to round up
+	newTip := bigMax(increasePercent(tip, percent), new(big.Int).Add(tip, big.NewInt(1)))
+	newFeeCap := bigMax(increasePercent(feeCap, percent), new(big.Int).Add(feeCap, big.NewInt(1)))
+
+	if maxFee != nil && maxFee.Sign() > 0 {
+		if feeCap.Cmp(maxFee) >= 0 {
+			return nil, nil, fmt.Errorf("fee cap %s already at max fee per gas", feeCap)
+		}
+		if newFeeCap.Cmp(maxFee) > 0 {
+			newFeeCap = new(big.Int).Set(maxFee)
+		}
+	}
+	if newTip.Cmp(newFeeCap) > 0 {
+		newTip = new(big.Int).Set(newFeeCap)
+	}
+	return newTip, newFeeCap, nil
+}
+
+// increasePercent returns n increased by percent, or n if percent is not set.
+func increasePercent(n, percent *big.Int) *big.Int {
+	if percent == nil || percent.Sign() <= 0 {
+		return n
+	}
+	return new(big.Int).Add(n, new(big.Int).Div(new(big.Int).Mul(n, percent), big.NewInt(100)))
+}
+
+// bigMax returns the larger of a and b.
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
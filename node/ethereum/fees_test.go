@@ -0,0 +1,63 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapFees(t *testing.T) {
+	// 1. no max fee leaves the fees as they are
+	tip, feeCap, err := capFees(big.NewInt(100), big.NewInt(10), big.NewInt(210), nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), tip)
+	assert.Equal(t, big.NewInt(210), feeCap)
+
+	// 2. the fee cap is capped at the max fee
+	tip, feeCap, err = capFees(big.NewInt(100), big.NewInt(10), big.NewInt(210), big.NewInt(150))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), tip)
+	assert.Equal(t, big.NewInt(150), feeCap)
+
+	// 3. the tip is capped at the fee cap
+	tip, feeCap, err = capFees(big.NewInt(1), big.NewInt(200), big.NewInt(202), big.NewInt(150))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(150), tip)
+	assert.Equal(t, big.NewInt(150), feeCap)
+
+	// 4. a base fee over the max fee fails
+	_, _, err = capFees(big.NewInt(200), big.NewInt(10), big.NewInt(410), big.NewInt(150))
+	require.ErrorIs(t, err, ErrMaxFee)
+}
+
+func TestBumpFees(t *testing.T) {
+	// 1. fees are bumped by the percent
+	tip, feeCap, err := bumpFees(big.NewInt(100), big.NewInt(1000), 20, nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(120), tip)
+	assert.Equal(t, big.NewInt(1200), feeCap)
+
+	// 2. fees are bumped by at least the min nodes accept
+	tip, feeCap, err = bumpFees(big.NewInt(100), big.NewInt(1000), 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(110), tip)
+	assert.Equal(t, big.NewInt(1100), feeCap)
+
+	// 3. fees too small to round up still go up
+	tip, feeCap, err = bumpFees(big.NewInt(0), big.NewInt(5), 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), tip)
+	assert.Equal(t, big.NewInt(6), feeCap)
+
+	// 4. the fee cap is capped at the max fee
+	tip, feeCap, err = bumpFees(big.NewInt(100), big.NewInt(1000), 20, big.NewInt(1050))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(120), tip)
+	assert.Equal(t, big.NewInt(1050), feeCap)
+
+	// 5. a fee cap already at the max fee can't be bumped
+	_, _, err = bumpFees(big.NewInt(100), big.NewInt(1050), 20, big.NewInt(1050))
+	require.Error(t, err)
+}
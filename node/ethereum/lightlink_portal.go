@@ -0,0 +1,77 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"hummingbird/node/contracts"
+	lightLinkPortalContract "hummingbird/node/contracts/LightLinkPortal.sol"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type LightLinkPortal interface {
+	ProveWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction, l2OutputIndex uint64, outputRootProof lightLinkPortalContract.TypesOutputRootProof, withdrawalProof [][]byte) (*types.Transaction, error)
+	FinalizeWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction) (*types.Transaction, error)
+	GetProvenWithdrawal(ctx context.Context, withdrawalHash common.Hash) (contracts.ProvenWithdrawal, error) // Timestamp is zero if the withdrawal has not been proven.
+	IsWithdrawalFinalized(ctx context.Context, withdrawalHash common.Hash) (bool, error)
+	IsOutputFinalized(ctx context.Context, l2OutputIndex uint64) (bool, error) // True once the rollup block at the index is past the challenge window.
+	FilterTransactionDeposited(opts *bind.FilterOpts, from []common.Address, to []common.Address, version []*big.Int) (*lightLinkPortalContract.LightLinkPortalTransactionDepositedIterator, error)
+}
+
+var _ LightLinkPortal = &Client{} // Ensure Client implements LightLinkPortal
+
+func (c *Client) ProveWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction, l2OutputIndex uint64, outputRootProof lightLinkPortalContract.TypesOutputRootProof, withdrawalProof [][]byte) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	t, err := c.lightLinkPortal.ProveWithdrawalTransaction(transactor, tx, new(big.Int).SetUint64(l2OutputIndex), outputRootProof, withdrawalProof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove withdrawal transaction: %w", err)
+	}
+
+	return t, nil
+}
+
+func (c *Client) FinalizeWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction) (*types.Transaction, error) {
+	transactor, err := c.transactor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	t, err := c.lightLinkPortal.FinalizeWithdrawalTransaction(transactor, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize withdrawal transaction: %w", err)
+	}
+
+	return t, nil
+}
+
+func (c *Client) GetProvenWithdrawal(ctx context.Context, withdrawalHash common.Hash) (contracts.ProvenWithdrawal, error) {
+	res, err := c.lightLinkPortal.ProvenWithdrawals(callOpts(ctx), withdrawalHash)
+	if err != nil {
+		return contracts.ProvenWithdrawal{}, fmt.Errorf("failed to get proven withdrawal: %w", err)
+	}
+
+	return contracts.ProvenWithdrawal{
+		OutputRoot:    res.OutputRoot,
+		Timestamp:     res.Timestamp,
+		L2OutputIndex: res.L2OutputIndex,
+	}, nil
+}
+
+func (c *Client) IsWithdrawalFinalized(ctx context.Context, withdrawalHash common.Hash) (bool, error) {
+	return c.lightLinkPortal.FinalizedWithdrawals(callOpts(ctx), withdrawalHash)
+}
+
+func (c *Client) IsOutputFinalized(ctx context.Context, l2OutputIndex uint64) (bool, error) {
+	return c.lightLinkPortal.IsOutputFinalized(callOpts(ctx), new(big.Int).SetUint64(l2OutputIndex))
+}
+
+func (c *Client) FilterTransactionDeposited(opts *bind.FilterOpts, from []common.Address, to []common.Address, version []*big.Int) (*lightLinkPortalContract.LightLinkPortalTransactionDepositedIterator, error) {
+	return c.lightLinkPortal.FilterTransactionDeposited(opts, from, to, version)
+}
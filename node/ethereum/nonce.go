@@ -0,0 +1,208 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"hummingbird/metrics"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// nonceGapTimeout is how long nonces handed out must stay missing from the
+// pending nonce of the node before they are treated as a gap, as the
+// pending nonce can lag behind txs that were just sent.
+const nonceGapTimeout = time.Minute
+
+// NonceStore persists the next nonce across restarts. node.KVStore
+// satisfies it.
+type NonceStore interface {
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	Put(ctx context.Context, key, value []byte) error
+}
+
+// nonceManager hands out the nonces of the txs sent by the signer, so txs
+// can be sent concurrently without waiting for the previous one to be
+// mined, and without two of them getting the same nonce from the node.
+//
+// A nonce is in flight from when it is handed out until its tx is sent. If
+// the tx is never sent, e.g. signing fails, the nonce is released and given
+// to the next tx, so no gap is left for later txs to get stuck behind.
+type nonceManager struct {
+	client     pendingNonceReader
+	address    common.Address
+	store      NonceStore // optional
+	logger     *slog.Logger
+	metrics    *metrics.Metrics
+	gapTimeout time.Duration
+
+	mu         sync.Mutex
+	loaded     bool
+	next       uint64              // next nonce to hand out
+	inflight   map[uint64]struct{} // nonces handed out, with txs not yet sent
+	released   []uint64            // nonces handed out, with txs never sent
+	gapSince   time.Time           // when the pending nonce was first seen behind the nonces handed out
+	gapPending uint64              // pending nonce when the gap was first seen
+}
+
+// pendingNonceReader reads the pending nonce of an account from the node.
+type pendingNonceReader interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+func newNonceManager(client pendingNonceReader, address common.Address, store NonceStore, logger *slog.Logger, m *metrics.Metrics) *nonceManager {
+	return &nonceManager{
+		client:     client,
+		address:    address,
+		store:      store,
+		logger:     logger,
+		metrics:    m,
+		gapTimeout: nonceGapTimeout,
+		inflight:   make(map[uint64]struct{}),
+	}
+}
+
+func (m *nonceManager) storeKey() []byte {
+	return append([]byte("nonce_"), m.address.Bytes()...)
+}
+
+// acquire returns the nonce for the next tx, which must be passed to sent
+// or release once the tx is sent or abandoned.
+func (m *nonceManager) acquire(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, err := m.client.PendingNonceAt(ctx, m.address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	// 1. load the next nonce stored before a restart
+	if !m.loaded {
+		m.next = m.load(ctx)
+		m.loaded = true
+	}
+
+	// 2. skip nonces used by txs sent from the address elsewhere
+	if m.next < pending {
+		m.next = pending
+	}
+	m.released = slices.DeleteFunc(m.released, func(n uint64) bool { return n < pending })
+
+	// 3. detect a gap, where nonces were handed out to txs the node does
+	// not have, e.g. dropped from its pool or lost in a restart. Any tx
+	// after the gap would never be mined, so the nonces are handed out again.
+	// The pending nonce can lag behind txs just sent, so it must stay behind
+	// for the gap timeout first. Nonces of txs that failed to send are
+	// released straight away instead.
+	lowest := m.next
+	for n := range m.inflight {
+		lowest = min(lowest, n)
+	}
+	if pending >= lowest || pending != m.gapPending {
+		m.gapSince, m.gapPending = time.Time{}, pending
+	}
+	if pending < lowest && m.gapSince.IsZero() {
+		m.gapSince = time.Now()
+	}
+	if pending < lowest && time.Since(m.gapSince) >= m.gapTimeout {
+		m.logger.Warn("Nonce gap detected, reusing nonces", "pending", pending, "next", m.next, "gap", lowest-pending, "missing_for", time.Since(m.gapSince))
+		m.metrics.NonceGap()
+		m.gapSince = time.Time{}
+		if len(m.inflight) == 0 {
+			m.next = pending
+			m.released = nil
+		} else {
+			for n := pending; n < lowest; n++ {
+				if !slices.Contains(m.released, n) {
+					m.released = append(m.released, n)
+				}
+			}
+		}
+	}
+
+	// 4. hand out the lowest released nonce first, so no gap is left
+	var nonce uint64
+	if len(m.released) > 0 {
+		slices.Sort(m.released)
+		nonce, m.released = m.released[0], m.released[1:]
+	} else {
+		nonce = m.next
+		m.next++
+		m.save(ctx)
+	}
+	m.inflight[nonce] = struct{}{}
+
+	return nonce, nil
+}
+
+// sent marks the tx with the nonce as sent.
+func (m *nonceManager) sent(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inflight, nonce)
+}
+
+// release hands the nonce of a tx that was never sent out again.
+func (m *nonceManager) release(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.inflight[nonce]; !ok {
+		return
+	}
+	delete(m.inflight, nonce)
+	m.released = append(m.released, nonce)
+}
+
+// load returns the next nonce from the store, or 0 if it is not stored.
+func (m *nonceManager) load(ctx context.Context) uint64 {
+	if m.store == nil {
+		return 0
+	}
+	buf, err := m.store.Get(ctx, m.storeKey())
+	if err != nil || len(buf) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(buf)
+}
+
+// save stores the next nonce, so a gap left before a restart is detected.
+func (m *nonceManager) save(ctx context.Context) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Put(ctx, m.storeKey(), binary.BigEndian.AppendUint64(nil, m.next)); err != nil {
+		m.logger.Warn("Failed to store nonce", "nonce", m.next, "error", err)
+	}
+}
+
+// nonceBackend is the backend of the contract bindings. It hands out nonces
+// from the nonce manager in place of the pending nonce from the node.
+type nonceBackend struct {
+	*ethclient.Client
+	nonces *nonceManager
+}
+
+func (b *nonceBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if account != b.nonces.address {
+		return b.Client.PendingNonceAt(ctx, account)
+	}
+	return b.nonces.acquire(ctx)
+}
+
+func (b *nonceBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := b.Client.SendTransaction(ctx, tx); err != nil {
+		b.nonces.release(tx.Nonce())
+		return err
+	}
+	b.nonces.sent(tx.Nonce())
+	return nil
+}
@@ -0,0 +1,82 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pendingNonce uint64
+
+func (p *pendingNonce) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return uint64(*p), nil
+}
+
+type memStore map[string][]byte
+
+func (m memStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if v, ok := m[string(key)]; ok {
+		return v, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m memStore) Put(ctx context.Context, key, value []byte) error {
+	m[string(key)] = value
+	return nil
+}
+
+func TestNonceManager(t *testing.T) {
+	ctx := t.Context()
+	node := pendingNonce(5)
+	store := memStore{}
+	nonces := newNonceManager(&node, common.Address{0x01}, store, slog.Default(), nil)
+
+	acquire := func() uint64 {
+		nonce, err := nonces.acquire(ctx)
+		require.NoError(t, err)
+		return nonce
+	}
+
+	// 1. nonces are handed out from the pending nonce without waiting for txs to be sent
+	assert.Equal(t, uint64(5), acquire())
+	assert.Equal(t, uint64(6), acquire())
+	nonces.sent(5)
+	node = 6
+
+	// 2. a nonce never sent is handed out again first
+	assert.Equal(t, uint64(7), acquire())
+	nonces.release(6)
+	assert.Equal(t, uint64(6), acquire())
+	nonces.sent(6)
+	nonces.sent(7)
+	node = 8
+
+	// 3. nonces used by txs sent elsewhere are skipped
+	node = 10
+	assert.Equal(t, uint64(10), acquire())
+	nonces.sent(10)
+	node = 11
+
+	// 4. the next nonce survives a restart
+	nonces = newNonceManager(&node, common.Address{0x01}, store, slog.Default(), nil)
+	nonces.gapTimeout = 50 * time.Millisecond
+	assert.Equal(t, uint64(11), acquire())
+	nonces.sent(11)
+
+	// 5. a pending nonce lagging behind the sent txs is not a gap
+	node = 9
+	assert.Equal(t, uint64(12), acquire())
+	nonces.sent(12)
+
+	// 6. a gap left by txs the node dropped is filled once it stays missing
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, uint64(9), acquire())
+	assert.Equal(t, uint64(10), acquire())
+}
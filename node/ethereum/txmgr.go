@@ -0,0 +1,205 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxTxRecords is the number of managed txs kept before the oldest are dropped.
+const maxTxRecords = 1000
+
+// ErrNonceUsed is returned when waiting for a managed tx whose nonce was used
+// by a tx that is neither it nor any of its known replacements.
+var ErrNonceUsed = errors.New("nonce used by another tx")
+
+// TxRecord tracks a managed tx through any replacements until it is mined.
+type TxRecord struct {
+	Hash         common.Hash    // Hash of the tx as first sent.
+	Nonce        uint64         // Nonce of the tx and its replacements.
+	Replacements []common.Hash  // Hashes of the replacement txs, in the order sent.
+	Receipt      *types.Receipt // Receipt of whichever tx was mined, nil until then.
+
+	current *types.Transaction // last tx sent, replaced with a higher fee, nil if not known
+	sentAt  time.Time          // when the last tx was sent
+}
+
+// hashes returns the hashes of the original tx and its replacements.
+func (r *TxRecord) hashes() []common.Hash {
+	return append([]common.Hash{r.Hash}, r.Replacements...)
+}
+
+// txManager tracks the rollup, defence and shares txs, so Wait can replace
+// them with a higher fee if they stay pending for too long.
+type txManager struct {
+	mu    sync.Mutex
+	txs   map[common.Hash]*TxRecord
+	order []common.Hash
+}
+
+func newTxManager() *txManager {
+	return &txManager{txs: make(map[common.Hash]*TxRecord)}
+}
+
+// track starts tracking a sent tx.
+func (m *txManager) track(tx *types.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add(&TxRecord{Hash: tx.Hash(), Nonce: tx.Nonce(), current: tx, sentAt: time.Now()})
+}
+
+// adopt starts tracking a tx sent before a restart, by its nonce and the
+// hashes of the original tx and its replacements. current is the last of
+// them the node still knows, or nil if it knows none.
+func (m *txManager) adopt(nonce uint64, hashes []common.Hash, current *types.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.txs[hashes[0]]; ok {
+		return
+	}
+	m.add(&TxRecord{
+		Hash:         hashes[0],
+		Nonce:        nonce,
+		Replacements: append([]common.Hash(nil), hashes[1:]...),
+		current:      current,
+		sentAt:       time.Now(),
+	})
+}
+
+// add stores the record, dropping the oldest once there are too many.
+func (m *txManager) add(rec *TxRecord) {
+	m.txs[rec.Hash] = rec
+	m.order = append(m.order, rec.Hash)
+	if len(m.order) > maxTxRecords {
+		delete(m.txs, m.order[0])
+		m.order = m.order[1:]
+	}
+}
+
+// get returns a copy of the record for the tx with the given original hash.
+func (m *txManager) get(hash common.Hash) (TxRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.txs[hash]
+	if !ok {
+		return TxRecord{}, false
+	}
+	cp := *rec
+	cp.Replacements = append([]common.Hash(nil), rec.Replacements...)
+	return cp, true
+}
+
+// replaced records a replacement sent for the tx with the given original hash.
+func (m *txManager) replaced(hash common.Hash, tx *types.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.txs[hash]; ok {
+		rec.Replacements = append(rec.Replacements, tx.Hash())
+		rec.current = tx
+		rec.sentAt = time.Now()
+	}
+}
+
+// mined records the receipt of the tx with the given original hash.
+func (m *txManager) mined(hash common.Hash, receipt *types.Receipt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.txs[hash]; ok {
+		rec.Receipt = receipt
+	}
+}
+
+// TxRecord returns the record of a rollup, defence or shares tx by the hash
+// it was first sent with, including any replacements and the final receipt.
+func (c *Client) TxRecord(hash common.Hash) (TxRecord, bool) {
+	return c.txs.get(hash)
+}
+
+// manage tracks a sent tx so Wait replaces it if it stays pending.
+func (c *Client) manage(tx *types.Transaction, err error) (*types.Transaction, error) {
+	if err != nil || c.opts.DryRun {
+		return tx, err
+	}
+	c.txs.track(tx)
+	return tx, nil
+}
+
+// replace resends the tracked tx with the same nonce and a higher fee. The
+// replacement is sent through the nonce manager, like any other tx.
+func (c *Client) replace(ctx context.Context, rec TxRecord) (*types.Transaction, error) {
+	cur := rec.current
+	if cur == nil {
+		return nil, errors.New("no known tx to replace")
+	}
+
+	tip, feeCap, err := bumpFees(cur.GasTipCap(), cur.GasFeeCap(), c.opts.FeeBumpPercent, c.opts.MaxFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+
+	// pay the current fees if they have risen by more than the bump
+	if suggestedTip, suggestedFeeCap, err := c.suggestFees(ctx); err == nil {
+		tip = bigMax(tip, suggestedTip)
+		feeCap = bigMax(feeCap, suggestedFeeCap)
+	}
+
+	tx, err := c.signer.SignTx(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID:    c.chainId,
+		Nonce:      cur.Nonce(),
+		GasTipCap:  tip,
+		GasFeeCap:  feeCap,
+		Gas:        cur.Gas(),
+		To:         cur.To(),
+		Value:      cur.Value(),
+		Data:       cur.Data(),
+		AccessList: cur.AccessList(),
+	}), c.chainId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement tx: %w", err)
+	}
+
+	if err := c.backend.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to send replacement tx: %w", err)
+	}
+
+	c.txs.replaced(rec.Hash, tx)
+	c.opts.Metrics.TxReplaced()
+	c.logger.Info("Replaced pending transaction", "txHash", rec.Hash.Hex(), "replacement", tx.Hash().Hex(), "nonce", tx.Nonce(), "tipCap", tip, "feeCap", feeCap)
+
+	return tx, nil
+}
+
+// lastKnownTx returns the last of the txs with the given hashes the node
+// knows, whether pending or mined, or nil if it knows none of them.
+func (c *Client) lastKnownTx(ctx context.Context, hashes []common.Hash) *types.Transaction {
+	for i := len(hashes) - 1; i >= 0; i-- {
+		tx, _, err := c.client.TransactionByHash(ctx, hashes[i])
+		if err == nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// nonceUsed returns whether a tx with the nonce has been mined.
+func (c *Client) nonceUsed(ctx context.Context, nonce uint64) bool {
+	if c.signer == nil {
+		return false
+	}
+	mined, err := c.client.NonceAt(ctx, c.signer.Address(), nil)
+	if err != nil {
+		c.logger.Debug("Failed to get nonce", "error", err)
+		return false
+	}
+	return mined > nonce
+}
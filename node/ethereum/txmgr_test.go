@@ -0,0 +1,42 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager(t *testing.T) {
+	txs := newTxManager()
+	tx := types.NewTx(&types.DynamicFeeTx{Nonce: 7})
+	replacement := types.NewTx(&types.DynamicFeeTx{Nonce: 7, GasTipCap: common.Big1})
+
+	// 1. a sent tx is tracked by its hash and nonce, with its replacements
+	txs.track(tx)
+	txs.replaced(tx.Hash(), replacement)
+	rec, ok := txs.get(tx.Hash())
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), rec.Nonce)
+	assert.Equal(t, []common.Hash{tx.Hash(), replacement.Hash()}, rec.hashes())
+	assert.Equal(t, replacement.Hash(), rec.current.Hash())
+
+	// 2. a tx sent before a restart is adopted with its replacements, to be
+	// replaced again from the last one the node knows
+	txs = newTxManager()
+	hashes := []common.Hash{tx.Hash(), replacement.Hash(), {0x01}}
+	txs.adopt(7, hashes, replacement)
+	rec, ok = txs.get(tx.Hash())
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), rec.Nonce)
+	assert.Equal(t, hashes, rec.hashes())
+	assert.Equal(t, replacement.Hash(), rec.current.Hash())
+
+	// 3. a tx already tracked is not adopted again
+	txs.adopt(8, hashes[:1], nil)
+	rec, _ = txs.get(tx.Hash())
+	assert.Equal(t, uint64(7), rec.Nonce)
+	assert.Len(t, rec.Replacements, 2)
+}
@@ -0,0 +1,43 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+// Heartbeat records when the main loop of a long running command last made
+// progress, and the stage it was in, so a stuck loop can be detected. A nil
+// *Heartbeat records nothing.
+type Heartbeat struct {
+	mu    sync.Mutex
+	last  time.Time
+	stage string
+}
+
+func NewHeartbeat() *Heartbeat {
+	return &Heartbeat{last: time.Now(), stage: "starting"}
+}
+
+// Beat records progress of the main loop, entering the given stage.
+func (h *Heartbeat) Beat(stage string) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+	h.stage = stage
+}
+
+// Last returns when the main loop last made progress, and the stage it
+// entered.
+func (h *Heartbeat) Last() (time.Time, string) {
+	if h == nil {
+		return time.Time{}, ""
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last, h.stage
+}
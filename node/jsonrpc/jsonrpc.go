@@ -2,75 +2,310 @@ package jsonrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"hummingbird/utils"
 )
 
 type Request struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params"`
-	ID      int         `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+	ID      uint64 `json:"id"`
 }
 
 type Response struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Result  interface{} `json:"result"`
-	Error   interface{} `json:"error"`
-	ID      int         `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+	ID      uint64          `json:"id"`
+}
+
+// Bind decodes the result into result, or returns the error of the response.
+func (r *Response) Bind(result any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if len(r.Result) == 0 {
+		return fmt.Errorf("response has no result")
+	}
+	return json.Unmarshal(r.Result, result)
 }
 
+// Error is a JSON-RPC error object returned by the endpoint.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("json-rpc error %d: %s: %s", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// JSON-RPC error codes the call is retried on.
+const (
+	CodeInternalError = -32603
+	CodeLimitExceeded = -32005
+)
+
+// HTTPError is returned when the endpoint responds with a status other than
+// 200 OK.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected http status %d: %s", e.StatusCode, e.Body)
+}
+
+type ClientOpts struct {
+	Endpoints  []string          // Endpoints to call, failing over to the next on a retryable error.
+	Transport  http.RoundTripper // optional, defaults to http.DefaultTransport
+	Timeout    time.Duration     // Timeout of each attempt, defaults to 30 seconds.
+	Retries    int               // Number of times read only calls are retried, 0 for none.
+	RetryDelay time.Duration     // Delay before the first retry, doubled on each retry and jittered, defaults to 1 second.
+	Logger     *slog.Logger
+}
+
+// Client is a JSON-RPC client over HTTP. Read only calls are retried with
+// backoff, failing over to the next endpoint, so a flaky endpoint returns
+// an error instead of a bad response.
 type Client struct {
-	endpoint   string
+	opts       *ClientOpts
 	httpClient *http.Client
+
+	mu       sync.Mutex
+	endpoint int // index of the endpoint calls are sent to
+	nextID   uint64
 }
 
-func NewClient(endpoint string) (*Client, error) {
+func NewClient(opts *ClientOpts) (*Client, error) {
+	if len(opts.Endpoints) == 0 {
+		return nil, fmt.Errorf("no json-rpc endpoints")
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = time.Second
+	}
+
 	return &Client{
-		endpoint:   endpoint,
-		httpClient: &http.Client{},
+		opts:       opts,
+		httpClient: &http.Client{Transport: opts.Transport},
 	}, nil
 }
 
-func (c *Client) Call(method string, params any) (*Response, error) {
-	req := Request{
-		JSONRPC: "2.0",
-		Method:  method,
-		Params:  params,
-		ID:      1,
-	}
+// Call calls the method with the params, decoding the result into result
+// if it is not nil. A null result leaves result unchanged.
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	req := c.request(method, params)
 
-	reqBytes, err := json.Marshal(req)
+	var resp Response
+	err := c.retry(ctx, isReadOnly(method), func(ctx context.Context, endpoint string) error {
+		resp = Response{}
+		if err := c.post(ctx, endpoint, req, &resp); err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("%s: failed to decode result: %w", method, err)
+	}
+	return nil
+}
+
+// BatchCall sends the requests in a single batch, returning the responses
+// in the order of the requests. Errors of the individual calls are left in
+// the responses, use Response.Bind to get them.
+func (c *Client) BatchCall(ctx context.Context, reqs []Request) ([]*Response, error) {
+	if len(reqs) == 0 {
+		return nil, nil
 	}
 
-	resp, err := c.httpClient.Post(c.endpoint, "application/json", bytes.NewReader(reqBytes))
+	readOnly := true
+	batch := make([]Request, len(reqs))
+	ids := make(map[uint64]int, len(reqs))
+	for i, req := range reqs {
+		batch[i] = c.request(req.Method, req.Params)
+		ids[batch[i].ID] = i
+		readOnly = readOnly && isReadOnly(req.Method)
+	}
+
+	var resps []*Response
+	err := c.retry(ctx, readOnly, func(ctx context.Context, endpoint string) error {
+		var rpcResps []*Response
+		if err := c.post(ctx, endpoint, batch, &rpcResps); err != nil {
+			return err
+		}
+
+		// responses may come back in any order
+		resps = make([]*Response, len(reqs))
+		for _, resp := range rpcResps {
+			if resp == nil {
+				continue
+			}
+			if i, ok := ids[resp.ID]; ok {
+				resps[i] = resp
+			}
+		}
+		for i, resp := range resps {
+			if resp == nil {
+				return fmt.Errorf("no response to batch request %d (%s)", i, reqs[i].Method)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("batch of %d: %w", len(reqs), err)
 	}
-	defer resp.Body.Close()
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
+	return resps, nil
+}
+
+func (c *Client) request(method string, params []any) Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	if params == nil {
+		params = []any{}
+	}
+	return Request{JSONRPC: "2.0", Method: method, Params: params, ID: c.nextID}
+}
+
+// retry calls the call against the current endpoint. If it fails with a
+// retryable error, calls move to the next endpoint, and a read only call is
+// retried there after a jittered backoff.
+func (c *Client) retry(ctx context.Context, readOnly bool, call func(ctx context.Context, endpoint string) error) error {
+	delay := c.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		i, endpoint := c.currentEndpoint()
+
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+		err := call(attemptCtx, endpoint)
+		cancel()
+		if err == nil || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		c.failover(i)
+		if !readOnly || attempt >= c.opts.Retries {
+			return err
+		}
+
+		c.opts.Logger.Debug("JSON-RPC call failed, retrying", "endpoint", endpoint, "attempt", attempt+1, "delay", delay, "error", err)
+		if err := utils.Sleep(ctx, delay/2+rand.N(delay/2+1)); err != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) currentEndpoint() (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.endpoint, c.opts.Endpoints[c.endpoint]
+}
+
+// failover moves calls on to the next endpoint, unless another call has
+// already moved them on from the failed one.
+func (c *Client) failover(failed int) {
+	if len(c.opts.Endpoints) < 2 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.endpoint == failed {
+		c.endpoint = (failed + 1) % len(c.opts.Endpoints)
+		c.opts.Logger.Warn("Failing over to next JSON-RPC endpoint", "from", c.opts.Endpoints[failed], "to", c.opts.Endpoints[c.endpoint])
+	}
+}
+
+func (c *Client) post(ctx context.Context, endpoint string, body any, out any) error {
+	reqBytes, err := json.Marshal(body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var rpcResp Response
-	err = json.Unmarshal(respBytes, &rpcResp)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBytes))
 	if err != nil {
-		return nil, err
+		return err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	return &rpcResp, nil
-}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-func (r *Response) Bind(result any) error {
-	resBytes, err := json.Marshal(r.Result)
+	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(respBytes))}
+	}
+
+	return json.Unmarshal(respBytes, out)
+}
+
+// isReadOnly returns true if calling the method has no side effects, so it
+// is safe to retry.
+func isReadOnly(method string) bool {
+	switch method {
+	case "eth_chainId", "eth_blockNumber", "eth_call", "eth_estimateGas", "eth_gasPrice", "eth_feeHistory", "net_version":
+		return true
+	}
+	return strings.HasPrefix(method, "eth_get")
+}
+
+// retryable returns true if the call may succeed if retried, i.e. the error
+// is from the transport or the endpoint being overloaded, rather than from
+// the call itself.
+func retryable(err error) bool {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == CodeInternalError || rpcErr.Code == CodeLimitExceeded
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
 
-	return json.Unmarshal(resBytes, result)
+	return true
 }
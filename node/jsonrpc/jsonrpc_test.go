@@ -0,0 +1,91 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hummingbird/node/jsonrpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer returns an endpoint that answers every call with the handler,
+// counting the calls.
+func newServer(t *testing.T, handle func(w http.ResponseWriter, req jsonrpc.Request)) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req jsonrpc.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		handle(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+func reply(w http.ResponseWriter, req jsonrpc.Request, body string) {
+	_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,%s}`, req.ID, body)
+}
+
+func unavailable(w http.ResponseWriter, req jsonrpc.Request) {
+	http.Error(w, "unavailable", http.StatusServiceUnavailable)
+}
+
+func TestClient(t *testing.T) {
+	ctx := t.Context()
+
+	down, downCalls := newServer(t, unavailable)
+	up, upCalls := newServer(t, func(w http.ResponseWriter, req jsonrpc.Request) {
+		switch req.Method {
+		case "eth_blockNumber":
+			reply(w, req, `"result":"0x10"`)
+		default:
+			reply(w, req, `"error":{"code":-32000,"message":"nonce too low"}`)
+		}
+	})
+
+	client, err := jsonrpc.NewClient(&jsonrpc.ClientOpts{
+		Endpoints:  []string{down.URL, up.URL},
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// 1. a read only call fails over to the next endpoint
+	var height string
+	require.NoError(t, client.Call(ctx, &height, "eth_blockNumber"))
+	assert.Equal(t, "0x10", height)
+	assert.Equal(t, int32(1), downCalls.Load())
+	assert.Equal(t, int32(1), upCalls.Load())
+
+	// 2. error objects are returned as errors with their code, and not retried
+	err = client.Call(ctx, nil, "eth_sendRawTransaction", "0x00")
+	var rpcErr *jsonrpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32000, rpcErr.Code)
+	assert.Equal(t, int32(2), upCalls.Load())
+
+	// 3. a call with side effects is not retried
+	client, err = jsonrpc.NewClient(&jsonrpc.ClientOpts{
+		Endpoints:  []string{down.URL},
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	err = client.Call(ctx, nil, "eth_sendRawTransaction", "0x00")
+	var httpErr *jsonrpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.Equal(t, int32(2), downCalls.Load())
+
+	// 4. a read only call is retried, then gives up
+	require.Error(t, client.Call(ctx, &height, "eth_blockNumber"))
+	assert.Equal(t, int32(5), downCalls.Load())
+}
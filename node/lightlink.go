@@ -1,12 +1,16 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hummingbird/metrics"
 	"hummingbird/node/jsonrpc"
 	"hummingbird/node/lightlink"
 	"hummingbird/utils"
 	"log/slog"
+	"math/big"
+	"sync"
 	"time"
 
 	"hummingbird/node/lightlink/types"
@@ -15,36 +19,66 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/sync/errgroup"
 )
 
 // LinkLink is a client for the LightLink layer 2 network.
 type LightLink interface {
-	GetHeight() (uint64, error) // GetHeight returns the current height of the lightlink network.
-	GetBlock(height uint64) (*types.Block, error)
-	GetBlocks(start, end uint64) ([]*types.Block, error)
-	GetOutputV0(last *ethtypes.Header) (OutputV0, error)
-	GetProof(address common.Address, keys []string, height uint64) (*RawProof, error)
+	GetChainId(ctx context.Context) (uint64, error)                 // GetChainId returns the chain id of the lightlink network.
+	GetHeight(ctx context.Context) (uint64, error)                  // GetHeight returns the current height of the lightlink network.
+	GetHeightByTag(ctx context.Context, tag string) (uint64, error) // GetHeightByTag returns the height of the block with the tag, e.g. "safe" or "finalized".
+	GetBlock(ctx context.Context, height uint64) (*types.Block, error)
+	GetBlocks(ctx context.Context, start, end, maxSize uint64) ([]*types.Block, error) // GetBlocks returns the blocks from start to end, cut to fit in maxSize bytes as a bundle, or all of them if maxSize is 0.
+	GetOutputV0(ctx context.Context, last *ethtypes.Header) (OutputV0, error)
+	GetProof(ctx context.Context, address common.Address, keys []string, height uint64) (*RawProof, error)
+	GetReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error)
+	GetLogs(ctx context.Context, start, end uint64, address common.Address, topics [][]common.Hash) ([]ethtypes.Log, error)
 	WithdrawalAddress(height uint64) common.Address
 }
 
 type LightLinkClientOpts struct {
 	Endpoint                string
-	Delay                   time.Duration
+	FallbackEndpoints       []string      // optional, endpoints failed over to, in order, if the endpoint fails.
+	Timeout                 time.Duration // Timeout of each request, defaults to 30 seconds.
+	Retries                 int           // Number of times read requests are retried, defaults to 3.
+	Delay                   time.Duration // Min delay between requests when fetching blocks.
+	BatchSize               int           // Number of blocks fetched in each batch request, defaults to 10.
+	Workers                 int           // Number of batch requests in flight at once, defaults to 4.
 	Logger                  *slog.Logger
 	L2ToL1MessagePasserAddr common.Address
+	Metrics                 *metrics.Metrics // optional
 }
 
 type LightLinkClient struct {
-	client *jsonrpc.Client
-	opts   *LightLinkClientOpts
+	client  *jsonrpc.Client
+	opts    *LightLinkClientOpts
+	chainID *big.Int // chain id tx senders are recovered for
+
+	mu          sync.Mutex
+	nextRequest time.Time // earliest time the next block request may be sent
 }
 
-func NewLightLinkClient(opts *LightLinkClientOpts) (*LightLinkClient, error) {
+func NewLightLinkClient(ctx context.Context, opts *LightLinkClientOpts) (*LightLinkClient, error) {
 	if opts.Logger == nil {
 		opts.Logger = slog.Default()
 	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 3
+	}
 
-	client, err := jsonrpc.NewClient(opts.Endpoint)
+	client, err := jsonrpc.NewClient(&jsonrpc.ClientOpts{
+		Endpoints: append([]string{opts.Endpoint}, opts.FallbackEndpoints...),
+		Transport: opts.Metrics.Transport(metrics.BackendLightLink, nil),
+		Timeout:   opts.Timeout,
+		Retries:   opts.Retries,
+		Logger:    opts.Logger,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to LightLink: %w", err)
 	}
@@ -52,129 +86,239 @@ func NewLightLinkClient(opts *LightLinkClientOpts) (*LightLinkClient, error) {
 	ll := &LightLinkClient{client: client, opts: opts}
 
 	// check connection
-	chainId, err := ll.GetChainId()
+	chainId, err := ll.GetChainId(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chain id: %w", err)
 	}
 
+	ll.chainID = new(big.Int).SetUint64(chainId)
+
 	opts.Logger.Info("Connected to LightLink", "chainId", chainId)
 	return ll, nil
 }
 
-func (l *LightLinkClient) GetChainId() (uint64, error) {
-	resp, err := l.client.Call("eth_chainId", nil)
-	if err != nil {
+func (l *LightLinkClient) GetChainId(ctx context.Context) (uint64, error) {
+	var chainId hexutil.Uint64
+	if err := l.client.Call(ctx, &chainId, "eth_chainId"); err != nil {
 		return 0, err
 	}
 
-	numHex := resp.Result.(string)
-	return hexutil.DecodeUint64(numHex)
+	return uint64(chainId), nil
 }
 
-func (l *LightLinkClient) GetHeight() (uint64, error) {
-	resp, err := l.client.Call("eth_blockNumber", nil)
-	if err != nil {
+func (l *LightLinkClient) GetHeight(ctx context.Context) (uint64, error) {
+	var height hexutil.Uint64
+	if err := l.client.Call(ctx, &height, "eth_blockNumber"); err != nil {
 		return 0, err
 	}
 
-	numHex := resp.Result.(string)
-	return hexutil.DecodeUint64(numHex)
+	return uint64(height), nil
 }
 
-func (l *LightLinkClient) GetBlock(height uint64) (*types.Block, error) {
+func (l *LightLinkClient) GetHeightByTag(ctx context.Context, tag string) (uint64, error) {
+	var header *struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	if err := l.client.Call(ctx, &header, "eth_getBlockByNumber", tag, false); err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("no %s block", tag)
+	}
 
-	resp, err := l.client.Call("eth_getBlockByNumber", []any{hexutil.EncodeUint64(height), true})
-	if err != nil {
+	return uint64(header.Number), nil
+}
+
+func (l *LightLinkClient) GetBlock(ctx context.Context, height uint64) (*types.Block, error) {
+	var raw json.RawMessage
+	if err := l.client.Call(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeUint64(height), true); err != nil {
 		return nil, err
 	}
 
-	result, ok := resp.Result.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("response is not a map[string]interface{}: %v", resp.Result)
+	return decodeBlock(raw, l.chainID)
+}
+
+// decodeBlock decodes the block from an eth_getBlockByNumber result, and
+// verifies it against the hash and tx senders the endpoint reported.
+func decodeBlock(raw json.RawMessage, chainID *big.Int) (*types.Block, error) {
+	var body struct {
+		Hash         *common.Hash         `json:"hash"`
+		Transactions []json.RawMessage    `json:"transactions"`
+		Withdrawals  ethtypes.Withdrawals `json:"withdrawals"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode block: %w", err)
+	}
+	if body.Transactions == nil {
+		return nil, fmt.Errorf("block not found")
 	}
-	txs := types.Transactions{}
-	for k, v := range result["transactions"].([]interface{}) {
 
-		tx, err := unmarshalJsonTx(v)
+	txs := types.Transactions{}
+	senders := make([]*common.Address, 0, len(body.Transactions))
+	for k, v := range body.Transactions {
+		tx, err := lightlink.UnMarshallTx(v)
 		if err != nil {
 			return nil, fmt.Errorf("failed to bind transaction %d: %w", k, err)
 		}
+		var from struct {
+			From *common.Address `json:"from"`
+		}
+		if err := json.Unmarshal(v, &from); err != nil {
+			return nil, fmt.Errorf("failed to decode sender of transaction %d: %w", k, err)
+		}
 
 		txs = append(txs, tx)
+		senders = append(senders, from.From)
 	}
 
 	h := &ethtypes.Header{}
-	err = resp.Bind(h)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, h); err != nil {
+		return nil, fmt.Errorf("failed to decode block header: %w", err)
+	}
+
+	block := types.NewBlockWithHeader(h).WithBody(txs, nil)
+	if h.WithdrawalsHash != nil {
+		block = block.WithWithdrawals(body.Withdrawals)
 	}
 
-	return types.NewBlockWithHeader(h).WithBody(txs, nil), nil
+	if err := verifyBlock(block, body.Hash, senders, chainID); err != nil {
+		return nil, fmt.Errorf("block %d failed verification: %w", h.Number, err)
+	}
+	return block, nil
 }
 
-func (l *LightLinkClient) GetBlocks(start, end uint64) ([]*types.Block, error) {
+// GetBlocks returns the blocks from start to end. If maxSize is not 0, it
+// stops early at the last block that keeps them under maxSize bytes encoded
+// as a bundle, and returns an error if the first block alone is over it.
+// Blocks are fetched in batch requests by a pool of workers, a window at a
+// time, so little is fetched past the size limit.
+func (l *LightLinkClient) GetBlocks(ctx context.Context, start, end, maxSize uint64) ([]*types.Block, error) {
+	window := uint64(l.opts.BatchSize * l.opts.Workers)
 
 	var blocks []*types.Block
-	for i := start; i <= end; i++ {
-		var block *types.Block
-		var err error
-
-		// retry up to 5 times in case of connreset or timeout errors etc
-		for retry := 0; retry < 5; retry++ {
-			block, err = l.GetBlock(i)
-			if err == nil {
-				break
-			}
-			time.Sleep(time.Second * time.Duration(2<<retry)) // exponential backoff
-		}
-
-		// if after 5 retries we still have an error, return it
+	sizer := &bundleSizer{limit: maxSize}
+	for from := start; from <= end; from += window {
+		fetched, err := l.fetchBlocks(ctx, from, min(from+window-1, end))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get block at height %d: %w", i, err)
+			return nil, err
 		}
-
-		// check if the block can be added to the bundle or if
-		// the bundle has reached the max celestia tx size limit
-		bundle := &Bundle{Blocks: append(blocks, block)}
-		isUnderLimit, bundleSizeLimit, bundleEncodedSize, err := bundle.IsUnderTxLimit()
-		if err != nil {
-			return nil, fmt.Errorf("failed to check bundle size: %w", err)
+		if maxSize == 0 {
+			blocks = append(blocks, fetched...)
+			continue
 		}
 
-		if !isUnderLimit {
-			l.opts.Logger.Info("Bundle has reached max celestia tx size limit", "blockCount", len(blocks), "bundleSize", bundleEncodedSize, "txSizeLimit", bundleSizeLimit)
-			return blocks, nil
-		}
+		// check if each block can be added to the bundle or if
+		// the bundle has reached the size limit
+		for _, block := range fetched {
+			bundleEncodedSize, isUnderLimit, err := sizer.add(block)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check bundle size: %w", err)
+			}
 
-		// add the block to the bundle
-		blocks = append(blocks, block)
+			if !isUnderLimit {
+				if len(blocks) == 0 {
+					return nil, fmt.Errorf("block %d alone encodes to %d bytes, over the bundle size limit of %d bytes", block.NumberU64(), bundleEncodedSize, maxSize)
+				}
+				l.opts.Logger.Info("Bundle has reached max size limit", "blockCount", len(blocks), "bundleSize", bundleEncodedSize, "sizeLimit", maxSize)
+				return blocks, nil
+			}
 
-		// delay between requests
-		if l.opts.Delay > 0 {
-			time.Sleep(l.opts.Delay)
+			blocks = append(blocks, block)
 		}
 	}
 
 	return blocks, nil
 }
 
-func (l *LightLinkClient) GetWithdrawalRoot(height uint64) (common.Hash, error) {
-	// get the storage root for L2ToL1MessagePasserAddr at the last block height
-	proofRaw, err := l.client.Call("eth_getProof", []any{l.opts.L2ToL1MessagePasserAddr.Hex(), []string{}, hexutil.EncodeUint64(height)})
+// fetchBlocks fetches the blocks from start to end concurrently, in batches,
+// returning them in order.
+func (l *LightLinkClient) fetchBlocks(ctx context.Context, start, end uint64) ([]*types.Block, error) {
+	batchSize := uint64(l.opts.BatchSize)
+	blocks := make([]*types.Block, end-start+1)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(l.opts.Workers)
+	for from := start; from <= end; from += batchSize {
+		to := min(from+batchSize-1, end)
+		g.Go(func() error {
+			batch, err := l.fetchBatch(gctx, from, to)
+			if err != nil {
+				return err
+			}
+			copy(blocks[from-start:], batch)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// fetchBatch fetches the blocks from start to end in a single batch request.
+func (l *LightLinkClient) fetchBatch(ctx context.Context, start, end uint64) ([]*types.Block, error) {
+	reqs := make([]jsonrpc.Request, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		reqs = append(reqs, jsonrpc.Request{
+			Method: "eth_getBlockByNumber",
+			Params: []any{hexutil.EncodeUint64(i), true},
+		})
+	}
+
+	if err := l.throttle(ctx); err != nil {
+		return nil, err
+	}
+
+	// the client retries the batch in case of connreset or timeout errors etc
+	resps, err := l.client.BatchCall(ctx, reqs)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to get withdrawal address proof: %w", err)
+		return nil, fmt.Errorf("failed to get blocks %d to %d: %w", start, end, err)
 	}
-	proof := make(map[string]interface{})
-	err = proofRaw.Bind(&proof)
+
+	blocks := make([]*types.Block, len(resps))
+	for i, resp := range resps {
+		var raw json.RawMessage
+		if err := resp.Bind(&raw); err != nil {
+			return nil, fmt.Errorf("failed to get block at height %d: %w", start+uint64(i), err)
+		}
+		if blocks[i], err = decodeBlock(raw, l.chainID); err != nil {
+			return nil, fmt.Errorf("failed to get block at height %d: %w", start+uint64(i), err)
+		}
+	}
+	return blocks, nil
+}
+
+// throttle waits until Delay has passed since the last block request, so
+// the workers together don't send more than one request per Delay.
+func (l *LightLinkClient) throttle(ctx context.Context) error {
+	if l.opts.Delay <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.nextRequest.After(now) {
+		at = l.nextRequest
+	}
+	l.nextRequest = at.Add(l.opts.Delay)
+	l.mu.Unlock()
+
+	return utils.Sleep(ctx, at.Sub(now))
+}
+
+func (l *LightLinkClient) GetWithdrawalRoot(ctx context.Context, height uint64) (common.Hash, error) {
+	// get the storage root for L2ToL1MessagePasserAddr at the last block height
+	proof, err := l.GetProof(ctx, l.opts.L2ToL1MessagePasserAddr, []string{}, height)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to bind withdrawal address proof: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to get withdrawal address proof: %w", err)
 	}
-	withdrawalRoot := proof["storageHash"].(string)
-	if withdrawalRoot == "" {
+	if proof.StorageHash == "" {
 		return common.Hash{}, fmt.Errorf("failed to get withdrawal address proof: storageHash is empty")
 	}
 
-	return common.HexToHash(withdrawalRoot), nil
+	return common.HexToHash(proof.StorageHash), nil
 }
 
 func (l *LightLinkClient) WithdrawalAddress(height uint64) common.Address {
@@ -195,19 +339,40 @@ type RawProof struct {
 	} `json:"storageProof"`
 }
 
-func (l *LightLinkClient) GetProof(address common.Address, keys []string, height uint64) (*RawProof, error) {
-	proofRaw, err := l.client.Call("eth_getProof", []any{address.Hex(), keys, hexutil.EncodeUint64(height)})
-	if err != nil {
+func (l *LightLinkClient) GetProof(ctx context.Context, address common.Address, keys []string, height uint64) (*RawProof, error) {
+	proof := &RawProof{}
+	if err := l.client.Call(ctx, proof, "eth_getProof", address.Hex(), keys, hexutil.EncodeUint64(height)); err != nil {
 		return nil, fmt.Errorf("failed to get proof: %w", err)
 	}
 
-	proof := &RawProof{}
-	err = proofRaw.Bind(proof)
-	if err != nil {
-		return nil, fmt.Errorf("failed to bind proof: %w", err)
+	return proof, nil
+}
+
+func (l *LightLinkClient) GetReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	var receipt *ethtypes.Receipt
+	if err := l.client.Call(ctx, &receipt, "eth_getTransactionReceipt", txHash.Hex()); err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("receipt for tx %s not found", txHash.Hex())
 	}
 
-	return proof, nil
+	return receipt, nil
+}
+
+func (l *LightLinkClient) GetLogs(ctx context.Context, start, end uint64, address common.Address, topics [][]common.Hash) ([]ethtypes.Log, error) {
+	filter := map[string]any{
+		"fromBlock": hexutil.EncodeUint64(start),
+		"toBlock":   hexutil.EncodeUint64(end),
+		"address":   address.Hex(),
+		"topics":    topics,
+	}
+	logs := []ethtypes.Log{}
+	if err := l.client.Call(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+
+	return logs, nil
 }
 
 type OutputV0 struct {
@@ -230,8 +395,8 @@ func (o OutputV0) Root() common.Hash {
 	return crypto.Keccak256Hash(buf[:])
 }
 
-func (l *LightLinkClient) GetOutputV0(last *ethtypes.Header) (OutputV0, error) {
-	withdrawalRoot, err := l.GetWithdrawalRoot(last.Number.Uint64())
+func (l *LightLinkClient) GetOutputV0(ctx context.Context, last *ethtypes.Header) (OutputV0, error) {
+	withdrawalRoot, err := l.GetWithdrawalRoot(ctx, last.Number.Uint64())
 	if err != nil {
 		return OutputV0{}, err
 	}
@@ -244,48 +409,70 @@ func (l *LightLinkClient) GetOutputV0(last *ethtypes.Header) (OutputV0, error) {
 	}, nil
 }
 
-func unmarshalJsonTx(from any) (*types.Transaction, error) {
-	b, err := json.Marshal(from)
-	if err != nil {
-		return nil, err
-	}
-
-	return lightlink.UnMarshallTx(b)
-}
-
 // LightLinkMock is a mock LightLink client.
 
+var _ LightLink = &lightLinkMock{}
+
 type lightLinkMock struct {
 	Height uint64
-	Blocks []*ethtypes.Block
+	Blocks []*types.Block
 }
 
 func NewLightLinkMock() *lightLinkMock {
-	return &lightLinkMock{Height: 0, Blocks: []*ethtypes.Block{}}
+	return &lightLinkMock{Height: 0, Blocks: []*types.Block{}}
+}
+
+func (m *lightLinkMock) GetChainId(ctx context.Context) (uint64, error) {
+	return 0, nil
+}
+
+func (m *lightLinkMock) GetHeight(ctx context.Context) (uint64, error) {
+	return m.Height, nil
 }
 
-func (m *lightLinkMock) GetHeight() (uint64, error) {
+func (m *lightLinkMock) GetHeightByTag(ctx context.Context, tag string) (uint64, error) {
 	return m.Height, nil
 }
 
-func (m *lightLinkMock) GetBlock(height uint64) (*ethtypes.Block, error) {
+func (m *lightLinkMock) GetBlock(ctx context.Context, height uint64) (*types.Block, error) {
+	if height >= uint64(len(m.Blocks)) {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
 	return m.Blocks[height], nil
 }
 
-func (m *lightLinkMock) GetBlocks(start, end uint64) ([]*ethtypes.Block, error) {
-	return m.Blocks[start:end], nil
+func (m *lightLinkMock) GetBlocks(ctx context.Context, start, end, maxSize uint64) ([]*types.Block, error) {
+	if start > end || end >= uint64(len(m.Blocks)) {
+		return nil, fmt.Errorf("blocks %d to %d not found", start, end)
+	}
+	if maxSize == 0 {
+		return m.Blocks[start : end+1], nil
+	}
+	bundle, err := PackBundle(m.Blocks[start:end+1], maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Blocks, nil
 }
 
-func (m *lightLinkMock) SimulateAddBlock(block *ethtypes.Block) {
+func (m *lightLinkMock) SimulateAddBlock(block *types.Block) {
 	m.Blocks = append(m.Blocks, block)
-	m.Height++
+	m.Height = uint64(len(m.Blocks) - 1)
 }
 
-func (m *lightLinkMock) GetOutputV0(last *ethtypes.Header) (OutputV0, error) {
+func (m *lightLinkMock) GetOutputV0(ctx context.Context, last *ethtypes.Header) (OutputV0, error) {
 	return OutputV0{}, nil
 }
 
-func (m *lightLinkMock) GetProof(address common.Address, keys []string, height uint64) (*RawProof, error) {
+func (m *lightLinkMock) GetProof(ctx context.Context, address common.Address, keys []string, height uint64) (*RawProof, error) {
+	return nil, nil
+}
+
+func (m *lightLinkMock) GetReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	return nil, fmt.Errorf("receipt for tx %s not found", txHash.Hex())
+}
+
+func (m *lightLinkMock) GetLogs(ctx context.Context, start, end uint64, address common.Address, topics [][]common.Hash) ([]ethtypes.Log, error) {
 	return nil, nil
 }
 
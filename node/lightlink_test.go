@@ -0,0 +1,114 @@
+package node
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"hummingbird/node/jsonrpc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchServer returns a JSON-RPC server that answers batches of
+// eth_getBlockByNumber requests, in reverse order, with blocks carrying
+// extra data of the given size. It records the size of each batch.
+func newBatchServer(t *testing.T, extraSize int) (*httptest.Server, func() []int) {
+	var mu sync.Mutex
+	var batches []int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []jsonrpc.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+
+		mu.Lock()
+		batches = append(batches, len(reqs))
+		mu.Unlock()
+
+		resps := make([]map[string]any, 0, len(reqs))
+		for _, req := range slices.Backward(reqs) {
+			height, err := hexutil.DecodeUint64(req.Params[0].(string))
+			require.NoError(t, err)
+
+			header, err := json.Marshal(&ethtypes.Header{
+				Number:     new(big.Int).SetUint64(height),
+				Difficulty: big.NewInt(0),
+				TxHash:     ethtypes.EmptyTxsHash,
+				Extra:      make([]byte, extraSize),
+			})
+			require.NoError(t, err)
+			block := map[string]any{}
+			require.NoError(t, json.Unmarshal(header, &block))
+			block["transactions"] = []any{}
+
+			resps = append(resps, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": block})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resps))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(batches)
+	}
+}
+
+func newTestLightLinkClient(t *testing.T, endpoint string) *LightLinkClient {
+	client, err := jsonrpc.NewClient(&jsonrpc.ClientOpts{Endpoints: []string{endpoint}})
+	require.NoError(t, err)
+	return &LightLinkClient{client: client, opts: &LightLinkClientOpts{
+		BatchSize: 4,
+		Workers:   3,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}}
+}
+
+func TestGetBlocks(t *testing.T) {
+	ctx := t.Context()
+
+	// 1. blocks are fetched in batches and returned in order
+	srv, batches := newBatchServer(t, 0)
+	l := newTestLightLinkClient(t, srv.URL)
+
+	blocks, err := l.GetBlocks(ctx, 5, 29, 0)
+	require.NoError(t, err)
+	require.Len(t, blocks, 25)
+	for i, block := range blocks {
+		assert.Equal(t, uint64(5+i), block.NumberU64())
+	}
+	assert.Len(t, batches(), 7)
+	assert.LessOrEqual(t, slices.Max(batches()), 4)
+
+	// 2. the blocks are cut at the last one that keeps the bundle under
+	// the size limit
+	srv, _ = newBatchServer(t, 100_000)
+	l = newTestLightLinkClient(t, srv.URL)
+
+	blocks, err = l.GetBlocks(ctx, 0, 100, BundleSizeLimit)
+	require.NoError(t, err)
+	require.NotEmpty(t, blocks)
+	require.Less(t, len(blocks), 101)
+
+	underLimit, _, _, err := (&Bundle{Blocks: blocks}).IsUnderTxLimit()
+	require.NoError(t, err)
+	assert.True(t, underLimit)
+
+	next, err := l.fetchBlocks(ctx, uint64(len(blocks)), uint64(len(blocks)))
+	require.NoError(t, err)
+	underLimit, _, _, err = (&Bundle{Blocks: append(blocks, next...)}).IsUnderTxLimit()
+	require.NoError(t, err)
+	assert.False(t, underLimit)
+	// 3. a first block over the size limit is an error, not an empty slice
+	_, err = l.GetBlocks(ctx, 0, 100, 10)
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package node
+
+import (
+	"fmt"
+	"math/big"
+
+	"hummingbird/node/lightlink/types"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// verifyBlock checks a block fetched from LightLink is consistent with its
+// header before it goes into a bundle, so a corrupt or tampered response is
+// caught before it is published to Celestia, where it would be permanent
+// and challengeable.
+//
+// If the endpoint reported the block hash or the sender of a tx, they are
+// checked too. Senders are recovered for the chainID.
+func verifyBlock(block *types.Block, hash *common.Hash, senders []*common.Address, chainID *big.Int) error {
+	header := block.Header()
+
+	// 1. the tx root commits to the txs
+	if root := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); root != header.TxHash {
+		return fmt.Errorf("tx root %s, header has %s", root.Hex(), header.TxHash.Hex())
+	}
+
+	// 2. the withdrawals root commits to the withdrawals
+	if header.WithdrawalsHash != nil {
+		if root := types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)); root != *header.WithdrawalsHash {
+			return fmt.Errorf("withdrawals root %s, header has %s", root.Hex(), header.WithdrawalsHash.Hex())
+		}
+	}
+
+	// 3. every tx is signed, by the reported sender
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(txSigner(tx, chainID), tx)
+		if err != nil {
+			return fmt.Errorf("failed to recover sender of tx %d %s: %w", i, tx.Hash().Hex(), err)
+		}
+		if i < len(senders) && senders[i] != nil && from != *senders[i] {
+			return fmt.Errorf("tx %d %s is signed by %s, reported sender %s", i, tx.Hash().Hex(), from.Hex(), senders[i].Hex())
+		}
+	}
+
+	// 4. the header hashes to the reported hash
+	if hash != nil && block.Hash() != *hash {
+		return fmt.Errorf("hash %s, reported %s", block.Hash().Hex(), hash.Hex())
+	}
+
+	// 5. the block round-trips through the RLP it is published as, to the
+	// same header hash the L1 contracts check
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return fmt.Errorf("failed to encode block: %w", err)
+	}
+	decoded := &types.Block{}
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		return fmt.Errorf("failed to decode encoded block: %w", err)
+	}
+	want := utils.HashHeaderWithoutExtraData(ethtypes.CopyHeader(header))
+	if got := utils.HashHeaderWithoutExtraData(decoded.Header()); got != want {
+		return fmt.Errorf("header hash %s does not round-trip, decoded as %s", want.Hex(), got.Hex())
+	}
+	if root := types.DeriveSha(decoded.Transactions(), trie.NewStackTrie(nil)); root != header.TxHash {
+		return fmt.Errorf("txs do not round-trip, decoded tx root %s", root.Hex())
+	}
+
+	return nil
+}
+
+// txSigner returns the signer to recover the sender of the tx with. The
+// LightLink signer handles legacy and deposit txs, the latest signer the
+// typed txs it does not.
+func txSigner(tx *types.Transaction, chainID *big.Int) types.Signer {
+	switch tx.Type() {
+	case types.AccessListTxType, types.DynamicFeeTxType, types.BlobTxType:
+		return types.LatestSignerForChainID(chainID)
+	}
+	return types.NewLightLinkSigner(chainID)
+}
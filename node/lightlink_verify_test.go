@@ -0,0 +1,69 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"hummingbird/node/lightlink/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBlock(t *testing.T) {
+	chainID := big.NewInt(1891)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	signTx := func(chainID *big.Int) *types.Transaction {
+		return types.MustSignNewTx(key, types.NewEIP155Signer(chainID), &types.LegacyTx{
+			To:       &common.Address{0x01},
+			Value:    big.NewInt(1),
+			Gas:      21_000,
+			GasPrice: big.NewInt(1),
+		})
+	}
+	newBlock := func(txs types.Transactions, withdrawals ethtypes.Withdrawals, withdrawalsHash *common.Hash) *types.Block {
+		header := &ethtypes.Header{
+			Number:          big.NewInt(1),
+			Difficulty:      big.NewInt(0),
+			TxHash:          types.DeriveSha(txs, trie.NewStackTrie(nil)),
+			WithdrawalsHash: withdrawalsHash,
+			Extra:           []byte("extra"),
+		}
+		block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+		if withdrawalsHash != nil {
+			block = block.WithWithdrawals(withdrawals)
+		}
+		return block
+	}
+
+	// 1. a consistent block, with the reported hash and sender, is valid
+	block := newBlock(types.Transactions{signTx(chainID)}, nil, nil)
+	hash := block.Hash()
+	require.NoError(t, verifyBlock(block, &hash, []*common.Address{&sender}, chainID))
+
+	// 2. the txs must match the tx root
+	tampered := types.NewBlockWithHeader(block.Header()).WithBody(types.Transactions{signTx(chainID), signTx(chainID)}, nil)
+	assert.ErrorContains(t, verifyBlock(tampered, nil, nil, chainID), "tx root")
+
+	// 3. the header must hash to the reported hash
+	other := common.Hash{0x01}
+	assert.ErrorContains(t, verifyBlock(block, &other, nil, chainID), "reported")
+
+	// 4. the txs must be signed by the reported sender, for the chain
+	assert.ErrorContains(t, verifyBlock(block, nil, []*common.Address{{0x02}}, chainID), "reported sender")
+	block = newBlock(types.Transactions{signTx(big.NewInt(1))}, nil, nil)
+	assert.ErrorContains(t, verifyBlock(block, nil, nil, chainID), "failed to recover sender")
+
+	// 5. the withdrawals must match the withdrawals root
+	withdrawals := ethtypes.Withdrawals{{Index: 1, Address: common.Address{0x03}, Amount: 1}}
+	root := types.DeriveSha(withdrawals, trie.NewStackTrie(nil))
+	require.NoError(t, verifyBlock(newBlock(nil, withdrawals, &root), nil, nil, chainID))
+	assert.ErrorContains(t, verifyBlock(newBlock(nil, nil, &root), nil, nil, chainID), "withdrawals root")
+}
@@ -1,17 +1,19 @@
 package node
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"hummingbird/config"
+	"hummingbird/metrics"
 	canonicalstatechain "hummingbird/node/contracts/CanonicalStateChain.sol"
 	"hummingbird/node/ethereum"
+	"hummingbird/node/signer"
 	"log/slog"
 	"math/big"
 	"runtime"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/spf13/viper"
 )
 
@@ -20,11 +22,13 @@ type Node struct {
 	Celestia
 	LightLink
 
-	Store KVStore
+	Store     KVStore
+	Metrics   *metrics.Metrics // nil records no metrics
+	Heartbeat *Heartbeat       // nil records no heartbeat
 }
 
 // NewFromConfig creates a new node from the given config.
-func NewFromConfig(cfg *config.Config, logger *slog.Logger, ethKey *ecdsa.PrivateKey) (*Node, error) {
+func NewFromConfig(ctx context.Context, cfg *config.Config, logger *slog.Logger, ethSigner signer.Signer) (*Node, error) {
 
 	logger.Info("Starting LightLink Hummingbird ("+viper.GetString("version")+")",
 		"Go Version", runtime.Version(),
@@ -34,24 +38,53 @@ func NewFromConfig(cfg *config.Config, logger *slog.Logger, ethKey *ecdsa.Privat
 	// log config file path
 	logger.Info("Using config file", "path", viper.ConfigFileUsed())
 
-	eth, err := ethereum.NewClient(ethereum.ClientOpts{
+	m := metrics.New()
+
+	// leave store as a nil interface if disabled, so callers can check
+	// for it with n.Store == nil
+	var store KVStore
+	var err error
+
+	if cfg.Rollup.Store {
+		store, err = NewLDBStore(cfg.StorePath)
+		if err != nil {
+			return nil, err
+		}
+		// the store is opened first for the ethereum client's nonces, so
+		// release it if a client fails to connect
+		defer func() {
+			if err != nil {
+				store.Close()
+			}
+		}()
+	}
+
+	maxFeePerGas, _ := new(big.Float).Mul(big.NewFloat(cfg.Ethereum.MaxFeePerGas), big.NewFloat(params.GWei)).Int(nil)
+	eth, err := ethereum.NewClient(ctx, ethereum.ClientOpts{
 		Endpoint:                   cfg.Ethereum.HTTPEndpoint,
+		WSEndpoint:                 cfg.Ethereum.WSEndpoint,
 		CanonicalStateChainAddress: common.HexToAddress(cfg.Ethereum.CanonicalStateChain),
 		ChallengeAddress:           common.HexToAddress(cfg.Ethereum.Challenge),
 		ChainOracleAddress:         common.HexToAddress(cfg.Ethereum.ChainOracle),
 		BlobstreamXAddress:         common.HexToAddress(cfg.Ethereum.BlobstreamX),
-		Signer:                     ethKey,
+		LightLinkPortalAddress:     common.HexToAddress(cfg.Ethereum.LightLinkPortal),
+		Signer:                     ethSigner,
 		Logger:                     logger.With("ctx", "ethereum-http"),
 		DryRun:                     cfg.DryRun,
 		GasPriceIncreasePercent:    big.NewInt(int64(cfg.Ethereum.GasPriceIncreasePercent)),
+		MaxFeePerGas:               maxFeePerGas,
+		ReplaceAfter:               time.Duration(cfg.Ethereum.ReplaceAfter) * time.Millisecond,
+		FeeBumpPercent:             cfg.Ethereum.FeeBumpPercent,
 		BlockTime:                  cfg.Ethereum.BlockTime,
 		Timeout:                    time.Duration(cfg.Ethereum.Timeout) * time.Minute,
+		NonceStore:                 store,
+		Metrics:                    m,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	cel, err := NewCelestiaClient(CelestiaClientOpts{
+	cel, err := NewCelestiaClient(ctx, CelestiaClientOpts{
 		Endpoint:                cfg.Celestia.Endpoint,
 		Token:                   cfg.Celestia.Token,
 		TendermintRPC:           cfg.Celestia.TendermintRPC,
@@ -59,53 +92,69 @@ func NewFromConfig(cfg *config.Config, logger *slog.Logger, ethKey *ecdsa.Privat
 		Logger:                  logger.With("ctx", "celestia"),
 		GasPrice:                cfg.Celestia.GasPrice,
 		GasPriceIncreasePercent: big.NewInt(int64(cfg.Celestia.GasPriceIncreasePercent)),
+		MaxGasPrice:             cfg.Celestia.MaxGasPrice,
+		RetryIncreasePercent:    cfg.Celestia.RetryIncreasePercent,
 		GasAPI:                  cfg.Celestia.GasAPI,
 		Retries:                 cfg.Celestia.Retries,
 		RetryDelay:              time.Duration(cfg.Celestia.RetryDelay) * time.Millisecond,
+		InclusionTimeout:        time.Duration(cfg.Celestia.InclusionTimeout) * time.Millisecond,
+		Metrics:                 m,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	ll, err := NewLightLinkClient(&LightLinkClientOpts{
+	ll, err := NewLightLinkClient(ctx, &LightLinkClientOpts{
 		Endpoint:                cfg.LightLink.Endpoint,
+		FallbackEndpoints:       cfg.LightLink.FallbackEndpoints,
+		Timeout:                 time.Duration(cfg.LightLink.Timeout) * time.Millisecond,
+		Retries:                 cfg.LightLink.Retries,
 		Delay:                   time.Duration(cfg.LightLink.Delay) * time.Millisecond,
+		BatchSize:               cfg.LightLink.BatchSize,
+		Workers:                 cfg.LightLink.Workers,
 		Logger:                  logger.With("ctx", "lightlink"),
 		L2ToL1MessagePasserAddr: common.HexToAddress(cfg.LightLink.L2ToL1MessagePasser),
+		Metrics:                 m,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	var store *LDBStore
-
-	if cfg.Rollup.Store {
-		store, err = NewLDBStore(cfg.StorePath)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	logger.Info("Rollup Node created!", "dryRun", cfg.DryRun)
 
-	logger.Info("Ethereum private key address", "address", crypto.PubkeyToAddress(ethKey.PublicKey).Hex())
+	logger.Info("Ethereum signer address", "address", ethSigner.Address().Hex())
 
 	return &Node{
 		Ethereum:  eth,
 		Celestia:  cel,
 		LightLink: ll,
 
-		Store: store,
+		Store:     store,
+		Metrics:   m,
+		Heartbeat: NewHeartbeat(),
 	}, nil
 }
 
-// GetDAPointer gets the Celestia pointer for the given rollup block hash.
-func (n *Node) GetDAPointer(hash common.Hash) ([]*CelestiaPointer, error) {
+// Close releases the resources held by the node, closing the store if it is
+// enabled.
+func (n *Node) Close() error {
+	if n.Store == nil {
+		return nil
+	}
+	return n.Store.Close()
+}
 
-	// TODO FETCH FROM LOCAL STORE!
+// GetDAPointer gets the Celestia pointers for the given rollup block hash,
+// from the local store if it has them.
+func (n *Node) GetDAPointer(ctx context.Context, hash common.Hash) ([]*CelestiaPointer, error) {
+	if n.Store != nil {
+		if pointers, err := n.Store.GetDAPointers(ctx, hash); err == nil {
+			return pointers, nil
+		}
+	}
 
 	// pointer is not found in local store so get rollup header
-	header, err := n.Ethereum.GetRollupHeaderByHash(hash)
+	header, err := n.Ethereum.GetRollupHeaderByHash(ctx, hash)
 	if err != nil {
 		return nil, err
 	}
@@ -124,8 +173,8 @@ func (n *Node) GetDAPointer(hash common.Hash) ([]*CelestiaPointer, error) {
 	return pointers, nil
 }
 
-func (n *Node) FetchRollupBlock(rblock common.Hash) (*canonicalstatechain.CanonicalStateChainHeader, []*Bundle, error) {
-	header, err := n.Ethereum.GetRollupHeaderByHash(rblock)
+func (n *Node) FetchRollupBlock(ctx context.Context, rblock common.Hash) (*canonicalstatechain.CanonicalStateChainHeader, []*Bundle, error) {
+	header, err := n.Ethereum.GetRollupHeaderByHash(ctx, rblock)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -138,7 +187,7 @@ func (n *Node) FetchRollupBlock(rblock common.Hash) (*canonicalstatechain.Canoni
 			ShareLen:   uint64(header.CelestiaPointers[i].ShareLen),
 		}
 
-		shares, err := n.Celestia.GetSharesByNamespace(pointer)
+		shares, err := n.Celestia.GetSharesByNamespace(ctx, pointer)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -154,19 +203,12 @@ func (n *Node) FetchRollupBlock(rblock common.Hash) (*canonicalstatechain.Canoni
 	return &header, bundles, nil
 }
 
-// Returns true if the given ethKey is the publisher set in CanonicalStateChain
-func (n *Node) IsPublisher(ethKey *ecdsa.PrivateKey) bool {
-	if ethKey == nil {
-		panic("eth key is nil")
-	}
-
-	p, err := n.Ethereum.GetPublisher()
+// Returns true if the given address is the publisher set in CanonicalStateChain
+func (n *Node) IsPublisher(ctx context.Context, addr common.Address) bool {
+	p, err := n.Ethereum.GetPublisher(ctx)
 	if err != nil {
 		panic(err)
 	}
 
-	// Get address of public key
-	addr := crypto.PubkeyToAddress(ethKey.PublicKey)
-
 	return p == addr
 }
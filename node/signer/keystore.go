@@ -0,0 +1,35 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// NewKeystore returns a signer for the key in the encrypted go-ethereum
+// keystore JSON file at path.
+func NewKeystore(path, password string) (*PrivateKey, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+
+	return NewPrivateKey(key.PrivateKey), nil
+}
+
+// ReadPassword reads a keystore password from the file at path, ignoring
+// any trailing newline.
+func ReadPassword(path string) (string, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
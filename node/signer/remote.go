@@ -0,0 +1,130 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"hummingbird/node/jsonrpc"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type RemoteOpts struct {
+	Endpoint  string            // Endpoint of the remote signer, e.g. clef or web3signer.
+	Address   common.Address    // Address the remote signer signs for.
+	Transport http.RoundTripper // optional, defaults to http.DefaultTransport
+}
+
+// Remote signs with a remote signer over the eth_signTransaction JSON-RPC
+// method, so the key never leaves the signer, e.g. an HSM or KMS backed one.
+type Remote struct {
+	client  *jsonrpc.Client
+	address common.Address
+}
+
+func NewRemote(opts *RemoteOpts) (*Remote, error) {
+	if opts.Address == (common.Address{}) {
+		return nil, fmt.Errorf("remote signer address not set")
+	}
+
+	client, err := jsonrpc.NewClient(&jsonrpc.ClientOpts{
+		Endpoints: []string{opts.Endpoint},
+		Transport: opts.Transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote signer client: %w", err)
+	}
+
+	return &Remote{client: client, address: opts.Address}, nil
+}
+
+func (r *Remote) Address() common.Address {
+	return r.address
+}
+
+// txArgs are the eth_signTransaction params, as go-ethereum accepts them.
+type txArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+func (r *Remote) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := txArgs{
+		From:    r.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("remote signer does not support tx type %d", tx.Type())
+	}
+
+	var result json.RawMessage
+	if err := r.client.Call(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("failed to call remote signer: %w", err)
+	}
+
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed tx: %w", err)
+	}
+
+	// check the remote signer signed the tx we asked it to, for our address
+	txSigner := types.LatestSignerForChainID(chainID)
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, fmt.Errorf("remote signer signed a different tx")
+	}
+	sender, err := types.Sender(txSigner, signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signed tx sender: %w", err)
+	}
+	if sender != r.address {
+		return nil, fmt.Errorf("remote signer signed for %s, expected %s", sender.Hex(), r.address.Hex())
+	}
+
+	return signed, nil
+}
+
+// decodeSignResult returns the raw signed tx. go-ethereum returns it with
+// the decoded tx as {raw, tx}, while other signers return the raw tx only.
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+
+	var res struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &res); err != nil || len(res.Raw) == 0 {
+		return nil, fmt.Errorf("unexpected remote signer result: %s", result)
+	}
+	return res.Raw, nil
+}
@@ -0,0 +1,90 @@
+// Package signer signs the L1 transactions sent by the ethereum client, so
+// the key can be held in an encrypted keystore or by a remote signer rather
+// than in the ETH_KEY env var.
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs transactions for a single address.
+type Signer interface {
+	Address() common.Address                                                                         // Address returns the address transactions are signed for.
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) // SignTx returns the tx signed for the chain id.
+}
+
+// PrivateKey signs with a private key held in memory.
+type PrivateKey struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewPrivateKey(key *ecdsa.PrivateKey) *PrivateKey {
+	return &PrivateKey{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (p *PrivateKey) Address() common.Address {
+	return p.address
+}
+
+func (p *PrivateKey) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), p.key)
+}
+
+// ErrMockSigner is returned by a Mock set to fail.
+var ErrMockSigner = errors.New("mock signer failed")
+
+// Mock signs with a generated key and records every tx it signs, for tests.
+type Mock struct {
+	*PrivateKey
+
+	mu     sync.Mutex
+	signed []*types.Transaction
+	fail   bool
+}
+
+func NewMock() *Mock {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return &Mock{PrivateKey: NewPrivateKey(key)}
+}
+
+func (m *Mock) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fail {
+		return nil, ErrMockSigner
+	}
+
+	signed, err := m.PrivateKey.SignTx(ctx, tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	m.signed = append(m.signed, signed)
+	return signed, nil
+}
+
+// SetFail sets whether SignTx fails with ErrMockSigner.
+func (m *Mock) SetFail(fail bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fail = fail
+}
+
+// Signed returns the txs signed so far, in order.
+func (m *Mock) Signed() []*types.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*types.Transaction(nil), m.signed...)
+}
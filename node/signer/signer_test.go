@@ -0,0 +1,123 @@
+package signer_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hummingbird/node/signer"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chainID = big.NewInt(1337)
+
+func newTx() *types.Transaction {
+	to := common.Address{0x01}
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21_000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+}
+
+func TestKeystore(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "password", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0o600))
+
+	_, err = signer.NewKeystore(path, "wrong")
+	require.Error(t, err)
+
+	s, err := signer.NewKeystore(path, "password")
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Address())
+
+	signed, err := s.SignTx(t.Context(), newTx(), chainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	assert.Equal(t, s.Address(), sender)
+}
+
+// remoteSigner serves eth_signTransaction the way go-ethereum does,
+// signing with the given signer whatever address is asked for.
+func remoteSigner(t *testing.T, s signer.Signer) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []struct {
+				To                   *common.Address `json:"to"`
+				Gas                  hexutil.Uint64  `json:"gas"`
+				MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+				MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+				Value                *hexutil.Big    `json:"value"`
+				Nonce                hexutil.Uint64  `json:"nonce"`
+				Data                 hexutil.Bytes   `json:"data"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		args := req.Params[0]
+
+		signed, err := s.SignTx(r.Context(), types.NewTx(&types.DynamicFeeTx{
+			Nonce:     uint64(args.Nonce),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     args.Value.ToInt(),
+			Data:      args.Data,
+		}), chainID)
+		require.NoError(t, err)
+		raw, err := signed.MarshalBinary()
+		require.NoError(t, err)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result":  map[string]any{"raw": hexutil.Bytes(raw), "tx": signed},
+		})
+	}))
+}
+
+func TestRemote(t *testing.T) {
+	key := signer.NewMock()
+	srv := remoteSigner(t, key)
+	defer srv.Close()
+
+	// 1. signs for the configured address
+	s, err := signer.NewRemote(&signer.RemoteOpts{Endpoint: srv.URL, Address: key.Address()})
+	require.NoError(t, err)
+	tx := newTx()
+	signed, err := s.SignTx(t.Context(), tx, chainID)
+	require.NoError(t, err)
+	assert.Equal(t, key.Signed()[0].Hash(), signed.Hash())
+	assert.Equal(t, tx.Nonce(), signed.Nonce())
+
+	// 2. rejects a tx signed for another address
+	s, err = signer.NewRemote(&signer.RemoteOpts{Endpoint: srv.URL, Address: common.Address{0x02}})
+	require.NoError(t, err)
+	_, err = s.SignTx(t.Context(), tx, chainID)
+	require.ErrorContains(t, err, "remote signer signed for")
+}
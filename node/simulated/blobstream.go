@@ -0,0 +1,122 @@
+package simulated
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"hummingbird/node/ethereum"
+
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
+)
+
+// commitment is a BlobstreamX data commitment over the Celestia heights
+// [start, end).
+type commitment struct {
+	nonce uint64
+	start uint64
+	end   uint64
+	root  common.Hash
+}
+
+var _ ethereum.BlobstreamX = &Ethereum{}
+
+// RelayBlobstream commits the data roots of every Celestia block not yet
+// covered by a commitment, as the BlobstreamX relayer would. It returns the
+// new commitments nonce, or 0 if there was nothing to commit.
+func (e *Ethereum) RelayBlobstream() (uint64, error) {
+	height := e.celestia.Height()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if height <= e.committedHeight {
+		return 0, nil
+	}
+
+	start, end := e.committedHeight+1, height+1
+	leaves := [][]byte{}
+	for h := start; h < end; h++ {
+		root, err := e.celestia.DataRoot(h)
+		if err != nil {
+			return 0, err
+		}
+		leaves = append(leaves, append(common.BigToHash(u256(h)).Bytes(), root[:]...))
+	}
+
+	c := &commitment{
+		nonce: uint64(len(e.commitments)) + 1,
+		start: start,
+		end:   end,
+		root:  common.BytesToHash(merkle.HashFromByteSlices(leaves)),
+	}
+	e.commitments = append(e.commitments, c)
+	e.committedHeight = height
+
+	tx := e.mine(BlobstreamXAddress, nil)
+	e.emit(blobstreamXABI, BlobstreamXAddress, tx, "DataCommitmentStored", u256(c.nonce), c.start, c.end, [32]byte(c.root))
+
+	return c.nonce, nil
+}
+
+func (e *Ethereum) FilterDataCommitmentStored(opts *bind.FilterOpts, startBlock []uint64, endBlock []uint64, dataCommitment [][32]byte) (*blobstreamXContract.BlobstreamXDataCommitmentStoredIterator, error) {
+	return e.blobstreamX.FilterDataCommitmentStored(opts, startBlock, endBlock, dataCommitment)
+}
+
+func (e *Ethereum) DAVerify(ctx context.Context, proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.verifyAttestation(proofNonce, tuple, proof)
+}
+
+// verifyAttestation checks the tuple is part of the commitment with the
+// given nonce. The merkle side nodes are not checked, instead the tuple is
+// compared against the Celestia block it claims to be for. Must be called
+// with the lock held.
+func (e *Ethereum) verifyAttestation(proofNonce *big.Int, tuple blobstreamXContract.DataRootTuple, proof blobstreamXContract.BinaryMerkleProof) (bool, error) {
+	if proofNonce == nil || proofNonce.Sign() <= 0 || proofNonce.Uint64() > uint64(len(e.commitments)) {
+		return false, nil
+	}
+	c := e.commitments[proofNonce.Uint64()-1]
+
+	height := tuple.Height.Uint64()
+	if height < c.start || height >= c.end {
+		return false, nil
+	}
+	if proof.Key == nil || proof.Key.Uint64() != height-c.start || proof.NumLeaves == nil || proof.NumLeaves.Uint64() != c.end-c.start {
+		return false, nil
+	}
+
+	root, err := e.celestia.DataRoot(height)
+	if err != nil {
+		return false, nil
+	}
+	return root == tuple.DataRoot, nil
+}
+
+func (e *Ethereum) GetBlobstreamCommitment(ctx context.Context, height int64) (*blobstreamXContract.BlobstreamXDataCommitmentStored, error) {
+	events, err := e.FilterDataCommitmentStored(&bind.FilterOpts{Context: context.Background()}, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter events: %w", err)
+	}
+
+	lastCommitHeight := uint64(0)
+	for events.Next() {
+		ev := events.Event
+		if ev.EndBlock > lastCommitHeight {
+			lastCommitHeight = ev.EndBlock
+		}
+		if int64(ev.StartBlock) <= height && height < int64(ev.EndBlock) {
+			return ev, nil
+		}
+	}
+	if err := events.Error(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("no commitment found for height %d (last commitment is for %d)", height, lastCommitHeight)
+}
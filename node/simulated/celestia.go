@@ -0,0 +1,280 @@
+package simulated
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+
+	"hummingbird/node"
+	"hummingbird/utils"
+
+	"github.com/celestiaorg/go-square/v3/share"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	tmproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	"github.com/cometbft/cometbft/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
+	challengeContract "hummingbird/node/contracts/Challenge.sol"
+)
+
+// reservedShares is the number of shares placed before the first blob in
+// every simulated block, standing in for the PFB tx shares a real Celestia
+// block starts with. It keeps blob share indexes from starting at 0.
+const reservedShares = 1
+
+// GasPrice is the gas price in utia blobs are published at.
+const GasPrice = 0.002
+
+const (
+	pfbGas         = 65_000 // fixed gas of a pay for blob tx
+	gasPerBlobByte = 8
+)
+
+// celestiaBlock is a simulated Celestia block.
+type celestiaBlock struct {
+	height   uint64
+	shares   []share.Share
+	dataRoot common.Hash
+}
+
+// Celestia is an in-memory Celestia network. Every pay for blobs tx is
+// included in its own block, with its blobs laid out one after another
+// exactly as utils.BlobToShares would lay each out.
+type Celestia struct {
+	mu        sync.RWMutex
+	namespace string
+	blocks    []*celestiaBlock // blocks[i] is at height i+1
+	txs       map[common.Hash]*node.CelestiaPointer
+}
+
+var _ node.Celestia = &Celestia{}
+
+// NewCelestia returns an empty simulated Celestia network.
+func NewCelestia(namespace string) *Celestia {
+	return &Celestia{
+		namespace: namespace,
+		txs:       make(map[common.Hash]*node.CelestiaPointer),
+	}
+}
+
+func (c *Celestia) Namespace() string {
+	return c.namespace
+}
+
+func (c *Celestia) PingNode(ctx context.Context) error {
+	return nil
+}
+
+func (c *Celestia) PingTendermint(ctx context.Context) error {
+	return nil
+}
+
+// Height returns the latest Celestia height.
+func (c *Celestia) Height() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return uint64(len(c.blocks))
+}
+
+// DataRoot returns the data root of the block at the given height.
+func (c *Celestia) DataRoot(height uint64) (common.Hash, error) {
+	b, err := c.block(height)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return b.dataRoot, nil
+}
+
+// Share returns the share at the given absolute index in the block at the
+// given height.
+func (c *Celestia) Share(height uint64, index uint64) (share.Share, error) {
+	b, err := c.block(height)
+	if err != nil {
+		return share.Share{}, err
+	}
+	if index >= uint64(len(b.shares)) {
+		return share.Share{}, fmt.Errorf("share %d out of range at height %d", index, height)
+	}
+	return b.shares[index], nil
+}
+
+func (c *Celestia) block(height uint64) (*celestiaBlock, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if height == 0 || height > uint64(len(c.blocks)) {
+		return nil, fmt.Errorf("no celestia block at height %d", height)
+	}
+	return c.blocks[height-1], nil
+}
+
+func (c *Celestia) PublishBundles(ctx context.Context, bundles []node.Bundle) ([]*node.CelestiaPointer, *node.CelestiaFee, error) {
+	if len(bundles) == 0 {
+		return nil, nil, fmt.Errorf("no bundles to publish")
+	}
+
+	// 1. lay each bundle out as shares, one blob after another
+	shares := share.ReservedPaddingShares(reservedShares)
+	pointers := make([]*node.CelestiaPointer, len(bundles))
+	for i, bundle := range bundles {
+		b, err := bundle.Blob(c.namespace)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get bundle blob: %w", err)
+		}
+		blobShares, err := utils.BlobToShares(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get bundle shares: %w", err)
+		}
+		pointers[i] = &node.CelestiaPointer{
+			ShareStart: uint64(len(shares)),
+			ShareLen:   uint64(len(blobShares)),
+			Commitment: common.BytesToHash(b.Commitment),
+		}
+		shares = append(shares, blobShares...)
+	}
+	dataRoot := common.BytesToHash(merkle.HashFromByteSlices(share.ToBytes(shares)))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 2. include them in a new block
+	height := uint64(len(c.blocks)) + 1
+	c.blocks = append(c.blocks, &celestiaBlock{
+		height:   height,
+		shares:   shares,
+		dataRoot: dataRoot,
+	})
+
+	// 3. record the pay for blobs tx
+	txHash := crypto.Keccak256Hash(dataRoot[:], new(big.Int).SetUint64(height).Bytes())
+	for _, pointer := range pointers {
+		pointer.Height = height
+		pointer.DataRoot = dataRoot
+		pointer.TxHash = txHash
+	}
+	c.txs[txHash] = pointers[0]
+
+	// 4. charge for the blobs as Celestia does, per byte of their shares
+	gasLimit := pfbGas + uint64(len(shares)-reservedShares)*share.ShareSize*gasPerBlobByte
+	fee := &node.CelestiaFee{
+		GasPrice: GasPrice,
+		GasLimit: gasLimit,
+		GasUsed:  gasLimit,
+		Amount:   uint64(math.Ceil(GasPrice * float64(gasLimit))),
+	}
+
+	res := make([]*node.CelestiaPointer, len(pointers))
+	for i, pointer := range pointers {
+		p := *pointer
+		res[i] = &p
+	}
+	return res, fee, nil
+}
+
+func (c *Celestia) GetPointer(ctx context.Context, txHash common.Hash) (*node.CelestiaPointer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	pointer, ok := c.txs[txHash]
+	if !ok {
+		return nil, fmt.Errorf("tx %s not found", txHash.Hex())
+	}
+	p := *pointer
+	return &p, nil
+}
+
+// GetProof returns the data root tuple for the pointers height, along with
+// its position in the data commitment spanning startBlock to endBlock.
+func (c *Celestia) GetProof(ctx context.Context, pointer *node.CelestiaPointer, startBlock uint64, endBlock uint64, proofNonce big.Int) (*node.CelestiaProof, error) {
+	b, err := c.block(pointer.Height)
+	if err != nil {
+		return nil, err
+	}
+	if pointer.Height < startBlock || pointer.Height >= endBlock {
+		return nil, fmt.Errorf("height %d is not in the commitment range [%d, %d)", pointer.Height, startBlock, endBlock)
+	}
+
+	return &node.CelestiaProof{
+		Nonce: &proofNonce,
+		Tuple: &blobstreamXContract.DataRootTuple{
+			Height:   new(big.Int).SetUint64(pointer.Height),
+			DataRoot: b.dataRoot,
+		},
+		WrappedProof: &challengeContract.BinaryMerkleProof{
+			SideNodes: [][32]byte{},
+			Key:       new(big.Int).SetUint64(pointer.Height - startBlock),
+			NumLeaves: new(big.Int).SetUint64(endBlock - startBlock),
+		},
+	}, nil
+}
+
+func (c *Celestia) GetSharesByNamespace(ctx context.Context, pointer *node.CelestiaPointer) ([]share.Share, error) {
+	b, err := c.block(pointer.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := share.NewV0Namespace([]byte(c.namespace))
+	if err != nil {
+		return nil, fmt.Errorf("GetShares: failed to get namespace: %w", err)
+	}
+
+	start, end := pointer.ShareStart, pointer.ShareStart+pointer.ShareLen
+	if end > uint64(len(b.shares)) {
+		return nil, fmt.Errorf("GetShares: %w: share range [%d, %d) out of bounds at height %d", node.ErrSharesMissing, start, end, pointer.Height)
+	}
+
+	shares := []share.Share{}
+	for _, s := range b.shares[start:end] {
+		if !s.Namespace().Equals(ns) {
+			return nil, fmt.Errorf("GetShares: %w: share in namespace %x, expected %x", node.ErrSharesMissing, s.Namespace().ID(), ns.ID())
+		}
+		shares = append(shares, s)
+	}
+	return shares, nil
+}
+
+func (c *Celestia) GetSharesByPointer(ctx context.Context, pointer *node.CelestiaPointer) ([]share.Share, error) {
+	proof, err := c.proveShares(pointer.Height, pointer.ShareStart, pointer.ShareStart+pointer.ShareLen)
+	if err != nil {
+		return nil, err
+	}
+	return share.FromBytes(proof.Data)
+}
+
+func (c *Celestia) GetShareProof(ctx context.Context, celestiaPointer *node.CelestiaPointer, shareIndex uint32) (*types.ShareProof, error) {
+	shareStart := celestiaPointer.ShareStart + uint64(shareIndex)
+	return c.proveShares(celestiaPointer.Height, shareStart, shareStart+1)
+}
+
+func (c *Celestia) GetSharesProof(ctx context.Context, celPointer *node.CelestiaPointer, sharePointer *node.SharePointer) (*types.ShareProof, error) {
+	shareStart := celPointer.ShareStart + uint64(sharePointer.StartShare)
+	shareEnd := celPointer.ShareStart + uint64(sharePointer.EndShare()+1)
+	return c.proveShares(celPointer.Height, shareStart, shareEnd)
+}
+
+// proveShares returns the shares in the range [start, end) at the given
+// height. The NMT and row proofs are left empty, the range itself is
+// recorded so the simulated contracts can check which shares were proven.
+func (c *Celestia) proveShares(height, start, end uint64) (*types.ShareProof, error) {
+	b, err := c.block(height)
+	if err != nil {
+		return nil, err
+	}
+	if start >= end || end > uint64(len(b.shares)) {
+		return nil, fmt.Errorf("share range [%d, %d) out of bounds at height %d", start, end, height)
+	}
+
+	ns := b.shares[start].Namespace()
+	return &types.ShareProof{
+		Data: share.ToBytes(b.shares[start:end]),
+		ShareProofs: []*tmproto.NMTProof{
+			{Start: int32(start), End: int32(end)},
+		},
+		NamespaceID:      ns.ID(),
+		NamespaceVersion: uint32(ns.Version()),
+	}, nil
+}
@@ -0,0 +1,175 @@
+package simulated
+
+import (
+	"bytes"
+	"context"
+
+	"hummingbird/node/ethereum"
+	"hummingbird/node/lightlink/types"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
+	chainOracleContract "hummingbird/node/contracts/ChainOracle.sol"
+)
+
+// providedHeader is an L2 header loaded into the ChainOracle, along with the
+// rollup block it was loaded from.
+type providedHeader struct {
+	rblock common.Hash
+	header *ethtypes.Header
+}
+
+var _ ethereum.ChainOracle = &Ethereum{}
+
+func shareKey(rblock common.Hash, shareData [][]byte) common.Hash {
+	return crypto.Keccak256Hash(append([][]byte{rblock[:]}, shareData...)...)
+}
+
+// ProvideShares stores shares once the proof shows they are within the
+// rollup blocks pointer and were committed to BlobstreamX.
+func (e *Ethereum) ProvideShares(ctx context.Context, rblock common.Hash, pointerIndex uint8, shareProof *chainOracleContract.SharesProof) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	index, ok := e.indexOf[rblock]
+	if !ok {
+		return nil, revert("rollup block not found")
+	}
+	header := e.headers[index]
+	if int(pointerIndex) >= len(header.CelestiaPointers) {
+		return nil, revert("invalid pointer index")
+	}
+	pointer := header.CelestiaPointers[pointerIndex]
+
+	att := shareProof.AttestationProof
+	if att.Tuple.Height.Uint64() != pointer.Height {
+		return nil, revert("proof is for the wrong celestia height")
+	}
+	ok, err := e.verifyAttestation(att.TupleRootNonce, blobstreamXContract.DataRootTuple(att.Tuple), blobstreamXContract.BinaryMerkleProof(att.Proof))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, revert("invalid attestation proof")
+	}
+
+	if len(shareProof.ShareProofs) != 1 || len(shareProof.Data) == 0 {
+		return nil, revert("invalid share proof")
+	}
+	start := shareProof.ShareProofs[0].BeginKey.Uint64()
+	end := start + uint64(len(shareProof.Data))
+	if start < pointer.ShareStart.Uint64() || end > pointer.ShareStart.Uint64()+uint64(pointer.ShareLen) {
+		return nil, revert("shares are outside the pointer range")
+	}
+	for i, data := range shareProof.Data {
+		s, err := e.celestia.Share(pointer.Height, start+uint64(i))
+		if err != nil {
+			return nil, revert("%s", err)
+		}
+		if !bytes.Equal(s.ToBytes(), data) {
+			return nil, revert("invalid share proof")
+		}
+	}
+
+	tx := e.mine(ChainOracleAddress, nil)
+	e.shares[shareKey(rblock, shareProof.Data)] = shareProof.Data
+
+	return tx, nil
+}
+
+// extract reads the bytes the ranges point to out of the provided shares.
+// Must be called with the lock held.
+func (e *Ethereum) extract(rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) ([]byte, error) {
+	shares, ok := e.shares[shareKey(rblock, shareData)]
+	if !ok {
+		return nil, revert("shares not found")
+	}
+	if len(ranges) > len(shares) {
+		return nil, revert("too many ranges")
+	}
+
+	data := []byte{}
+	for i, r := range ranges {
+		start, end := r.Start.Uint64(), r.End.Uint64()
+		if start > end || end > uint64(len(shares[i])) {
+			return nil, revert("invalid range")
+		}
+		data = append(data, shares[i][start:end]...)
+	}
+	return data, nil
+}
+
+func (e *Ethereum) ProvideHeader(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	data, err := e.extract(rblock, shareData, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &ethtypes.Header{}
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil, revert("failed to decode header: %s", err)
+	}
+
+	tx := e.mine(ChainOracleAddress, nil)
+	hash := utils.HashHeaderWithoutExtraData(types.CopyHeader(header))
+	if _, ok := e.l2Headers[hash]; !ok {
+		e.l2Headers[hash] = &providedHeader{rblock: rblock, header: header}
+	}
+
+	return tx, nil
+}
+
+func (e *Ethereum) ProvideLegacyTx(ctx context.Context, rblock common.Hash, shareData [][]byte, ranges []chainOracleContract.ChainOracleShareRange) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	data, err := e.extract(rblock, shareData, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	l2Tx := &types.Transaction{}
+	if err := rlp.DecodeBytes(data, l2Tx); err != nil {
+		return nil, revert("failed to decode tx: %s", err)
+	}
+	if l2Tx.Type() != types.LegacyTxType {
+		return nil, revert("not a legacy tx")
+	}
+
+	tx := e.mine(ChainOracleAddress, nil)
+	e.l2Txs[l2Tx.Hash()] = true
+
+	return tx, nil
+}
+
+func (e *Ethereum) AlreadyProvidedShares(ctx context.Context, rblock common.Hash, shareData [][]byte) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.shares[shareKey(rblock, shareData)]
+	return ok, nil
+}
+
+func (e *Ethereum) AlreadyProvidedHeader(ctx context.Context, l2Hash common.Hash) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.l2Headers[l2Hash]
+	return ok, nil
+}
+
+// ProvidedLegacyTx reports whether the L2 tx with the given hash has been
+// loaded into the ChainOracle.
+func (e *Ethereum) ProvidedLegacyTx(hash common.Hash) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.l2Txs[hash]
+}
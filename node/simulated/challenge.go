@@ -0,0 +1,449 @@
+package simulated
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+
+	"hummingbird/node/contracts"
+	"hummingbird/node/ethereum"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+
+	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
+	challengeContract "hummingbird/node/contracts/Challenge.sol"
+)
+
+type daChallenge struct {
+	blockHash    common.Hash
+	blockIndex   uint64
+	pointerIndex uint8
+	shareIndex   uint32
+	challenger   common.Address
+	expiry       int64
+	status       uint8
+	claimed      bool
+}
+
+type l2HeaderChallenge struct {
+	hash       common.Hash
+	blockIndex uint64
+	header     challengeContract.ChallengeL2HeaderL2HeaderPointer
+	prevHeader challengeContract.ChallengeL2HeaderL2HeaderPointer
+	challenger common.Address
+	expiry     int64
+	status     uint8
+	claimed    bool
+}
+
+var _ ethereum.Challenge = &Ethereum{}
+
+func (e *Ethereum) GetChallengeFee(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).Set(e.opts.ChallengeFee), nil
+}
+
+func (e *Ethereum) GetDefender(ctx context.Context) (common.Address, error) {
+	return e.opts.Defender, nil
+}
+
+func (e *Ethereum) GetMaxBundleSize(ctx context.Context) (uint64, error) {
+	return e.opts.MaxBundleSize, nil
+}
+
+func (e *Ethereum) GetChallengeWindow(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(int64(e.opts.ChallengeWindow.Seconds())), nil
+}
+
+// GetChallengeWindowBlockRanges returns a single range covering every L1
+// block, the simulated log backend has no range limit.
+func (e *Ethereum) GetChallengeWindowBlockRanges(ctx context.Context) ([][]uint64, error) {
+	height, err := e.GetHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return [][]uint64{{0, height}}, nil
+}
+
+// challengeable returns an error unless the rollup block at the given index
+// can still be challenged. Must be called with the lock held.
+func (e *Ethereum) challengeable(index uint64) error {
+	if index == 0 || index >= uint64(len(e.headers)) {
+		return revert("block not found")
+	}
+	if e.now.After(e.pushedAt[index].Add(e.opts.ChallengeWindow)) {
+		return revert("block is outside the challenge window")
+	}
+	return nil
+}
+
+func daChallengeKey(blockHash common.Hash, pointerIndex uint8, shareIndex uint32) common.Hash {
+	return crypto.Keccak256Hash(blockHash[:], []byte{pointerIndex}, binary.BigEndian.AppendUint32(nil, shareIndex))
+}
+
+func (e *Ethereum) DataRootInclusionChallengeKey(opts *bind.CallOpts, blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (common.Hash, error) {
+	return daChallengeKey(blockHash, pointerIndex, shareIndex), nil
+}
+
+func (e *Ethereum) GetDataRootInclusionChallenge(ctx context.Context, blockHash common.Hash, pointerIndex uint8, shareIndex uint32) (contracts.ChallengeDaInfo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.daChallenges[daChallengeKey(blockHash, pointerIndex, shareIndex)]
+	if !ok {
+		return contracts.ChallengeDaInfo{
+			BlockIndex: big.NewInt(0),
+			Challenger: common.Address{}.Hex(),
+			Expiry:     big.NewInt(0),
+			Status:     contracts.ChallengeDAStatusNone,
+		}, nil
+	}
+
+	return contracts.ChallengeDaInfo{
+		BlockIndex: u256(c.blockIndex),
+		Challenger: c.challenger.Hex(),
+		Expiry:     big.NewInt(c.expiry),
+		Status:     c.status,
+		Claimed:    c.claimed,
+	}, nil
+}
+
+// ChallengeDataRootInclusion challenges the availability of the share at
+// the given absolute share index, which must be within the pointers range.
+func (e *Ethereum) ChallengeDataRootInclusion(ctx context.Context, index uint64, pointerIndex uint8, shareIndex uint32) (*ethtypes.Transaction, common.Hash, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.challengeable(index); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	header := e.headers[index]
+	if int(pointerIndex) >= len(header.CelestiaPointers) {
+		return nil, common.Hash{}, revert("invalid pointer index")
+	}
+	pointer := header.CelestiaPointers[pointerIndex]
+	start := pointer.ShareStart.Uint64()
+	if uint64(shareIndex) < start || uint64(shareIndex) >= start+uint64(pointer.ShareLen) {
+		return nil, common.Hash{}, revert("share index out of range")
+	}
+
+	blockHash := e.hashes[index]
+	key := daChallengeKey(blockHash, pointerIndex, shareIndex)
+	if c, ok := e.daChallenges[key]; ok && c.status != contracts.ChallengeDAStatusNone {
+		return nil, common.Hash{}, revert("challenge already exists")
+	}
+
+	tx := e.mine(ChallengeAddress, e.opts.ChallengeFee)
+	c := &daChallenge{
+		blockHash:    blockHash,
+		blockIndex:   index,
+		pointerIndex: pointerIndex,
+		shareIndex:   shareIndex,
+		challenger:   e.opts.Publisher,
+		expiry:       e.now.Add(e.opts.ChallengePeriod).Unix(),
+		status:       contracts.ChallengeDAStatusChallengerInitiated,
+	}
+	e.daChallenges[key] = c
+	e.emitDAUpdate(tx, c)
+
+	return tx, blockHash, nil
+}
+
+// DefendDataRootInclusion checks the proof attests to the challenged share
+// being included in the data root committed to BlobstreamX.
+func (e *Ethereum) DefendDataRootInclusion(ctx context.Context, key common.Hash, proof challengeContract.SharesProof) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.daChallenges[key]
+	if !ok || c.status != contracts.ChallengeDAStatusChallengerInitiated {
+		return nil, revert("challenge is not in the challenger initiated state")
+	}
+	if e.now.Unix() > c.expiry {
+		return nil, revert("challenge has expired")
+	}
+
+	pointer := e.headers[c.blockIndex].CelestiaPointers[c.pointerIndex]
+	if proof.AttestationProof.Tuple.Height.Uint64() != pointer.Height {
+		return nil, revert("proof is for the wrong celestia height")
+	}
+	ok, err := e.verifyAttestation(proof.AttestationProof.TupleRootNonce, blobstreamXContract.DataRootTuple(proof.AttestationProof.Tuple), blobstreamXContract.BinaryMerkleProof(proof.AttestationProof.Proof))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, revert("invalid attestation proof")
+	}
+
+	if len(proof.Data) != 1 || len(proof.ShareProofs) != 1 || proof.ShareProofs[0].BeginKey.Uint64() != uint64(c.shareIndex) {
+		return nil, revert("proof is not for the challenged share")
+	}
+	s, err := e.celestia.Share(pointer.Height, uint64(c.shareIndex))
+	if err != nil {
+		return nil, revert("%s", err)
+	}
+	if string(s.ToBytes()) != string(proof.Data[0]) {
+		return nil, revert("invalid share proof")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.status = contracts.ChallengeDAStatusDefenderWon
+	e.emitDAUpdate(tx, c)
+
+	return tx, nil
+}
+
+// SettleDataRootInclusion settles an expired, undefended DA challenge in the
+// challengers favour, rolling back the challenged block.
+func (e *Ethereum) SettleDataRootInclusion(ctx context.Context, key common.Hash) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.daChallenges[key]
+	if !ok || c.status != contracts.ChallengeDAStatusChallengerInitiated {
+		return nil, revert("challenge is not in the challenger initiated state")
+	}
+	if e.now.Unix() <= c.expiry {
+		return nil, revert("challenge has not expired")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.status = contracts.ChallengeDAStatusChallengerWon
+	e.emitDAUpdate(tx, c)
+	if c.blockIndex < uint64(len(e.headers)) && e.hashes[c.blockIndex] == c.blockHash {
+		e.rollback(c.blockIndex, tx)
+	}
+
+	return tx, nil
+}
+
+func (e *Ethereum) ClaimDAChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.daChallenges[key]
+	if !ok || (c.status != contracts.ChallengeDAStatusChallengerWon && c.status != contracts.ChallengeDAStatusDefenderWon) {
+		return nil, revert("challenge is not settled")
+	}
+	if c.claimed {
+		return nil, revert("reward already claimed")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.claimed = true
+
+	hash := tx.Hash()
+	return &hash, nil
+}
+
+func (e *Ethereum) emitDAUpdate(tx *ethtypes.Transaction, c *daChallenge) {
+	e.emit(challengeABI, ChallengeAddress, tx, "ChallengeDAUpdate",
+		[32]byte(c.blockHash), u256(uint64(c.pointerIndex)), c.shareIndex, u256(c.blockIndex), big.NewInt(c.expiry), c.status)
+}
+
+func (e *Ethereum) FilterChallengeDAUpdate(opts *bind.FilterOpts, _blockHash [][32]byte, _blockIndex []*big.Int, _status []uint8) (*challengeContract.ChallengeChallengeDAUpdateIterator, error) {
+	return e.challenge.FilterChallengeDAUpdate(opts, _blockHash, _blockIndex, _status)
+}
+
+func l2HeaderChallengeHash(rblockHash common.Hash, l2Num *big.Int) common.Hash {
+	return crypto.Keccak256Hash(rblockHash[:], common.BigToHash(l2Num).Bytes())
+}
+
+func (e *Ethereum) GetL2HeaderChallengeHash(ctx context.Context, rblockHash common.Hash, l2Num *big.Int) (common.Hash, error) {
+	return l2HeaderChallengeHash(rblockHash, l2Num), nil
+}
+
+// ChallengeL2Header challenges the publisher to prove the L2 header at
+// l2Num, and its parent, were included in the rollup block at rblockNum.
+func (e *Ethereum) ChallengeL2Header(ctx context.Context, rblockNum *big.Int, l2Num *big.Int) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	index := rblockNum.Uint64()
+	if err := e.challengeable(index); err != nil {
+		return nil, err
+	}
+
+	num := l2Num.Uint64()
+	prev := e.headers[index-1]
+	if num <= prev.L2Height || num > e.headers[index].L2Height {
+		return nil, revert("l2 block is not in the rollup block")
+	}
+
+	// the previous header is either in the same rollup block, or is the
+	// last header of the previous rollup block.
+	prevIndex := index
+	if num-1 == prev.L2Height {
+		prevIndex = index - 1
+	}
+	if prevIndex == 0 {
+		return nil, revert("cannot challenge the first l2 block")
+	}
+
+	hash := l2HeaderChallengeHash(e.hashes[index], l2Num)
+	if c, ok := e.l2Challenges[hash]; ok && c.status != contracts.ChallengeL2HeaderStatusNone {
+		return nil, revert("challenge already exists")
+	}
+
+	tx := e.mine(ChallengeAddress, e.opts.ChallengeFee)
+	c := &l2HeaderChallenge{
+		hash:       hash,
+		blockIndex: index,
+		header:     challengeContract.ChallengeL2HeaderL2HeaderPointer{Rblock: e.hashes[index], Number: new(big.Int).Set(l2Num)},
+		prevHeader: challengeContract.ChallengeL2HeaderL2HeaderPointer{Rblock: e.hashes[prevIndex], Number: u256(num - 1)},
+		challenger: e.opts.Publisher,
+		expiry:     e.now.Add(e.opts.ChallengePeriod).Unix(),
+		status:     contracts.ChallengeL2HeaderStatusChallengerInitiated,
+	}
+	e.l2Challenges[hash] = c
+	e.emitL2HeaderUpdate(tx, c)
+
+	return tx, nil
+}
+
+func (e *Ethereum) GetL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (contracts.L2HeaderChallengeInfo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.l2Challenges[challengeHash]
+	if !ok {
+		return contracts.L2HeaderChallengeInfo{
+			Header:       challengeContract.ChallengeL2HeaderL2HeaderPointer{Number: big.NewInt(0)},
+			PrevHeader:   challengeContract.ChallengeL2HeaderL2HeaderPointer{Number: big.NewInt(0)},
+			ChallengeEnd: big.NewInt(0),
+		}, nil
+	}
+
+	return contracts.L2HeaderChallengeInfo{
+		Header:       c.header,
+		PrevHeader:   c.prevHeader,
+		ChallengeEnd: big.NewInt(c.expiry),
+		Challenger:   c.challenger,
+		Status:       c.status,
+		Claimed:      c.claimed,
+	}, nil
+}
+
+// DefendL2Header checks both headers were provided to the ChainOracle from
+// the challenged rollup blocks, and that they link together.
+func (e *Ethereum) DefendL2Header(ctx context.Context, challengeHash, headerHash, prevHeaderHash common.Hash) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.l2Challenges[challengeHash]
+	if !ok || c.status != contracts.ChallengeL2HeaderStatusChallengerInitiated {
+		return nil, revert("challenge is not in the challenger initiated state")
+	}
+	if e.now.Unix() > c.expiry {
+		return nil, revert("challenge has expired")
+	}
+
+	header, ok := e.l2Headers[headerHash]
+	if !ok {
+		return nil, revert("header not provided")
+	}
+	prevHeader, ok := e.l2Headers[prevHeaderHash]
+	if !ok {
+		return nil, revert("previous header not provided")
+	}
+
+	if header.rblock != c.header.Rblock || header.header.Number.Cmp(c.header.Number) != 0 {
+		return nil, revert("header does not match the challenge")
+	}
+	if prevHeader.rblock != c.prevHeader.Rblock || prevHeader.header.Number.Cmp(c.prevHeader.Number) != 0 {
+		return nil, revert("previous header does not match the challenge")
+	}
+	if header.header.ParentHash != prevHeader.header.Hash() {
+		return nil, revert("header is not a child of the previous header")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.status = contracts.ChallengeL2HeaderStatusDefenderWon
+	e.emitL2HeaderUpdate(tx, c)
+
+	return tx, nil
+}
+
+// SettleL2HeaderChallenge settles an expired, undefended L2 header challenge
+// in the challengers favour, rolling back the challenged block.
+func (e *Ethereum) SettleL2HeaderChallenge(ctx context.Context, challengeHash common.Hash) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.l2Challenges[challengeHash]
+	if !ok || c.status != contracts.ChallengeL2HeaderStatusChallengerInitiated {
+		return nil, revert("challenge is not in the challenger initiated state")
+	}
+	if e.now.Unix() <= c.expiry {
+		return nil, revert("challenge has not expired")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.status = contracts.ChallengeL2HeaderStatusChallengerWon
+	e.emitL2HeaderUpdate(tx, c)
+	if c.blockIndex < uint64(len(e.headers)) && e.hashes[c.blockIndex] == c.header.Rblock {
+		e.rollback(c.blockIndex, tx)
+	}
+
+	return tx, nil
+}
+
+// InvalidateHeader rolls back the rollup block at index if its header does
+// not extend the block before it. PushRollupHead rejects such headers, so
+// this only succeeds for headers injected with ForceRollupHead.
+func (e *Ethereum) InvalidateHeader(ctx context.Context, index uint64) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.challengeable(index); err != nil {
+		return nil, err
+	}
+	if err := e.checkExtends(&e.headers[index], index-1); err == nil {
+		return nil, revert("header is valid")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	e.rollback(index, tx)
+
+	return tx, nil
+}
+
+func (e *Ethereum) ClaimL2HeaderChallengeReward(ctx context.Context, key common.Hash) (*common.Hash, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.l2Challenges[key]
+	if !ok || (c.status != contracts.ChallengeL2HeaderStatusChallengerWon && c.status != contracts.ChallengeL2HeaderStatusDefenderWon) {
+		return nil, revert("challenge is not settled")
+	}
+	if c.claimed {
+		return nil, revert("reward already claimed")
+	}
+
+	tx := e.mine(ChallengeAddress, nil)
+	c.claimed = true
+
+	hash := tx.Hash()
+	return &hash, nil
+}
+
+func (e *Ethereum) emitL2HeaderUpdate(tx *ethtypes.Transaction, c *l2HeaderChallenge) {
+	e.emit(challengeABI, ChallengeAddress, tx, "L2HeaderChallengeUpdate",
+		[32]byte(c.hash), c.header.Number, c.header.Rblock, big.NewInt(c.expiry), c.status)
+}
+
+func (e *Ethereum) FilterL2HeaderChallengeUpdate(opts *bind.FilterOpts, _blockHash [][32]byte, _blockIndex []*big.Int, _status []uint8) (*challengeContract.ChallengeL2HeaderChallengeUpdateIterator, error) {
+	return e.challenge.FilterL2HeaderChallengeUpdate(opts, _blockHash, _blockIndex, _status)
+}
+
+func (e *Ethereum) WatchChallengeDAUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeChallengeDAUpdate, _blockHash [][32]byte, _pointerIndex []*big.Int, _status []uint8) (event.Subscription, error) {
+	return e.challenge.WatchChallengeDAUpdate(opts, sink, _blockHash, _pointerIndex, _status)
+}
+
+func (e *Ethereum) WatchL2HeaderChallengeUpdate(opts *bind.WatchOpts, sink chan<- *challengeContract.ChallengeL2HeaderChallengeUpdate, challengeHash [][32]byte, l2Number []*big.Int, status []uint8) (event.Subscription, error) {
+	return e.challenge.WatchL2HeaderChallengeUpdate(opts, sink, challengeHash, l2Number, status)
+}
@@ -0,0 +1,422 @@
+package simulated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"hummingbird/node/contracts"
+	"hummingbird/node/ethereum"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	blobstreamXContract "hummingbird/node/contracts/BlobstreamX.sol"
+	canonicalStateChainContract "hummingbird/node/contracts/CanonicalStateChain.sol"
+	challengeContract "hummingbird/node/contracts/Challenge.sol"
+	lightLinkPortalContract "hummingbird/node/contracts/LightLinkPortal.sol"
+)
+
+// GenesisTime is the unix time the simulated networks start at.
+const GenesisTime = 1_700_000_000
+
+// EthereumChainID is the chain id of the simulated Ethereum network.
+var EthereumChainID = big.NewInt(1337)
+
+// Addresses the simulated contracts emit their events from.
+var (
+	CanonicalStateChainAddress = common.HexToAddress("0x000000000000000000000000000000000000c5c0")
+	ChallengeAddress           = common.HexToAddress("0x000000000000000000000000000000000000c4a1")
+	ChainOracleAddress         = common.HexToAddress("0x000000000000000000000000000000000000041c")
+	BlobstreamXAddress         = common.HexToAddress("0x000000000000000000000000000000000000b10b")
+	LightLinkPortalAddress     = common.HexToAddress("0x000000000000000000000000000000000000b41d")
+)
+
+var (
+	canonicalStateChainABI = mustABI(canonicalStateChainContract.CanonicalStateChainMetaData)
+	challengeABI           = mustABI(challengeContract.ChallengeMetaData)
+	blobstreamXABI         = mustABI(blobstreamXContract.BlobstreamXMetaData)
+	lightLinkPortalABI     = mustABI(lightLinkPortalContract.LightLinkPortalMetaData)
+)
+
+// EthereumOpts configures the simulated Ethereum network.
+type EthereumOpts struct {
+	Publisher       common.Address
+	Defender        common.Address // defaults to the publisher
+	ChallengeFee    *big.Int
+	ChallengeWindow time.Duration // how long after being pushed a rollup block can be challenged
+	ChallengePeriod time.Duration // how long a defender has to respond to a challenge
+	MaxPointers     uint8         // max Celestia pointers in a rollup block header, defaults to 8
+	MaxBundleSize   uint64        // max L2 blocks in a rollup block, defaults to 1000
+}
+
+// Ethereum is an in-memory Ethereum network running the rollup contracts.
+//
+// Every transaction is sent by the publisher, mined immediately in its own
+// L1 block and always succeeds, calls that would revert on chain return an
+// error instead. Time only moves forward when AdvanceTime is called.
+type Ethereum struct {
+	mu sync.Mutex
+
+	opts     EthereumOpts
+	celestia *Celestia
+	logs     *logBackend
+
+	now      time.Time
+	height   uint64
+	times    []time.Time // times[i] is the time of L1 block i
+	nonce    uint64
+	receipts map[common.Hash]*ethtypes.Receipt
+
+	// CanonicalStateChain
+	headers  []canonicalStateChainContract.CanonicalStateChainHeader
+	hashes   []common.Hash
+	pushedAt []time.Time
+	indexOf  map[common.Hash]uint64
+
+	// Challenge
+	daChallenges map[common.Hash]*daChallenge
+	l2Challenges map[common.Hash]*l2HeaderChallenge
+
+	// ChainOracle
+	shares    map[common.Hash][][]byte
+	l2Headers map[common.Hash]*providedHeader
+	l2Txs     map[common.Hash]bool
+
+	// BlobstreamX
+	commitments     []*commitment
+	committedHeight uint64
+
+	// LightLinkPortal
+	provenWithdrawals    map[common.Hash]contracts.ProvenWithdrawal
+	finalizedWithdrawals map[common.Hash]bool
+
+	canonicalStateChain *canonicalStateChainContract.CanonicalStateChainFilterer
+	challenge           *challengeContract.ChallengeFilterer
+	blobstreamX         *blobstreamXContract.BlobstreamXFilterer
+	lightLinkPortal     *lightLinkPortalContract.LightLinkPortalFilterer
+}
+
+var _ ethereum.Ethereum = &Ethereum{}
+
+// NewEthereum returns a simulated Ethereum network with a genesis rollup
+// block. DA proofs are verified against the given Celestia network.
+func NewEthereum(celestia *Celestia, opts EthereumOpts) *Ethereum {
+	if opts.ChallengeFee == nil {
+		opts.ChallengeFee = big.NewInt(1e15)
+	}
+	if opts.Defender == (common.Address{}) {
+		opts.Defender = opts.Publisher
+	}
+	if opts.ChallengeWindow == 0 {
+		opts.ChallengeWindow = 3 * 24 * time.Hour
+	}
+	if opts.ChallengePeriod == 0 {
+		opts.ChallengePeriod = 2 * 24 * time.Hour
+	}
+	if opts.MaxPointers == 0 {
+		opts.MaxPointers = 8
+	}
+	if opts.MaxBundleSize == 0 {
+		opts.MaxBundleSize = 1000
+	}
+
+	e := &Ethereum{
+		opts:         opts,
+		celestia:     celestia,
+		logs:         &logBackend{},
+		now:          time.Unix(GenesisTime, 0),
+		times:        []time.Time{time.Unix(GenesisTime, 0)},
+		receipts:     make(map[common.Hash]*ethtypes.Receipt),
+		indexOf:      make(map[common.Hash]uint64),
+		daChallenges: make(map[common.Hash]*daChallenge),
+		l2Challenges: make(map[common.Hash]*l2HeaderChallenge),
+		shares:       make(map[common.Hash][][]byte),
+		l2Headers:    make(map[common.Hash]*providedHeader),
+		l2Txs:        make(map[common.Hash]bool),
+
+		provenWithdrawals:    make(map[common.Hash]contracts.ProvenWithdrawal),
+		finalizedWithdrawals: make(map[common.Hash]bool),
+	}
+
+	var err error
+	if e.canonicalStateChain, err = canonicalStateChainContract.NewCanonicalStateChainFilterer(CanonicalStateChainAddress, e.logs); err != nil {
+		panic(err)
+	}
+	if e.challenge, err = challengeContract.NewChallengeFilterer(ChallengeAddress, e.logs); err != nil {
+		panic(err)
+	}
+	if e.blobstreamX, err = blobstreamXContract.NewBlobstreamXFilterer(BlobstreamXAddress, e.logs); err != nil {
+		panic(err)
+	}
+	if e.lightLinkPortal, err = lightLinkPortalContract.NewLightLinkPortalFilterer(LightLinkPortalAddress, e.logs); err != nil {
+		panic(err)
+	}
+
+	genesis := canonicalStateChainContract.CanonicalStateChainHeader{
+		CelestiaPointers: []canonicalStateChainContract.CanonicalStateChainCelestiaPointer{},
+	}
+	hash, err := e.HashHeader(context.Background(), &genesis)
+	if err != nil {
+		panic(err)
+	}
+	e.appendHeader(genesis, hash)
+
+	return e
+}
+
+// Filterer returns the log backend the simulated contracts emit to. It can
+// be passed to the generated New*Filterer bindings to watch for events.
+func (e *Ethereum) Filterer() bind.ContractFilterer {
+	return e.logs
+}
+
+// Now returns the current L1 time.
+func (e *Ethereum) Now() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.now
+}
+
+// AdvanceTime moves the L1 clock forward, e.g. to expire challenges, and
+// mines an empty L1 block at the new time.
+func (e *Ethereum) AdvanceTime(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = e.now.Add(d)
+	e.height++
+	e.times = append(e.times, e.now)
+}
+
+// revert returns the error a reverted call would return.
+func revert(format string, args ...any) error {
+	return errors.New("execution reverted: " + fmt.Sprintf(format, args...))
+}
+
+// mine creates a tx and mines it in a new L1 block. Must be called with the
+// lock held.
+func (e *Ethereum) mine(to common.Address, value *big.Int) *ethtypes.Transaction {
+	tx := ethtypes.NewTx(&ethtypes.LegacyTx{
+		Nonce:    e.nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      1_000_000,
+		GasPrice: big.NewInt(1_000_000_000),
+	})
+	e.nonce++
+	e.height++
+	e.times = append(e.times, e.now)
+
+	e.receipts[tx.Hash()] = &ethtypes.Receipt{
+		Type:        ethtypes.LegacyTxType,
+		Status:      ethtypes.ReceiptStatusSuccessful,
+		TxHash:      tx.Hash(),
+		BlockHash:   blockHash(e.height),
+		BlockNumber: new(big.Int).SetUint64(e.height),
+	}
+	return tx
+}
+
+// blockHash returns the hash of the simulated L1 block at the given height.
+func blockHash(height uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte("block"), u256(height).Bytes())
+}
+
+// emit emits an event in the current L1 block. Must be called with the lock
+// held.
+func (e *Ethereum) emit(contract *abi.ABI, address common.Address, tx *ethtypes.Transaction, name string, args ...any) {
+	if err := e.logs.emit(contract, address, e.height, tx.Hash(), name, args...); err != nil {
+		panic(err)
+	}
+}
+
+func (e *Ethereum) appendHeader(header canonicalStateChainContract.CanonicalStateChainHeader, hash common.Hash) {
+	e.indexOf[hash] = uint64(len(e.headers))
+	e.headers = append(e.headers, header)
+	e.hashes = append(e.hashes, hash)
+	e.pushedAt = append(e.pushedAt, e.now)
+}
+
+// rollback removes the rollup block at the given index and all blocks
+// after it. Must be called with the lock held.
+func (e *Ethereum) rollback(index uint64, tx *ethtypes.Transaction) {
+	for _, hash := range e.hashes[index:] {
+		delete(e.indexOf, hash)
+	}
+	e.headers = e.headers[:index]
+	e.hashes = e.hashes[:index]
+	e.pushedAt = e.pushedAt[:index]
+
+	e.emit(canonicalStateChainABI, CanonicalStateChainAddress, tx, "RolledBack", u256(index))
+}
+
+func (e *Ethereum) GetRollupHeight(ctx context.Context) (uint64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return uint64(len(e.headers) - 1), nil
+}
+
+func (e *Ethereum) GetHeight(ctx context.Context) (uint64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.height, nil
+}
+
+func (e *Ethereum) GetBlockTime(ctx context.Context, height uint64) (time.Time, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if height >= uint64(len(e.times)) {
+		return time.Time{}, fmt.Errorf("block %d not found", height)
+	}
+	return e.times[height], nil
+}
+
+func (e *Ethereum) GetRollupHead(ctx context.Context) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.headers[len(e.headers)-1], nil
+}
+
+// PushRollupHead pushes a new rollup block header, checking it extends the
+// current head as CanonicalStateChain.sol does.
+func (e *Ethereum) PushRollupHead(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (*ethtypes.Transaction, error) {
+	hash, err := e.HashHeader(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkExtends(header, uint64(len(e.headers)-1)); err != nil {
+		return nil, err
+	}
+
+	return e.pushHeader(header, hash), nil
+}
+
+// ForceRollupHead pushes a new rollup block header without checking it
+// extends the current head, to simulate an invalid header being published.
+func (e *Ethereum) ForceRollupHead(header *canonicalStateChainContract.CanonicalStateChainHeader) (*ethtypes.Transaction, error) {
+	hash, err := e.HashHeader(context.Background(), header)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.pushHeader(header, hash), nil
+}
+
+// checkExtends returns an error unless the header extends the rollup block
+// at prevIndex. Must be called with the lock held.
+func (e *Ethereum) checkExtends(header *canonicalStateChainContract.CanonicalStateChainHeader, prevIndex uint64) error {
+	if header.PrevHash != e.hashes[prevIndex] {
+		return revert("prevHash mismatch")
+	}
+	if header.L2Height <= e.headers[prevIndex].L2Height {
+		return revert("l2Height must be greater than the previous block")
+	}
+	if len(header.CelestiaPointers) == 0 {
+		return revert("block must have at least one celestia pointer")
+	}
+	if len(header.CelestiaPointers) > int(e.opts.MaxPointers) {
+		return revert("block has more than %d celestia pointers", e.opts.MaxPointers)
+	}
+	if header.L2Height-e.headers[prevIndex].L2Height > e.opts.MaxBundleSize {
+		return revert("block has more than %d l2 blocks", e.opts.MaxBundleSize)
+	}
+	return nil
+}
+
+// pushHeader appends the header in a new L1 block. Must be called with the
+// lock held.
+func (e *Ethereum) pushHeader(header *canonicalStateChainContract.CanonicalStateChainHeader, hash common.Hash) *ethtypes.Transaction {
+	tx := e.mine(CanonicalStateChainAddress, nil)
+	e.appendHeader(*header, hash)
+	e.emit(canonicalStateChainABI, CanonicalStateChainAddress, tx, "BlockAdded", u256(uint64(len(e.headers)-1)))
+	return tx
+}
+
+func (e *Ethereum) GetRollupHeader(ctx context.Context, index uint64) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if index >= uint64(len(e.headers)) {
+		return canonicalStateChainContract.CanonicalStateChainHeader{}, revert("block %d not found", index)
+	}
+	return e.headers[index], nil
+}
+
+func (e *Ethereum) GetRollupHeaderByHash(ctx context.Context, hash common.Hash) (canonicalStateChainContract.CanonicalStateChainHeader, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	index, ok := e.indexOf[hash]
+	if !ok {
+		return canonicalStateChainContract.CanonicalStateChainHeader{}, revert("block %s not found", hash.Hex())
+	}
+	return e.headers[index], nil
+}
+
+func (e *Ethereum) Wait(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	receipt, ok := e.receipts[txHash]
+	if !ok {
+		return nil, fmt.Errorf("failed to get transaction receipt: not found")
+	}
+	return receipt, nil
+}
+
+// WaitTx returns the receipt of whichever tx with the given hashes was
+// mined. Simulated txs are never replaced, so replaced is not called.
+func (e *Ethereum) WaitTx(ctx context.Context, nonce uint64, hashes []common.Hash, replaced func(common.Hash)) (*ethtypes.Receipt, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, hash := range hashes {
+		if receipt, ok := e.receipts[hash]; ok {
+			return receipt, nil
+		}
+	}
+	if e.nonce > nonce {
+		return nil, ethereum.ErrNonceUsed
+	}
+	return nil, fmt.Errorf("failed to get transaction receipt: not found")
+}
+
+func (e *Ethereum) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).Set(EthereumChainID), nil
+}
+
+func (e *Ethereum) GetPublisher(ctx context.Context) (common.Address, error) {
+	return e.opts.Publisher, nil
+}
+
+func (e *Ethereum) GetMaxPointers(ctx context.Context) (uint8, error) {
+	return e.opts.MaxPointers, nil
+}
+
+// HashHeader hashes the header as CanonicalStateChain.calculateHeaderHash
+// does, keccak256(abi.encode(header)).
+func (e *Ethereum) HashHeader(ctx context.Context, header *canonicalStateChainContract.CanonicalStateChainHeader) (common.Hash, error) {
+	enc, err := canonicalStateChainABI.Methods["calculateHeaderHash"].Inputs.Pack(*header)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode header: %w", err)
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+func (e *Ethereum) FilterBlockAdded(opts *bind.FilterOpts, blockNumber []*big.Int) (*canonicalStateChainContract.CanonicalStateChainBlockAddedIterator, error) {
+	return e.canonicalStateChain.FilterBlockAdded(opts, blockNumber)
+}
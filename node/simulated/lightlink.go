@@ -0,0 +1,406 @@
+package simulated
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+
+	"hummingbird/node"
+	"hummingbird/node/lightlink/types"
+	"hummingbird/utils"
+
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+
+	l2ToL1MessagePasserContract "hummingbird/node/contracts/L2toL1MessagePasser.sol"
+	lightLinkPortalContract "hummingbird/node/contracts/LightLinkPortal.sol"
+)
+
+var l2ToL1MessagePasserABI = mustABI(l2ToL1MessagePasserContract.L2ToL1MessagePasserMetaData)
+
+// LightLinkChainID is the chain id used to sign simulated L2 transactions.
+var LightLinkChainID = big.NewInt(1891)
+
+// LightLink is an in-memory LightLink network. Blocks are linked by parent
+// hash and carry signed legacy transactions.
+//
+// The storage of L2ToL1MessagePasser.sol is kept in a real storage trie, so
+// withdrawal proofs verify against the output roots of rollup blocks.
+type LightLink struct {
+	mu          sync.RWMutex
+	blocks      []*types.Block // blocks[i] is at height i
+	receipts    map[common.Hash]*ethtypes.Receipt
+	logs        []ethtypes.Log
+	key         *ecdsa.PrivateKey
+	nonce       uint64
+	passer      common.Address
+	withdrawals []sentWithdrawal // every withdrawal sent, in order
+}
+
+// sentWithdrawal is a withdrawal stored in L2ToL1MessagePasser.sol.
+type sentWithdrawal struct {
+	height uint64
+	hash   common.Hash
+}
+
+var _ node.LightLink = &LightLink{}
+
+// NewLightLink returns a simulated LightLink network holding only a genesis
+// block.
+func NewLightLink() *LightLink {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+
+	genesis := types.NewBlockWithHeader(&ethtypes.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(0),
+		GasLimit:   30_000_000,
+		Time:       GenesisTime,
+		Extra:      []byte("genesis"),
+	})
+
+	return &LightLink{
+		blocks:   []*types.Block{genesis},
+		receipts: make(map[common.Hash]*ethtypes.Receipt),
+		key:      key,
+		passer:   common.HexToAddress("0x4200000000000000000000000000000000000016"),
+	}
+}
+
+// Address returns the address the simulated L2 transactions are sent from.
+func (l *LightLink) Address() common.Address {
+	return crypto.PubkeyToAddress(l.key.PublicKey)
+}
+
+// Mine adds n blocks to the chain, each with a single transfer tx, and
+// returns the new height.
+func (l *LightLink) Mine(n int) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		l.mineTx(l.signTx(common.Address{0x01}, big.NewInt(1), nil), nil)
+	}
+
+	return uint64(len(l.blocks) - 1)
+}
+
+// Reorg replaces the last depth blocks with new blocks holding different
+// txs, as a reorg of the chain would, and returns the new height.
+func (l *LightLink) Reorg(depth int) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.blocks = l.blocks[:len(l.blocks)-depth]
+	base := uint64(len(l.blocks) - 1)
+
+	// drop the receipts, logs and withdrawals of the reorged blocks
+	for hash, receipt := range l.receipts {
+		if receipt.BlockNumber.Uint64() > base {
+			delete(l.receipts, hash)
+		}
+	}
+	l.logs = slices.DeleteFunc(l.logs, func(log ethtypes.Log) bool { return log.BlockNumber > base })
+	l.withdrawals = slices.DeleteFunc(l.withdrawals, func(w sentWithdrawal) bool { return w.height > base })
+
+	for i := 0; i < depth; i++ {
+		l.mineTx(l.signTx(common.Address{0x02}, big.NewInt(2), nil), nil)
+	}
+
+	return uint64(len(l.blocks) - 1)
+}
+
+// Withdraw initiates a withdrawal to L1 as L2ToL1MessagePasser.initiateWithdrawal
+// would, mining it in a new block. It returns the L2 tx and the withdrawal.
+func (l *LightLink) Withdraw(target common.Address, value *big.Int, gasLimit uint64, data []byte) (common.Hash, lightLinkPortalContract.TypesWithdrawalTransaction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// nonces are versioned, with the version in the top two bytes
+	nonce := new(big.Int).Lsh(common.Big1, 240)
+	nonce.Or(nonce, new(big.Int).SetUint64(uint64(len(l.withdrawals))))
+
+	withdrawal := lightLinkPortalContract.TypesWithdrawalTransaction{
+		Nonce:    nonce,
+		Sender:   l.Address(),
+		Target:   target,
+		Value:    value,
+		GasLimit: new(big.Int).SetUint64(gasLimit),
+		Data:     data,
+	}
+	hash, err := node.HashWithdrawal(withdrawal)
+	if err != nil {
+		panic(err)
+	}
+
+	log, err := encodeLog(l2ToL1MessagePasserABI, l.passer, "MessagePassed", withdrawal.Nonce, withdrawal.Sender, withdrawal.Target, withdrawal.Value, withdrawal.GasLimit, withdrawal.Data, hash)
+	if err != nil {
+		panic(err)
+	}
+
+	txHash := l.mineTx(l.signTx(l.passer, value, data), []*ethtypes.Log{log})
+	l.withdrawals = append(l.withdrawals, sentWithdrawal{height: uint64(len(l.blocks) - 1), hash: hash})
+	return txHash, withdrawal
+}
+
+// IncludeDeposit mines a block holding the deposit tx of the deposit, as
+// the sequencer would once it sees the deposit on L1.
+func (l *LightLink) IncludeDeposit(d *node.Deposit) common.Hash {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var to *common.Address
+	if !d.IsCreation {
+		to = &d.To
+	}
+	tx := types.NewTx(&types.DepositTxV2{
+		SourceHash: d.SourceHash,
+		From:       d.From,
+		To:         to,
+		Mint:       d.Mint,
+		Value:      d.Value,
+		Gas:        d.Gas,
+		Data:       d.Data,
+	})
+	return l.mineTx(tx, nil)
+}
+
+// signTx returns a legacy tx signed by the simulated L2 key. Must be called
+// with the lock held.
+func (l *LightLink) signTx(to common.Address, value *big.Int, data []byte) *types.Transaction {
+	tx := types.MustSignNewTx(l.key, types.NewEIP155Signer(LightLinkChainID), &types.LegacyTx{
+		Nonce:    l.nonce,
+		GasPrice: big.NewInt(1_000_000_000),
+		Gas:      100_000,
+		To:       &to,
+		Value:    value,
+		Data:     data,
+	})
+	l.nonce++
+	return tx
+}
+
+// mineTx mines a block holding a single tx that emitted the given logs. Must
+// be called with the lock held.
+func (l *LightLink) mineTx(tx *types.Transaction, logs []*ethtypes.Log) common.Hash {
+	parent := l.blocks[len(l.blocks)-1]
+	number := new(big.Int).Add(parent.Number(), common.Big1)
+	txs := types.Transactions{tx}
+
+	header := &ethtypes.Header{
+		ParentHash: parent.Hash(),
+		Number:     number,
+		Difficulty: big.NewInt(0),
+		GasLimit:   30_000_000,
+		GasUsed:    21_000,
+		Time:       parent.Time() + 2,
+		Root:       crypto.Keccak256Hash([]byte("state"), number.Bytes()),
+		TxHash:     types.DeriveSha(txs, trie.NewStackTrie(nil)),
+		Extra:      []byte("simulated"),
+	}
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+	l.blocks = append(l.blocks, block)
+
+	for i, log := range logs {
+		log.BlockNumber = number.Uint64()
+		log.BlockHash = block.Hash()
+		log.TxHash = tx.Hash()
+		log.Index = uint(i)
+	}
+	for _, log := range logs {
+		l.logs = append(l.logs, *log)
+	}
+	l.receipts[tx.Hash()] = &ethtypes.Receipt{
+		Type:        ethtypes.LegacyTxType,
+		Status:      ethtypes.ReceiptStatusSuccessful,
+		Logs:        logs,
+		TxHash:      tx.Hash(),
+		BlockHash:   block.Hash(),
+		BlockNumber: number,
+	}
+
+	return tx.Hash()
+}
+
+// storageTrie returns the storage trie of L2ToL1MessagePasser.sol at the
+// given height, holding sentMessages[withdrawalHash] = true for every
+// withdrawal sent. Must be called with the lock held.
+func (l *LightLink) storageTrie(height uint64) (*trie.Trie, error) {
+	if height >= uint64(len(l.blocks)) {
+		return nil, fmt.Errorf("no state at height %d", height)
+	}
+
+	t := trie.NewEmpty(triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil))
+	value, _ := rlp.EncodeToBytes([]byte{0x01})
+	for _, w := range l.withdrawals {
+		if w.height > height {
+			break
+		}
+		slot := sentMessagesSlot(w.hash)
+		if err := t.Update(crypto.Keccak256(slot[:]), value); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// sentMessagesSlot returns the storage slot of sentMessages[withdrawalHash],
+// keccak256(abi.encode(withdrawalHash, 0)).
+func sentMessagesSlot(withdrawalHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(withdrawalHash[:], common.Hash{}.Bytes())
+}
+
+func (l *LightLink) GetChainId(ctx context.Context) (uint64, error) {
+	return LightLinkChainID.Uint64(), nil
+}
+
+func (l *LightLink) GetHeight(ctx context.Context) (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return uint64(len(l.blocks) - 1), nil
+}
+
+// GetHeightByTag returns the latest height, as simulated blocks are final
+// as soon as they are mined.
+func (l *LightLink) GetHeightByTag(ctx context.Context, tag string) (uint64, error) {
+	return l.GetHeight(ctx)
+}
+
+func (l *LightLink) GetBlock(ctx context.Context, height uint64) (*types.Block, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if height >= uint64(len(l.blocks)) {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	return l.blocks[height], nil
+}
+
+func (l *LightLink) GetBlocks(ctx context.Context, start, end, maxSize uint64) ([]*types.Block, error) {
+	blocks := []*types.Block{}
+	for i := start; i <= end; i++ {
+		block, err := l.GetBlock(ctx, i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block at height %d: %w", i, err)
+		}
+		blocks = append(blocks, block)
+	}
+	if maxSize == 0 {
+		return blocks, nil
+	}
+
+	bundle, err := node.PackBundle(blocks, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Blocks, nil
+}
+
+func (l *LightLink) GetOutputV0(ctx context.Context, last *ethtypes.Header) (node.OutputV0, error) {
+	l.mu.RLock()
+	storage, err := l.storageTrie(last.Number.Uint64())
+	l.mu.RUnlock()
+	if err != nil {
+		return node.OutputV0{}, err
+	}
+
+	return node.OutputV0{
+		StateRoot:                last.Root,
+		MessagePasserStorageRoot: storage.Hash(),
+		BlockHash:                utils.HashHeaderWithoutExtraData(types.CopyHeader(last)),
+	}, nil
+}
+
+func (l *LightLink) GetProof(ctx context.Context, address common.Address, keys []string, height uint64) (*node.RawProof, error) {
+	l.mu.RLock()
+	storage, err := l.storageTrie(height)
+	l.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &node.RawProof{
+		Address:      address.Hex(),
+		AccountProof: []string{},
+		Balance:      "0x0",
+		CodeHash:     crypto.Keccak256Hash(nil).Hex(),
+		Nonce:        "0x0",
+		StorageHash:  storage.Hash().Hex(),
+	}
+	for _, key := range keys {
+		nodes := &proofList{}
+		if err := storage.Prove(crypto.Keccak256(common.HexToHash(key).Bytes()), nodes); err != nil {
+			return nil, fmt.Errorf("failed to prove %s: %w", key, err)
+		}
+
+		value, err := storage.Get(crypto.Keccak256(common.HexToHash(key).Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		proof.StorageProof = append(proof.StorageProof, struct {
+			Key   string
+			Value string
+			Proof []string
+		}{Key: key, Value: hexutil.Encode(value), Proof: *nodes})
+	}
+
+	return proof, nil
+}
+
+func (l *LightLink) GetReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	receipt, ok := l.receipts[txHash]
+	if !ok {
+		return nil, fmt.Errorf("receipt for tx %s not found", txHash.Hex())
+	}
+	return receipt, nil
+}
+
+func (l *LightLink) GetLogs(ctx context.Context, start, end uint64, address common.Address, topics [][]common.Hash) ([]ethtypes.Log, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	q := geth.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+		Addresses: []common.Address{address},
+		Topics:    topics,
+	}
+	logs := []ethtypes.Log{}
+	for _, log := range l.logs {
+		if matchLog(q, log) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// proofList collects the trie nodes of a proof, as hex.
+type proofList []string
+
+func (p *proofList) Put(key []byte, value []byte) error {
+	*p = append(*p, hexutil.Encode(value))
+	return nil
+}
+
+func (p *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+func (l *LightLink) WithdrawalAddress(height uint64) common.Address {
+	return l.passer
+}
@@ -0,0 +1,177 @@
+package simulated
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// logBackend is an in-memory bind.ContractFilterer. Events emitted by the
+// simulated contracts are ABI encoded exactly as the real contracts would
+// emit them, so the generated Filter* and Watch* bindings work unchanged.
+type logBackend struct {
+	mu   sync.RWMutex
+	logs []types.Log
+	feed event.Feed
+}
+
+var _ bind.ContractFilterer = &logBackend{}
+
+// emit ABI encodes the event and appends it to the log.
+func (b *logBackend) emit(contract *abi.ABI, address common.Address, blockNumber uint64, txHash common.Hash, name string, args ...any) error {
+	l, err := encodeLog(contract, address, name, args...)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	l.BlockNumber = blockNumber
+	l.BlockHash = blockHash(blockNumber)
+	l.TxHash = txHash
+	l.Index = uint(len(b.logs))
+	b.logs = append(b.logs, *l)
+	b.mu.Unlock()
+
+	b.feed.Send(*l)
+	return nil
+}
+
+// encodeLog ABI encodes the event as the contract at address would emit it.
+func encodeLog(contract *abi.ABI, address common.Address, name string, args ...any) (*types.Log, error) {
+	ev, ok := contract.Events[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown event %s", name)
+	}
+	if len(args) != len(ev.Inputs) {
+		return nil, fmt.Errorf("event %s expects %d args, got %d", name, len(ev.Inputs), len(args))
+	}
+
+	topics := []common.Hash{ev.ID}
+	nonIndexed := []any{}
+	for i, input := range ev.Inputs {
+		if !input.Indexed {
+			nonIndexed = append(nonIndexed, args[i])
+			continue
+		}
+		t, err := abi.MakeTopics([]any{args[i]})
+		if err != nil {
+			return nil, fmt.Errorf("failed to make topic for %s.%s: %w", name, input.Name, err)
+		}
+		topics = append(topics, t[0][0])
+	}
+
+	data, err := ev.Inputs.NonIndexed().Pack(nonIndexed...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", name, err)
+	}
+
+	return &types.Log{Address: address, Topics: topics, Data: data}, nil
+}
+
+func (b *logBackend) FilterLogs(ctx context.Context, q geth.FilterQuery) ([]types.Log, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	logs := []types.Log{}
+	for _, l := range b.logs {
+		if matchLog(q, l) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (b *logBackend) SubscribeFilterLogs(ctx context.Context, q geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	sink := make(chan types.Log, 1024)
+	sub := b.feed.Subscribe(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case l := <-sink:
+				if !matchLog(q, l) {
+					continue
+				}
+				select {
+				case ch <- l:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// matchLog reports whether the log matches the filter query.
+func matchLog(q geth.FilterQuery, l types.Log) bool {
+	if q.BlockHash != nil && *q.BlockHash != l.BlockHash {
+		return false
+	}
+	if q.FromBlock != nil && q.FromBlock.Sign() >= 0 && l.BlockNumber < q.FromBlock.Uint64() {
+		return false
+	}
+	if q.ToBlock != nil && q.ToBlock.Sign() >= 0 && l.BlockNumber > q.ToBlock.Uint64() {
+		return false
+	}
+
+	if len(q.Addresses) > 0 {
+		found := false
+		for _, a := range q.Addresses {
+			if a == l.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Topics) > len(l.Topics) {
+		return false
+	}
+	for i, sub := range q.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, t := range sub {
+			if t == l.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mustABI parses the ABI from generated contract metadata.
+func mustABI(meta *bind.MetaData) *abi.ABI {
+	a, err := meta.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// u256 is a helper for encoding event args.
+func u256(n uint64) *big.Int {
+	return new(big.Int).SetUint64(n)
+}
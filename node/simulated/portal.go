@@ -0,0 +1,164 @@
+package simulated
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math/big"
+
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+	"hummingbird/node/ethereum"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/trie"
+
+	lightLinkPortalContract "hummingbird/node/contracts/LightLinkPortal.sol"
+)
+
+var _ ethereum.LightLinkPortal = &Ethereum{}
+
+// ProveWithdrawalTransaction verifies the output root proof against the
+// rollup block at l2OutputIndex, and the withdrawal proof against the
+// message passer storage root, as LightLinkPortal.sol does.
+func (e *Ethereum) ProveWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction, l2OutputIndex uint64, outputRootProof lightLinkPortalContract.TypesOutputRootProof, withdrawalProof [][]byte) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if l2OutputIndex >= uint64(len(e.headers)) {
+		return nil, revert("block %d not found", l2OutputIndex)
+	}
+
+	output := node.OutputV0{
+		StateRoot:                outputRootProof.StateRoot,
+		MessagePasserStorageRoot: outputRootProof.MessagePasserStorageRoot,
+		BlockHash:                outputRootProof.LatestBlockhash,
+	}
+	if output.Root() != common.Hash(e.headers[l2OutputIndex].OutputRoot) {
+		return nil, revert("invalid output root proof")
+	}
+
+	withdrawalHash, err := node.HashWithdrawal(tx)
+	if err != nil {
+		return nil, err
+	}
+	if e.finalizedWithdrawals[withdrawalHash] {
+		return nil, revert("withdrawal has already been finalized")
+	}
+
+	proofDb := memorydb.New()
+	for _, n := range withdrawalProof {
+		if err := proofDb.Put(crypto.Keccak256(n), n); err != nil {
+			return nil, err
+		}
+	}
+	slot := sentMessagesSlot(withdrawalHash)
+	value, err := trie.VerifyProof(output.MessagePasserStorageRoot, crypto.Keccak256(slot[:]), proofDb)
+	if err != nil || !bytes.Equal(value, []byte{0x01}) {
+		return nil, revert("invalid withdrawal inclusion proof")
+	}
+
+	t := e.mine(LightLinkPortalAddress, nil)
+	e.provenWithdrawals[withdrawalHash] = contracts.ProvenWithdrawal{
+		OutputRoot:    output.Root(),
+		Timestamp:     big.NewInt(e.now.Unix()),
+		L2OutputIndex: u256(l2OutputIndex),
+	}
+	e.emit(lightLinkPortalABI, LightLinkPortalAddress, t, "WithdrawalProven", withdrawalHash, tx.Sender, tx.Target)
+	return t, nil
+}
+
+// FinalizeWithdrawalTransaction finalizes a proven withdrawal once the
+// rollup block it was proven against is past the challenge window. The
+// withdrawal is not executed on L1.
+func (e *Ethereum) FinalizeWithdrawalTransaction(ctx context.Context, tx lightLinkPortalContract.TypesWithdrawalTransaction) (*ethtypes.Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	withdrawalHash, err := node.HashWithdrawal(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	proven, ok := e.provenWithdrawals[withdrawalHash]
+	if !ok {
+		return nil, revert("withdrawal has not been proven yet")
+	}
+	if e.finalizedWithdrawals[withdrawalHash] {
+		return nil, revert("withdrawal has already been finalized")
+	}
+	index := proven.L2OutputIndex.Uint64()
+	if index >= uint64(len(e.headers)) || common.Hash(e.headers[index].OutputRoot) != proven.OutputRoot {
+		return nil, revert("output root proven is not the same as current output root")
+	}
+	if !e.isOutputFinalized(index) {
+		return nil, revert("output proposal has not been finalized yet")
+	}
+
+	t := e.mine(LightLinkPortalAddress, nil)
+	e.finalizedWithdrawals[withdrawalHash] = true
+	e.emit(lightLinkPortalABI, LightLinkPortalAddress, t, "WithdrawalFinalized", withdrawalHash, true)
+	return t, nil
+}
+
+func (e *Ethereum) GetProvenWithdrawal(ctx context.Context, withdrawalHash common.Hash) (contracts.ProvenWithdrawal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	proven, ok := e.provenWithdrawals[withdrawalHash]
+	if !ok {
+		return contracts.ProvenWithdrawal{Timestamp: new(big.Int), L2OutputIndex: new(big.Int)}, nil
+	}
+	return proven, nil
+}
+
+func (e *Ethereum) IsWithdrawalFinalized(ctx context.Context, withdrawalHash common.Hash) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.finalizedWithdrawals[withdrawalHash], nil
+}
+
+func (e *Ethereum) IsOutputFinalized(ctx context.Context, l2OutputIndex uint64) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isOutputFinalized(l2OutputIndex), nil
+}
+
+// isOutputFinalized returns true once the rollup block at the index is past
+// the challenge window. Must be called with the lock held.
+func (e *Ethereum) isOutputFinalized(index uint64) bool {
+	if index >= uint64(len(e.headers)) {
+		return false
+	}
+	return e.now.After(e.pushedAt[index].Add(e.opts.ChallengeWindow))
+}
+
+// Deposit deposits into LightLink as LightLinkPortal.depositTransaction
+// would, emitting a TransactionDeposited event. The deposit is not included
+// on LightLink, see LightLink.IncludeDeposit.
+func (e *Ethereum) Deposit(from, to common.Address, mint, value *big.Int, gasLimit uint64, isCreation bool, data []byte) *ethtypes.Transaction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// opaqueData is abi.encodePacked(mint, value, gasLimit, isCreation, data)
+	opaque := append(common.BigToHash(mint).Bytes(), common.BigToHash(value).Bytes()...)
+	opaque = binary.BigEndian.AppendUint64(opaque, gasLimit)
+	if isCreation {
+		opaque = append(opaque, 1)
+	} else {
+		opaque = append(opaque, 0)
+	}
+	opaque = append(opaque, data...)
+
+	t := e.mine(LightLinkPortalAddress, mint)
+	e.emit(lightLinkPortalABI, LightLinkPortalAddress, t, "TransactionDeposited", from, to, new(big.Int), opaque)
+	return t
+}
+
+func (e *Ethereum) FilterTransactionDeposited(opts *bind.FilterOpts, from []common.Address, to []common.Address, version []*big.Int) (*lightLinkPortalContract.LightLinkPortalTransactionDepositedIterator, error) {
+	return e.lightLinkPortal.FilterTransactionDeposited(opts, from, to, version)
+}
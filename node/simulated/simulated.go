@@ -0,0 +1,55 @@
+// Package simulated provides in-memory Ethereum, Celestia and LightLink
+// networks that keep consistent state with each other, so the publisher,
+// challenger and defender can be run together in tests with no network.
+package simulated
+
+import (
+	"hummingbird/node"
+	"hummingbird/node/signer"
+)
+
+// DefaultNamespace is the Celestia namespace bundles are published to.
+const DefaultNamespace = "simulated"
+
+// Backend is a set of simulated networks.
+type Backend struct {
+	Ethereum  *Ethereum
+	Celestia  *Celestia
+	LightLink *LightLink
+
+	// Signer signs for the publisher set in the simulated
+	// CanonicalStateChain.
+	Signer *signer.Mock
+}
+
+// NewBackend returns a set of simulated networks with a genesis rollup
+// block and a LightLink chain holding only its genesis block.
+func NewBackend() *Backend {
+	publisher := signer.NewMock()
+
+	cel := NewCelestia(DefaultNamespace)
+	eth := NewEthereum(cel, EthereumOpts{Publisher: publisher.Address()})
+
+	return &Backend{
+		Ethereum:  eth,
+		Celestia:  cel,
+		LightLink: NewLightLink(),
+		Signer:    publisher,
+	}
+}
+
+// Node returns a node connected to the simulated networks, with an in
+// memory store.
+func (b *Backend) Node() (*node.Node, error) {
+	store, err := node.NewMemStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &node.Node{
+		Ethereum:  b.Ethereum,
+		Celestia:  b.Celestia,
+		LightLink: b.LightLink,
+		Store:     store,
+	}, nil
+}
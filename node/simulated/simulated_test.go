@@ -0,0 +1,936 @@
+package simulated_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hummingbird/api"
+	"hummingbird/challenger"
+	"hummingbird/defender"
+	"hummingbird/health"
+	"hummingbird/metrics"
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+	"hummingbird/node/simulated"
+	"hummingbird/relayer"
+	"hummingbird/rollup"
+	"hummingbird/settler"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type harness struct {
+	backend    *simulated.Backend
+	node       *node.Node
+	rollup     *rollup.Rollup
+	challenger *challenger.Challenger
+	defender   *defender.Defender
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	b := simulated.NewBackend()
+	n, err := b.Node()
+	require.NoError(t, err)
+
+	return &harness{
+		backend: b,
+		node:    n,
+		rollup: rollup.NewRollup(n, &rollup.Opts{
+			BundleCount: 1,
+			BundleSize:  10,
+			Logger:      logger,
+			Store:       true,
+		}),
+		challenger: challenger.NewChallenger(n, &challenger.Opts{Logger: logger}),
+		defender:   defender.NewDefender(n, &defender.Opts{Logger: logger}),
+	}
+}
+
+// publish mines L2 blocks and publishes them in a new rollup block.
+func (h *harness) publish(t *testing.T, l2Blocks int) *rollup.Block {
+	t.Helper()
+
+	ctx := t.Context()
+
+	h.backend.LightLink.Mine(l2Blocks)
+	block, _, err := h.rollup.CreateAndSubmitNextBlock(ctx)
+	require.NoError(t, err)
+	return block
+}
+
+func TestPublishBlock(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+
+	block := h.publish(t, 10)
+
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+	assert.Equal(t, uint64(10), block.L2Height)
+
+	// the published bundle can be read back from Celestia
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+	_, bundles, err := h.node.FetchRollupBlock(ctx, hash)
+	require.NoError(t, err)
+	require.Len(t, bundles, 1)
+	assert.Equal(t, uint64(10), bundles[0].Height())
+
+	// a second block extends the first
+	block = h.publish(t, 5)
+	assert.Equal(t, uint64(15), block.L2Height)
+	assert.Equal(t, [32]byte(hash), block.PrevHash)
+}
+
+func TestPublishBundlesAtOneHeight(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.rollup = rollup.NewRollup(h.node, &rollup.Opts{
+		BundleCount: 3,
+		BundleSize:  10,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Store:       true,
+	})
+
+	block := h.publish(t, 30)
+	assert.Equal(t, uint64(30), block.L2Height)
+
+	// every bundle lands at the same Celestia height, in its own share range
+	pointers := block.CelestiaPointers
+	require.Len(t, pointers, 3)
+	for i := 1; i < len(pointers); i++ {
+		assert.Equal(t, pointers[0].Height, pointers[i].Height)
+		assert.Equal(t, pointers[i-1].ShareStart.Uint64()+uint64(pointers[i-1].ShareLen), pointers[i].ShareStart.Uint64())
+	}
+	assert.Equal(t, uint64(1), h.backend.Celestia.Height())
+
+	// each bundle is read back from its own share range
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+	_, bundles, err := h.node.FetchRollupBlock(ctx, hash)
+	require.NoError(t, err)
+	require.Len(t, bundles, 3)
+	for i, bundle := range bundles {
+		assert.Equal(t, uint64(10*(i+1)), bundle.Height())
+	}
+}
+
+func TestBundlesPackedBySize(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+
+	// size the blobs to hold about two simulated blocks
+	h.backend.LightLink.Mine(1)
+	first, err := h.backend.LightLink.GetBlock(ctx, 1)
+	require.NoError(t, err)
+	enc, err := rlp.EncodeToBytes(first)
+	require.NoError(t, err)
+	maxBlobSize := rlp.ListSize(2 * uint64(len(enc)))
+
+	h.rollup = rollup.NewRollup(h.node, &rollup.Opts{
+		MaxBlobSize: maxBlobSize,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Store:       true,
+	})
+
+	// the bundles are packed by size, up to the on-chain max pointers, and
+	// the rest of the blocks are left for the next rollup block
+	block := h.publish(t, 29)
+	require.Len(t, block.Bundles, 8)
+	next := uint64(1)
+	for _, bundle := range block.Bundles {
+		assert.Equal(t, next, bundle.Blocks[0].NumberU64())
+		next = bundle.Height() + 1
+
+		encoded, err := bundle.EncodeRLP()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, uint64(len(encoded)), maxBlobSize)
+	}
+	assert.Less(t, block.L2Height, uint64(30))
+
+	block, _, err = h.rollup.CreateAndSubmitNextBlock(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, next, block.Bundles[0].Blocks[0].NumberU64())
+}
+
+func TestResumedBundlesRepacked(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+
+	// 1. leave a candidate with a stored bundle of ten blocks unsubmitted
+	h.backend.LightLink.Mine(10)
+	_, err := h.rollup.CreateNextBlock(ctx)
+	require.NoError(t, err)
+
+	// 2. shrink the blobs to hold about two simulated blocks
+	first, err := h.backend.LightLink.GetBlock(ctx, 11)
+	require.NoError(t, err)
+	enc, err := rlp.EncodeToBytes(first)
+	require.NoError(t, err)
+	h.rollup.Opts.MaxBlobSize = rlp.ListSize(2 * uint64(len(enc)))
+
+	// 3. the stored bundle no longer fits, so the candidate is discarded and
+	// the blocks are fetched again packed by the new size
+	block, _, err := h.rollup.CreateAndSubmitNextBlock(ctx)
+	require.NoError(t, err)
+	require.Len(t, block.Bundles, 1)
+	assert.Equal(t, uint64(11), block.Bundles[0].Blocks[0].NumberU64())
+	assert.Less(t, block.L2Height, uint64(20))
+	encoded, err := block.Bundles[0].EncodeRLP()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, uint64(len(encoded)), h.rollup.Opts.MaxBlobSize)
+}
+
+func TestMaxLatencyFlushesPartialBlock(t *testing.T) {
+	h := newHarness(t)
+	h.rollup.Opts.MaxLatency = 50 * time.Millisecond
+	h.backend.LightLink.Mine(3)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- h.rollup.Run(ctx) }()
+
+	// the 3 blocks are rolled up without waiting for a full block of 10
+	require.Eventually(t, func() bool {
+		head, err := h.backend.Ethereum.GetRollupHead(t.Context())
+		return err == nil && head.L2Height == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDAFeeRecorded(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+
+	block := h.publish(t, 10)
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	// the Celestia fee paid for the block is stored with it
+	buf, err := h.node.Store.Get(ctx, append([]byte("dafee_"), hash[:]...))
+	require.NoError(t, err)
+	var fee node.CelestiaFee
+	require.NoError(t, json.Unmarshal(buf, &fee))
+	assert.Greater(t, fee.Amount, uint64(0))
+	assert.InDelta(t, simulated.GasPrice, fee.GasPrice, 0.0001)
+}
+
+func TestDAPointersStored(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+
+	block := h.publish(t, 10)
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	// the pointers are served from the store, with the blob commitment
+	pointers, err := h.node.GetDAPointer(ctx, hash)
+	require.NoError(t, err)
+	require.Len(t, pointers, 1)
+	assert.Equal(t, common.Hash(hash), pointers[0].RollupHash)
+	assert.NotEqual(t, common.Hash{}, pointers[0].TxHash)
+
+	_, bundles, err := h.node.FetchRollupBlock(ctx, hash)
+	require.NoError(t, err)
+	b, err := bundles[0].Blob(h.node.Celestia.Namespace())
+	require.NoError(t, err)
+	assert.Equal(t, common.BytesToHash(b.Commitment), pointers[0].Commitment)
+}
+
+func TestBundlesStoredByRange(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.backend.LightLink.Mine(123)
+
+	// 1. ranges whose digits concatenate to the same string are stored apart
+	long, err := h.backend.LightLink.GetBlocks(ctx, 1, 123, 0)
+	require.NoError(t, err)
+	short, err := h.backend.LightLink.GetBlocks(ctx, 11, 23, 0)
+	require.NoError(t, err)
+	require.NoError(t, h.node.Store.PutBundle(ctx, &node.Bundle{Blocks: long}))
+	require.NoError(t, h.node.Store.PutBundle(ctx, &node.Bundle{Blocks: short}))
+
+	bundle, err := h.node.Store.GetBundle(ctx, 1, 123)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123), bundle.Size())
+	bundle, err = h.node.Store.GetBundle(ctx, 11, 23)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(13), bundle.Size())
+
+	// 2. an empty bundle is rejected
+	require.Error(t, h.node.Store.PutBundle(ctx, &node.Bundle{}))
+}
+
+func TestL2ReorgRefetched(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+
+	// 1. store a bundle of blocks that are then reorged out
+	h.backend.LightLink.Mine(10)
+	stale, err := h.backend.LightLink.GetBlocks(ctx, 11, 20, 0)
+	require.NoError(t, err)
+	require.NoError(t, h.node.Store.PutBundle(ctx, &node.Bundle{Blocks: stale}))
+	h.backend.LightLink.Reorg(5)
+
+	// 2. the stale bundle is not reused, the blocks are fetched again from
+	// the new chain
+	block, _, err := h.rollup.CreateAndSubmitNextBlock(ctx)
+	require.NoError(t, err)
+	canonical, err := h.backend.LightLink.GetBlock(ctx, 20)
+	require.NoError(t, err)
+	l2Blocks := block.L2Blocks()
+	assert.Equal(t, canonical.Hash(), l2Blocks[len(l2Blocks)-1].Hash())
+
+	// 3. with confirmations, only blocks buried deep enough are rolled up
+	h.rollup.Opts.L2Confirmations = 5
+	h.backend.LightLink.Mine(10)
+	block, _, err = h.rollup.CreateAndSubmitNextBlock(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(25), block.L2Height)
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	h := newHarness(t)
+	h.backend.LightLink.Mine(11)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- h.rollup.Run(ctx) }()
+
+	// the rollup publishes the mined blocks, then waits for more
+	require.Eventually(t, func() bool {
+		height, err := h.backend.Ethereum.GetRollupHeight(t.Context())
+		return err == nil && height == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after the context was cancelled")
+	}
+	require.NoError(t, h.node.Close())
+}
+
+func TestDAChallengeDefended(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	pointer := block.CelestiaPointers[0]
+	shareIndex := uint32(pointer.ShareStart.Uint64()) + 1
+
+	// 1. challenge a share in the rollup block
+	tx, blockHash, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+	_, err = h.backend.Ethereum.Wait(ctx, tx.Hash())
+	require.NoError(t, err)
+
+	info, err := h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeDAStatusChallengerInitiated), info.Status)
+
+	// 2. the defender can't respond until the data root is committed
+	_, err = h.defender.DefendDA(ctx, blockHash, 0, shareIndex)
+	assert.ErrorContains(t, err, defender.ErrNoDataCommitment)
+
+	_, err = h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+
+	// 3. defend the challenge
+	tx, err = h.defender.DefendDA(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+	_, err = h.backend.Ethereum.Wait(ctx, tx.Hash())
+	require.NoError(t, err)
+
+	info, err = h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeDAStatusDefenderWon), info.Status)
+
+	_, err = h.defender.ClaimDAChallengeReward(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+
+	// the block stays in the chain
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+}
+
+func TestDAProofShareIndex(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+	start := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, err = h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+
+	// a share in the range is proven
+	_, _, err = h.defender.GetDaProof(ctx, hash, 0, start+1)
+	require.NoError(t, err)
+
+	// a share before the start of the range is not in the rollup block
+	_, _, err = h.defender.GetDaProof(ctx, hash, 0, start-1)
+	assert.Error(t, err)
+}
+
+func TestDAChallengeExpired(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	shareIndex := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, blockHash, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+
+	key, err := h.backend.Ethereum.DataRootInclusionChallengeKey(nil, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+
+	// settling before expiry fails
+	_, err = h.backend.Ethereum.SettleDataRootInclusion(ctx, key)
+	assert.Error(t, err)
+
+	h.backend.Ethereum.AdvanceTime(3 * 24 * time.Hour)
+
+	_, err = h.backend.Ethereum.SettleDataRootInclusion(ctx, key)
+	require.NoError(t, err)
+
+	info, err := h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeDAStatusChallengerWon), info.Status)
+
+	// the challenged block is rolled back
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), height)
+}
+
+func TestL2HeaderChallengeDefended(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+	block := h.publish(t, 10)
+
+	_, err := h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+
+	// challenge the first l2 block in the second rollup block, so the
+	// previous header must be loaded from the first rollup block
+	l2Num := big.NewInt(11)
+	_, err = h.backend.Ethereum.ChallengeL2Header(ctx, big.NewInt(2), l2Num)
+	require.NoError(t, err)
+
+	rblock, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	tx, err := h.defender.DefendL2Header(ctx, rblock, l2Num)
+	require.NoError(t, err)
+	_, err = h.backend.Ethereum.Wait(ctx, tx.Hash())
+	require.NoError(t, err)
+
+	challengeHash, err := h.backend.Ethereum.GetL2HeaderChallengeHash(ctx, rblock, l2Num)
+	require.NoError(t, err)
+	info, err := h.backend.Ethereum.GetL2HeaderChallenge(ctx, challengeHash)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeL2HeaderStatusDefenderWon), info.Status)
+}
+
+func TestDefenderWatchesChallenges(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	_, err := h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+
+	d := defender.NewDefender(h.node, &defender.Opts{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WorkerDelay: time.Hour,
+		Subscribe:   true,
+	})
+	go d.Start(ctx) //nolint:errcheck
+
+	// wait for the defender to subscribe before challenging, so the
+	// challenge is only seen via the subscription
+	time.Sleep(100 * time.Millisecond)
+
+	shareIndex := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, blockHash, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		info, err := h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+		return err == nil && info.Status == contracts.ChallengeDAStatusDefenderWon
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDefenderResumesPendingChallenges(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	start := func(ctx context.Context) <-chan error {
+		d := defender.NewDefender(h.node, &defender.Opts{
+			Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+			WorkerDelay: 10 * time.Millisecond,
+			Subscribe:   true,
+		})
+		done := make(chan error, 1)
+		go func() { done <- d.Start(ctx) }()
+		return done
+	}
+
+	// 1. the challenge can't be defended before blobstream is relayed, and
+	// the defender is stopped after scanning past it
+	stopCtx, stop := context.WithCancel(ctx)
+	done := start(stopCtx)
+	shareIndex := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, blockHash, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	<-done
+
+	// 2. a restarted defender scans for the pending challenge again
+	_, err = h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+	start(ctx)
+
+	assert.Eventually(t, func() bool {
+		info, err := h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+		return err == nil && info.Status == contracts.ChallengeDAStatusDefenderWon
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestChallengerAuditsBlocks(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+
+	fee, err := h.backend.Ethereum.GetChallengeFee(ctx)
+	require.NoError(t, err)
+	h.challenger.Opts.Budget = fee
+
+	// 1. a block published honestly has no faults
+	faults, err := h.challenger.Audit(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, faults)
+
+	// 2. publish a block with the wrong output root
+	h.backend.LightLink.Mine(10)
+	block, err := h.rollup.CreateNextBlock(ctx)
+	require.NoError(t, err)
+	block.OutputRoot = [32]byte{1}
+	_, err = h.backend.Ethereum.PushRollupHead(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	faults, err = h.challenger.Audit(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, faults, 1)
+	assert.Equal(t, challenger.FaultL2Header, faults[0].Type)
+	assert.Equal(t, uint64(20), faults[0].L2Number)
+
+	// 3. challenge the last header in the block
+	require.NoError(t, h.challenger.Challenge(ctx, faults[0]))
+
+	challengeHash, err := h.backend.Ethereum.GetL2HeaderChallengeHash(ctx, faults[0].BlockHash, big.NewInt(20))
+	require.NoError(t, err)
+	info, err := h.backend.Ethereum.GetL2HeaderChallenge(ctx, challengeHash)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeL2HeaderStatusChallengerInitiated), info.Status)
+
+	// challenging again is a no-op, rather than spending more of the budget
+	require.NoError(t, h.challenger.Challenge(ctx, faults[0]))
+}
+
+func TestL2HeaderChallengeExpired(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	_, _, err := h.challenger.ChallengeL2Header(ctx, 1, 5)
+	require.NoError(t, err)
+
+	rblock, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	// settling before expiry fails
+	_, err = h.challenger.SettleL2HeaderChallenge(ctx, rblock, 5)
+	assert.Error(t, err)
+
+	h.backend.Ethereum.AdvanceTime(3 * 24 * time.Hour)
+
+	_, err = h.challenger.SettleL2HeaderChallenge(ctx, rblock, 5)
+	require.NoError(t, err)
+
+	// the challenged block is rolled back
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), height)
+}
+
+func TestInvalidateHeader(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	// a valid header can't be invalidated
+	_, err := h.challenger.InvalidateHeader(ctx, 1)
+	assert.Error(t, err)
+
+	// push a header that does not extend the head
+	header := *block.CanonicalStateChainHeader
+	header.L2Height = 20
+	_, err = h.backend.Ethereum.ForceRollupHead(&header)
+	require.NoError(t, err)
+
+	_, err = h.challenger.InvalidateHeader(ctx, 2)
+	require.NoError(t, err)
+
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+}
+
+func TestSettlerSweepsExpiredChallenges(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	s := settler.NewSettler(h.node, &settler.Opts{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Address: h.backend.Signer.Address(),
+	})
+
+	shareIndex := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, _, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+
+	// 1. the challenge is tracked, but can't be settled before expiry
+	require.NoError(t, s.Sweep(ctx))
+	records, err := s.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	for _, r := range records {
+		assert.False(t, r.Done)
+		assert.Equal(t, uint8(contracts.ChallengeDAStatusChallengerInitiated), r.Status)
+	}
+
+	// 2. once expired, the challenge is settled and the reward claimed
+	h.backend.Ethereum.AdvanceTime(3 * 24 * time.Hour)
+	require.NoError(t, s.Sweep(ctx))
+
+	records, err = s.Records(ctx)
+	require.NoError(t, err)
+	for _, r := range records {
+		assert.True(t, r.Done)
+		assert.Empty(t, r.Error)
+		assert.Equal(t, uint8(contracts.ChallengeDAStatusChallengerWon), r.Status)
+		assert.NotZero(t, r.SettleTx)
+		assert.NotZero(t, r.ClaimTx)
+	}
+
+	height, err := h.backend.Ethereum.GetRollupHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), height)
+
+	// a restarted settler has nothing left to do
+	s = settler.NewSettler(h.node, &settler.Opts{Address: h.backend.Signer.Address()})
+	require.NoError(t, s.Sweep(ctx))
+}
+
+func TestSettlerOnlyClaimsChallengesWon(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+
+	// the settler runs for someone who is neither challenger nor defender
+	s := settler.NewSettler(h.node, &settler.Opts{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Address: common.Address{0x0b},
+	})
+
+	shareIndex := uint32(block.CelestiaPointers[0].ShareStart.Uint64())
+	_, blockHash, err := h.challenger.ChallengeDA(ctx, 1, 0, shareIndex)
+	require.NoError(t, err)
+
+	// 1. the expired challenge is settled, but the reward is not claimed
+	h.backend.Ethereum.AdvanceTime(3 * 24 * time.Hour)
+	require.NoError(t, s.Sweep(ctx))
+
+	records, err := s.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	for _, r := range records {
+		assert.True(t, r.Done)
+		assert.False(t, r.Lost)
+		assert.NotZero(t, r.SettleTx)
+		assert.Zero(t, r.ClaimTx)
+	}
+
+	info, err := h.backend.Ethereum.GetDataRootInclusionChallenge(ctx, blockHash, 0, shareIndex)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(contracts.ChallengeDAStatusChallengerWon), info.Status)
+	assert.False(t, info.Claimed)
+}
+
+func TestWithdrawalProvedAndFinalized(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+
+	txHash, _ := h.backend.LightLink.Withdraw(common.Address{0x02}, big.NewInt(1e18), 100_000, []byte("hello"))
+	withdrawals, err := h.node.GetWithdrawals(ctx, txHash)
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	w := withdrawals[0]
+	assert.Equal(t, uint64(11), w.L2Height)
+
+	// 1. the withdrawal can't be proven until it is rolled up
+	_, err = h.node.ProveWithdrawal(ctx, w)
+	require.ErrorIs(t, err, node.ErrNotRolledUp)
+
+	h.publish(t, 9)
+	h.publish(t, 10)
+	status, err := h.node.GetWithdrawalStatus(ctx, w)
+	require.NoError(t, err)
+	assert.True(t, status.RolledUp)
+	assert.Equal(t, uint64(2), status.RollupIndex)
+	assert.False(t, status.Proven)
+
+	// 2. prove against the first rollup block including it
+	tx, err := h.node.ProveWithdrawal(ctx, w)
+	require.NoError(t, err)
+	_, err = h.backend.Ethereum.Wait(ctx, tx.Hash())
+	require.NoError(t, err)
+
+	status, err = h.node.GetWithdrawalStatus(ctx, w)
+	require.NoError(t, err)
+	assert.True(t, status.Proven)
+	assert.Equal(t, uint64(2), status.L2OutputIndex)
+	assert.False(t, status.ReadyToFinalize)
+
+	// 3. finalize once past the challenge window
+	_, err = h.node.FinalizeWithdrawal(ctx, w)
+	require.Error(t, err)
+
+	h.backend.Ethereum.AdvanceTime(3*24*time.Hour + time.Second)
+	_, err = h.node.FinalizeWithdrawal(ctx, w)
+	require.NoError(t, err)
+
+	status, err = h.node.GetWithdrawalStatus(ctx, w)
+	require.NoError(t, err)
+	assert.True(t, status.Finalized)
+}
+
+func TestRelayerProvesAndFinalizesWithdrawals(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	h.publish(t, 10)
+
+	allowed := common.Address{0x02}
+	r := relayer.NewRelayer(h.node, &relayer.Opts{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StartHeight: 1,
+		Targets:     []common.Address{allowed},
+	})
+
+	h.backend.LightLink.Withdraw(allowed, big.NewInt(1e18), 100_000, nil)
+	h.backend.LightLink.Withdraw(common.Address{0x03}, big.NewInt(1e18), 100_000, nil)
+
+	// 1. only the allowed withdrawal is tracked, pending a rollup block
+	require.NoError(t, r.Relay(ctx))
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	var rec *relayer.Record
+	for _, v := range records {
+		rec = v
+	}
+	assert.Equal(t, allowed, rec.Withdrawal.Tx.Target)
+	assert.Equal(t, relayer.StatePending, rec.State)
+
+	// 2. proven once rolled up
+	h.publish(t, 8)
+	require.NoError(t, r.Relay(ctx))
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	rec = records[rec.Withdrawal.Hash]
+	assert.Equal(t, relayer.StateProven, rec.State)
+	assert.NotEqual(t, common.Hash{}, rec.ProveTx)
+
+	// 3. finalized once past the challenge window
+	require.NoError(t, r.Relay(ctx))
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, relayer.StateProven, records[rec.Withdrawal.Hash].State)
+
+	h.backend.Ethereum.AdvanceTime(3*24*time.Hour + time.Second)
+	require.NoError(t, r.Relay(ctx))
+	rec, err = r.Record(ctx, rec.Withdrawal.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, relayer.StateFinalized, rec.State)
+	assert.NotEqual(t, common.Hash{}, rec.FinalizeTx)
+
+	finalized, err := h.backend.Ethereum.IsWithdrawalFinalized(ctx, rec.Withdrawal.Hash)
+	require.NoError(t, err)
+	assert.True(t, finalized)
+
+	// 4. a finalized withdrawal is no longer loaded, and can't be requeued
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	assert.Error(t, r.Requeue(ctx, rec.Withdrawal.Hash))
+}
+
+func TestTrackDeposits(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+
+	h.backend.Ethereum.Deposit(common.Address{0x04}, common.Address{0x05}, big.NewInt(1e18), big.NewInt(0), 100_000, false, nil)
+	h.backend.Ethereum.Deposit(common.Address{0x04}, common.Address{0x06}, big.NewInt(2e18), big.NewInt(0), 100_000, false, []byte("data"))
+
+	height, err := h.backend.Ethereum.GetHeight(ctx)
+	require.NoError(t, err)
+	deposits, err := h.node.GetDeposits(ctx, 0, height)
+	require.NoError(t, err)
+	require.Len(t, deposits, 2)
+	assert.Equal(t, big.NewInt(2e18), deposits[1].Mint)
+	assert.Equal(t, []byte("data"), deposits[1].Data)
+	assert.NotEqual(t, deposits[0].SourceHash, deposits[1].SourceHash)
+
+	// 1. the first deposit is included, the second is still pending
+	txHash := h.backend.LightLink.IncludeDeposit(deposits[0])
+	h.backend.LightLink.Mine(2)
+	require.NoError(t, h.node.TrackDeposits(ctx, deposits, time.Minute))
+	assert.Equal(t, node.DepositIncluded, deposits[0].State)
+	assert.Equal(t, txHash, deposits[0].L2TxHash)
+	assert.Equal(t, uint64(1), deposits[0].L2Height)
+	assert.Equal(t, node.DepositPending, deposits[1].State)
+
+	// 2. the second deposit is missing once LightLink is past the timeout
+	h.backend.LightLink.Mine(40)
+	require.NoError(t, h.node.TrackDeposits(ctx, deposits, time.Minute))
+	assert.Equal(t, node.DepositIncluded, deposits[0].State)
+	assert.Equal(t, node.DepositMissing, deposits[1].State)
+}
+
+func TestServeRollupData(t *testing.T) {
+	ctx := t.Context()
+	h := newHarness(t)
+	block := h.publish(t, 10)
+	hash, err := h.backend.Ethereum.HashHeader(ctx, block.CanonicalStateChainHeader)
+	require.NoError(t, err)
+
+	s, err := api.NewServer(h.node, &api.Opts{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	require.NoError(t, err)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	client, err := rpc.Dial(srv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	var info rollup.RollupInfo
+	require.NoError(t, client.Call(&info, "hb_getRollupInfo"))
+	assert.Equal(t, uint64(1), info.RollupHeight)
+	assert.Equal(t, hash, info.LatestRollup.Hash)
+
+	// a rollup block can be referenced by index or hash
+	var byIndex, byHash rollup.RollupBlockInfo
+	require.NoError(t, client.Call(&byIndex, "hb_getRollupBlock", 1))
+	require.NoError(t, client.Call(&byHash, "hb_getRollupBlock", hash))
+	assert.Equal(t, hash, byIndex.Hash)
+	assert.Equal(t, byIndex, byHash)
+
+	var bundle api.Bundle
+	require.NoError(t, client.Call(&bundle, "hb_getBundle", "0x1", 0))
+	require.Len(t, bundle.Blocks, 10)
+	assert.Equal(t, uint64(10), bundle.Blocks[9].Number)
+
+	_, err = h.backend.Ethereum.RelayBlobstream()
+	require.NoError(t, err)
+	var proof defender.L2ShareProof
+	require.NoError(t, client.Call(&proof, "hb_getHeaderShareProof", hash, bundle.Blocks[4].Hash))
+	assert.Equal(t, uint8(0), proof.PointerIndex)
+	assert.NotEmpty(t, proof.Ranges)
+
+	err = client.Call(&bundle, "hb_getBundle", hash, 1)
+	assert.ErrorContains(t, err, "out of range")
+}
+
+func TestMetricsRecorded(t *testing.T) {
+	h := newHarness(t)
+	h.node.Metrics = metrics.New()
+	h.publish(t, 10)
+
+	s, err := api.NewServer(h.node, &api.Opts{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "hb_rollup_push_gas_used_count 1")
+	assert.Contains(t, body, `hb_rpc_errors_total{backend="celestia"} 0`)
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	h := newHarness(t)
+	h.node.Heartbeat = node.NewHeartbeat()
+
+	checker := health.NewChecker(h.node, &health.Opts{
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxHeartbeatAge: 50 * time.Millisecond,
+		Signer:          h.backend.Signer,
+	})
+	mux := http.NewServeMux()
+	checker.Register(mux)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	// 1. every backend is reachable and the key is the publisher
+	rec := get("/readyz")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ready health.ReadyStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ready))
+	assert.True(t, ready.Ready)
+	assert.Len(t, ready.Checks, 5)
+	require.NotNil(t, ready.Roles)
+	assert.True(t, ready.Roles.IsPublisher)
+
+	// 2. healthy while the main loop beats, unhealthy once it is stuck
+	h.node.Heartbeat.Beat("awaitL2Height")
+	assert.Equal(t, http.StatusOK, get("/healthz").Code)
+
+	time.Sleep(100 * time.Millisecond)
+	rec = get("/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var status health.HealthStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, "awaitL2Height", status.Stage)
+}
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,15 +9,25 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 )
 
+// ErrNotFound is returned by a KVStore when the requested key does not exist.
+var ErrNotFound = errors.New("not found")
+
+// KVStore is the local store of rollup data. The context allows stores
+// backed by a remote database to be cancelled. LDBStore is local, so ignores
+// it, which lets state be checkpointed while shutting down.
 type KVStore interface {
-	Get(key []byte) ([]byte, error)
-	Put(key, value []byte) error
-	Delete(key []byte) error
-	GetDAPointer(hash common.Hash) (*CelestiaPointer, error)
-	PutBundle(bundle *Bundle) error
-	GetBundle(startBlock uint64, endBlock uint64) (*Bundle, error)
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	Put(ctx context.Context, key, value []byte) error
+	Delete(ctx context.Context, key []byte) error
+	GetDAPointers(ctx context.Context, hash common.Hash) ([]*CelestiaPointer, error)
+	PutDAPointers(ctx context.Context, hash common.Hash, pointers []*CelestiaPointer) error
+	PutBundle(ctx context.Context, bundle *Bundle) error
+	GetBundle(ctx context.Context, startBlock uint64, endBlock uint64) (*Bundle, error)
+	DeleteBundle(ctx context.Context, startBlock uint64, endBlock uint64) error
+	Close() error
 }
 
 type LDBStore struct {
@@ -32,69 +43,112 @@ func NewLDBStore(path string) (*LDBStore, error) {
 	return &LDBStore{db: db}, nil
 }
 
-func (l *LDBStore) Get(key []byte) ([]byte, error) {
-	return l.db.Get(key, nil)
+// NewMemStore returns an LDBStore that is kept in memory, useful for testing.
+func NewMemStore() (*LDBStore, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leveldb: %w", err)
+	}
+
+	return &LDBStore{db: db}, nil
+}
+
+func (l *LDBStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	buf, err := l.db.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return buf, err
 }
 
-func (l *LDBStore) Put(key, value []byte) error {
+func (l *LDBStore) Put(ctx context.Context, key, value []byte) error {
 	return l.db.Put(key, value, nil)
 }
 
-func (l *LDBStore) Delete(key []byte) error {
+func (l *LDBStore) Delete(ctx context.Context, key []byte) error {
 	return l.db.Delete(key, nil)
 }
 
-func (l *LDBStore) GetDAPointer(hash common.Hash) (*CelestiaPointer, error) {
+// Close closes the database, flushing any pending writes.
+func (l *LDBStore) Close() error {
+	return l.db.Close()
+}
+
+func pointerKey(hash common.Hash) []byte {
+	return append([]byte("pointer_"), hash[:]...)
+}
+
+// GetDAPointers returns the Celestia pointers of the rollup block with the
+// given hash.
+func (l *LDBStore) GetDAPointers(ctx context.Context, hash common.Hash) ([]*CelestiaPointer, error) {
 	if l.db == nil {
 		return nil, errors.New("no store")
 	}
 
-	key := append([]byte("pointer_"), hash[:]...)
-	buf, err := l.Get(key)
+	buf, err := l.Get(ctx, pointerKey(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get celestia pointers from store: %w", err)
+	}
+
+	pointers := []*CelestiaPointer{}
+	err = json.Unmarshal(buf, &pointers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get celestia pointer from store: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal celestia pointers: %w", err)
 	}
 
-	pointer := &CelestiaPointer{}
-	err = json.Unmarshal(buf, pointer)
+	return pointers, nil
+}
+
+// PutDAPointers stores the Celestia pointers of the rollup block with the
+// given hash.
+func (l *LDBStore) PutDAPointers(ctx context.Context, hash common.Hash, pointers []*CelestiaPointer) error {
+	if l.db == nil {
+		return errors.New("no store")
+	}
+
+	buf, err := json.Marshal(pointers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal celestia pointer: %w", err)
+		return fmt.Errorf("failed to marshal celestia pointers: %w", err)
 	}
 
-	return pointer, nil
+	return l.Put(ctx, pointerKey(hash), buf)
+}
+
+func bundleKey(startBlock uint64, endBlock uint64) []byte {
+	return []byte("bundle_" + strconv.FormatUint(startBlock, 10) + "_" + strconv.FormatUint(endBlock, 10))
 }
 
-func (l *LDBStore) PutBundle(bundle *Bundle) error {
+func (l *LDBStore) PutBundle(ctx context.Context, bundle *Bundle) error {
 	if l.db == nil {
 		return errors.New("no store")
 	}
 
-	startL2height := strconv.FormatUint(bundle.Blocks[0].NumberU64(), 10)
-	endL2height := strconv.FormatUint(bundle.Height(), 10)
-	key := []byte("bundle_" + startL2height + endL2height)
+	if bundle == nil || len(bundle.Blocks) == 0 {
+		return errors.New("empty bundle")
+	}
+
+	key := bundleKey(bundle.Blocks[0].NumberU64(), bundle.Height())
 
 	buf, err := bundle.EncodeRLP()
 	if err != nil {
 		return fmt.Errorf("failed to marshal bundle: %w", err)
 	}
 
-	if err := l.Put(key, buf); err != nil {
-		return fmt.Errorf("createNextBlock: Failed to store header: %w", err)
+	if err := l.Put(ctx, key, buf); err != nil {
+		return fmt.Errorf("failed to store bundle: %w", err)
 	}
 
-	return l.Put(key, buf)
+	return nil
 }
 
-func (l *LDBStore) GetBundle(startBlock uint64, endBlock uint64) (*Bundle, error) {
+func (l *LDBStore) GetBundle(ctx context.Context, startBlock uint64, endBlock uint64) (*Bundle, error) {
 	if l.db == nil {
 		return nil, errors.New("no store")
 	}
 
-	startL2height := strconv.FormatUint(startBlock, 10)
-	endL2height := strconv.FormatUint(endBlock, 10)
-	key := []byte("bundle_" + startL2height + endL2height)
+	key := bundleKey(startBlock, endBlock)
 
-	buf, err := l.Get(key)
+	buf, err := l.Get(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bundle from store: %w", err)
 	}
@@ -107,3 +161,13 @@ func (l *LDBStore) GetBundle(startBlock uint64, endBlock uint64) (*Bundle, error
 
 	return bundle, nil
 }
+
+// DeleteBundle deletes the bundle of the blocks from startBlock to endBlock,
+// e.g. after the blocks were reorged out of the L2 chain.
+func (l *LDBStore) DeleteBundle(ctx context.Context, startBlock uint64, endBlock uint64) error {
+	if l.db == nil {
+		return errors.New("no store")
+	}
+
+	return l.Delete(ctx, bundleKey(startBlock, endBlock))
+}
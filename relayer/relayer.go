@@ -0,0 +1,453 @@
+// Package relayer relays withdrawals from LightLink to L1. It follows the
+// withdrawals initiated on LightLink, proves each one in LightLinkPortal.sol
+// once a rollup block includes it, and finalizes it once that rollup block
+// is past the challenge window.
+package relayer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"hummingbird/node"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	lastScannedKey = []byte("relayer_last_scanned") // last L2 block scanned for withdrawals
+	openKey        = []byte("relayer_open")         // hashes of the withdrawals not done yet
+)
+
+// withdrawalKey is the key of a withdrawal record, kept after it is done so
+// it can still be looked up or requeued.
+func withdrawalKey(hash common.Hash) []byte {
+	return append([]byte("relayer_withdrawal_"), hash[:]...)
+}
+
+// maxScanRange is the max number of L2 blocks to filter logs for at once, to
+// avoid hitting eth_getLogs range limits.
+const maxScanRange = uint64(10000)
+
+// State is the progress of a withdrawal through the relayer.
+type State string
+
+const (
+	StatePending   State = "pending"   // waiting for a rollup block to include the withdrawal
+	StateProven    State = "proven"    // proven, waiting for the rollup block to pass the challenge window
+	StateFinalized State = "finalized" // finalized, nothing left to do
+	StateFailed    State = "failed"    // a tx reverted, the withdrawal is not retried until requeued
+)
+
+// Record is the relay state of a withdrawal. It is persisted after every
+// step, so a restarted relayer picks up where it left off.
+type Record struct {
+	Withdrawal *node.Withdrawal
+	State      State
+	ProveTx    common.Hash // set once proven by us
+	FinalizeTx common.Hash // set once finalized by us
+	Error      string      // last error, if any
+}
+
+// Done returns true once there is nothing left to do for the withdrawal.
+func (r *Record) Done() bool {
+	return r.State == StateFinalized || r.State == StateFailed
+}
+
+type Opts struct {
+	Logger      *slog.Logger
+	WorkerDelay time.Duration    // Delay between each relay run.
+	StartHeight uint64           // L2 block to start scanning from on the first run. If 0, starts from the current L2 height.
+	Senders     []common.Address // Only relay withdrawals sent from these L2 addresses. If empty, any sender is relayed.
+	Targets     []common.Address // Only relay withdrawals to these L1 addresses. If empty, any target is relayed.
+}
+
+type Relayer struct {
+	*node.Node
+	Opts *Opts
+
+	lastScanned *uint64                 // used when there is no store
+	open        []common.Hash           // used when there is no store
+	records     map[common.Hash]*Record // used when there is no store
+}
+
+func NewRelayer(node *node.Node, opts *Opts) *Relayer {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return &Relayer{Node: node, Opts: opts, records: make(map[common.Hash]*Record)}
+}
+
+// Start relays withdrawals every WorkerDelay.
+func (r *Relayer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Opts.WorkerDelay)
+	defer ticker.Stop()
+
+	for {
+		r.Heartbeat.Beat("relay")
+		if err := r.Relay(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay tracks any new withdrawals, then proves or finalizes every
+// withdrawal that is not done.
+func (r *Relayer) Relay(ctx context.Context) error {
+	// 1. track any new withdrawals
+	if err := r.scan(ctx); err != nil {
+		return err
+	}
+
+	records, err := r.Records(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load withdrawals: %w", err)
+	}
+
+	// 2. prove or finalize each withdrawal that is not done
+	for _, rec := range records {
+		err := r.advance(ctx, rec)
+		if err != nil {
+			rec.Error = err.Error()
+			// a revert may mean someone else proved or finalized the
+			// withdrawal first, so re-read its status before giving up
+			if strings.Contains(err.Error(), "execution reverted") {
+				if err := r.recheck(ctx, rec); err != nil {
+					r.Opts.Logger.Warn("Failed to get withdrawal status", "withdrawal", rec.Withdrawal.Hash.Hex(), "error", err)
+				}
+			}
+			r.Opts.Logger.Warn("Failed to relay withdrawal", "withdrawal", rec.Withdrawal.Hash.Hex(), "state", rec.State, "error", err)
+		}
+
+		if err := r.saveRecord(ctx, rec); err != nil {
+			return fmt.Errorf("failed to save withdrawal: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Requeue moves a failed withdrawal back to pending, so it is relayed again
+// on the next run. The relayer must be stopped while requeuing, as it holds
+// the store open.
+func (r *Relayer) Requeue(ctx context.Context, hash common.Hash) error {
+	rec, err := r.Record(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if rec.State != StateFailed {
+		return fmt.Errorf("withdrawal %s is %s, only failed withdrawals can be requeued", hash.Hex(), rec.State)
+	}
+
+	rec.State = StatePending
+	rec.Error = ""
+	return r.saveRecord(ctx, rec)
+}
+
+// scan tracks every allowed withdrawal initiated since the last scanned L2
+// block.
+func (r *Relayer) scan(ctx context.Context) error {
+	height, err := r.LightLink.GetHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get L2 height: %w", err)
+	}
+
+	start, err := r.loadLastScanned(ctx)
+	if errors.Is(err, node.ErrNotFound) {
+		start = r.Opts.StartHeight
+		if start == 0 {
+			start = height
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to load last scanned block: %w", err)
+	} else {
+		start++
+	}
+
+	for from := start; from <= height; from += maxScanRange {
+		to := min(from+maxScanRange-1, height)
+
+		withdrawals, err := r.GetWithdrawalsInRange(ctx, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to get withdrawals from L2 blocks %d to %d: %w", from, to, err)
+		}
+		for _, w := range withdrawals {
+			_, err := r.Record(ctx, w.Hash)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, node.ErrNotFound) {
+				return fmt.Errorf("failed to load withdrawal %s: %w", w.Hash.Hex(), err)
+			}
+			if !r.isAllowed(w) {
+				r.Opts.Logger.Debug("Withdrawal not in allowlist, skipping", "withdrawal", w.Hash.Hex(), "sender", w.Tx.Sender.Hex(), "target", w.Tx.Target.Hex())
+				continue
+			}
+
+			r.Opts.Logger.Info("Found withdrawal to relay", "withdrawal", w.Hash.Hex(), "l2TxHash", w.L2TxHash.Hex(), "l2Height", w.L2Height)
+			if err := r.saveRecord(ctx, &Record{Withdrawal: w, State: StatePending}); err != nil {
+				return fmt.Errorf("failed to save withdrawal %s: %w", w.Hash.Hex(), err)
+			}
+		}
+
+		if err := r.saveLastScanned(ctx, to); err != nil {
+			return fmt.Errorf("failed to save last scanned block: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// isAllowed returns true if the withdrawal sender and target are in the
+// allowlist.
+func (r *Relayer) isAllowed(w *node.Withdrawal) bool {
+	if len(r.Opts.Senders) > 0 && !slices.Contains(r.Opts.Senders, w.Tx.Sender) {
+		return false
+	}
+	if len(r.Opts.Targets) > 0 && !slices.Contains(r.Opts.Targets, w.Tx.Target) {
+		return false
+	}
+	return true
+}
+
+// advance reads the withdrawal status from LightLinkPortal.sol, then proves
+// it once it is included in a rollup block, or finalizes it once the rollup
+// block it was proven against is past the challenge window.
+func (r *Relayer) advance(ctx context.Context, rec *Record) error {
+	w := rec.Withdrawal
+	log := r.Opts.Logger.With("withdrawal", w.Hash.Hex())
+
+	// 1. refresh the status, the withdrawal may have been proven or
+	// finalized by someone else
+	status, err := r.GetWithdrawalStatus(ctx, w)
+	if err != nil {
+		return fmt.Errorf("failed to get withdrawal status: %w", err)
+	}
+
+	switch {
+	case status.Finalized:
+		rec.State = StateFinalized
+
+	// 2. finalize once past the challenge window
+	case status.ReadyToFinalize:
+		log.Info("Finalizing withdrawal", "rblockIndex", status.L2OutputIndex)
+		tx, err := r.FinalizeWithdrawal(ctx, w)
+		if err != nil {
+			return fmt.Errorf("failed to finalize withdrawal: %w", err)
+		}
+		rec.FinalizeTx = tx.Hash()
+		if err := r.wait(ctx, tx.Hash()); err != nil {
+			return fmt.Errorf("failed to finalize withdrawal: %w", err)
+		}
+		log.Info("Finalized withdrawal", "tx", tx.Hash().Hex())
+		rec.State = StateFinalized
+
+	case status.Proven:
+		rec.State = StateProven
+
+	// 3. prove once a rollup block includes the withdrawal
+	case status.RolledUp:
+		log.Info("Proving withdrawal", "rblockIndex", status.RollupIndex)
+		tx, err := r.ProveWithdrawal(ctx, w)
+		if err != nil {
+			return fmt.Errorf("failed to prove withdrawal: %w", err)
+		}
+		rec.ProveTx = tx.Hash()
+		if err := r.wait(ctx, tx.Hash()); err != nil {
+			return fmt.Errorf("failed to prove withdrawal: %w", err)
+		}
+		log.Info("Proved withdrawal", "tx", tx.Hash().Hex())
+		rec.State = StateProven
+
+	default:
+		rec.State = StatePending
+	}
+
+	rec.Error = ""
+	return nil
+}
+
+// recheck re-reads the status of a withdrawal whose tx reverted. It moves
+// on if someone else proved or finalized the withdrawal, otherwise it is
+// failed until an operator requeues it.
+func (r *Relayer) recheck(ctx context.Context, rec *Record) error {
+	status, err := r.GetWithdrawalStatus(ctx, rec.Withdrawal)
+	if err != nil {
+		return err
+	}
+
+	state := StatePending
+	switch {
+	case status.Finalized:
+		state = StateFinalized
+	case status.Proven:
+		state = StateProven
+	}
+	if state == rec.State {
+		rec.State = StateFailed
+		return nil
+	}
+
+	rec.State = state
+	rec.Error = ""
+	return nil
+}
+
+// wait waits for the tx and returns an error if it failed, leaving the
+// withdrawal in its current state to be retried.
+func (r *Relayer) wait(ctx context.Context, txHash common.Hash) error {
+	receipt, err := r.Ethereum.Wait(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to wait for tx %s: %w", txHash.Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("tx %s failed", txHash.Hex())
+	}
+	return nil
+}
+
+// Records returns every withdrawal the relayer has not finished relaying,
+// keyed by withdrawal hash.
+func (r *Relayer) Records(ctx context.Context) (map[common.Hash]*Record, error) {
+	open, err := r.loadOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[common.Hash]*Record, len(open))
+	for _, hash := range open {
+		rec, err := r.Record(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load withdrawal %s: %w", hash.Hex(), err)
+		}
+		records[hash] = rec
+	}
+	return records, nil
+}
+
+// Record returns the withdrawal with the given hash, whether or not it is
+// done, or node.ErrNotFound if the relayer has not seen it.
+func (r *Relayer) Record(ctx context.Context, hash common.Hash) (*Record, error) {
+	if r.Store == nil {
+		rec, ok := r.records[hash]
+		if !ok {
+			return nil, node.ErrNotFound
+		}
+		return rec, nil
+	}
+
+	buf, err := r.Store.Get(ctx, withdrawalKey(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{}
+	if err := json.Unmarshal(buf, rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal withdrawal: %w", err)
+	}
+	return rec, nil
+}
+
+// saveRecord persists the withdrawal under its own key, and adds it to or
+// removes it from the open withdrawals, so finished ones are no longer
+// loaded on every run.
+func (r *Relayer) saveRecord(ctx context.Context, rec *Record) error {
+	hash := rec.Withdrawal.Hash
+	if r.Store == nil {
+		r.records[hash] = rec
+	} else if err := r.Store.Put(ctx, withdrawalKey(hash), utils.MustJsonMarshal(rec)); err != nil {
+		return err
+	}
+
+	open, err := r.loadOpen(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.Index(open, hash)
+	switch {
+	case rec.Done() && i >= 0:
+		open = slices.Delete(open, i, i+1)
+	case !rec.Done() && i < 0:
+		open = append(open, hash)
+	default:
+		return nil
+	}
+	return r.saveOpen(ctx, open)
+}
+
+// loadOpen returns the hashes of the withdrawals not done yet.
+func (r *Relayer) loadOpen(ctx context.Context) ([]common.Hash, error) {
+	if r.Store == nil {
+		return slices.Clone(r.open), nil
+	}
+
+	buf, err := r.Store.Get(ctx, openKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	open := []common.Hash{}
+	if err := json.Unmarshal(buf, &open); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal open withdrawals: %w", err)
+	}
+	return open, nil
+}
+
+// saveOpen persists the hashes of the withdrawals not done yet.
+func (r *Relayer) saveOpen(ctx context.Context, open []common.Hash) error {
+	if r.Store == nil {
+		r.open = open
+		return nil
+	}
+
+	return r.Store.Put(ctx, openKey, utils.MustJsonMarshal(open))
+}
+
+// loadLastScanned returns the last L2 block scanned for withdrawals, or
+// node.ErrNotFound if the relayer has not run before.
+func (r *Relayer) loadLastScanned(ctx context.Context) (uint64, error) {
+	if r.Store == nil {
+		if r.lastScanned == nil {
+			return 0, node.ErrNotFound
+		}
+		return *r.lastScanned, nil
+	}
+
+	buf, err := r.Store.Get(ctx, lastScannedKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("invalid last scanned block")
+	}
+
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// saveLastScanned persists the last L2 block scanned for withdrawals.
+func (r *Relayer) saveLastScanned(ctx context.Context, block uint64) error {
+	r.lastScanned = &block
+	if r.Store == nil {
+		return nil
+	}
+
+	return r.Store.Put(ctx, lastScannedKey, binary.BigEndian.AppendUint64(nil, block))
+}
@@ -11,6 +11,8 @@ import (
 type Block struct {
 	*canonicalStateChainContract.CanonicalStateChainHeader
 	Bundles []*node.Bundle
+
+	candidate *Candidate // set if the block was built by this publisher
 }
 
 func (b *Block) CelestiaHeights() []uint64 {
@@ -0,0 +1,552 @@
+package rollup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hummingbird/node"
+	"hummingbird/utils"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"hummingbird/node/lightlink/types"
+
+	canonicalStateChainContract "hummingbird/node/contracts/CanonicalStateChain.sol"
+)
+
+// candidateKey is the local store key of the rollup block candidate currently
+// being published.
+var candidateKey = []byte("candidate")
+
+// Stage is the last completed step of a rollup block candidate.
+type Stage uint8
+
+const (
+	StageNone        Stage = iota // No candidate is in progress.
+	StageFetched                  // The L2 bundles have been fetched and stored locally.
+	StagePublished                // Every bundle has been published to Celestia.
+	StageHeaderBuilt              // The rollup header has been built and hashed.
+	StageTxSent                   // The rollup header has been pushed to L1.
+	StageTxConfirmed              // The L1 tx has been mined.
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageNone:
+		return "None"
+	case StageFetched:
+		return "Fetched"
+	case StagePublished:
+		return "Published"
+	case StageHeaderBuilt:
+		return "HeaderBuilt"
+	case StageTxSent:
+		return "TxSent"
+	case StageTxConfirmed:
+		return "TxConfirmed"
+	default:
+		return "Unknown"
+	}
+}
+
+// BundleRange is the inclusive range of L2 blocks in a bundle.
+type BundleRange struct {
+	Start uint64
+	End   uint64
+	Hash  common.Hash // Hash of the last block in the range.
+}
+
+// Candidate is a rollup block that is in the process of being published.
+// It is persisted to the local store after every completed step, so that
+// a restarted publisher can resume where it left off instead of paying to
+// publish the same bundles to Celestia again.
+type Candidate struct {
+	Stage    Stage
+	Epoch    uint64
+	PrevHash common.Hash
+	Bundles  []BundleRange
+	Pointers []*node.CelestiaPointer // Pointers[i] is the Celestia pointer of Bundles[i], once published.
+	Fees     []*node.CelestiaFee     // Fees paid to Celestia, one per pay for blobs tx.
+	Header   *canonicalStateChainContract.CanonicalStateChainHeader
+	Hash     common.Hash
+	TxHash   common.Hash
+	TxNonce  uint64        // Nonce of the L1 tx, shared by its replacements.
+	Replaced []common.Hash // Hashes of the replacements of the L1 tx, in the order sent.
+
+	bundles []*node.Bundle // not persisted, loaded from the store or lightlink on resume
+}
+
+// DAFee returns the total Celestia fee paid to publish the candidates bundles.
+func (c *Candidate) DAFee() *node.CelestiaFee {
+	total := &node.CelestiaFee{}
+	for _, fee := range c.Fees {
+		if fee == nil {
+			continue
+		}
+		total.GasLimit += fee.GasLimit
+		total.GasUsed += fee.GasUsed
+		total.Amount += fee.Amount
+	}
+	if total.GasLimit > 0 {
+		total.GasPrice = float64(total.Amount) / float64(total.GasLimit)
+	}
+	return total
+}
+
+// txHashes returns the hashes of the candidates L1 tx and its replacements.
+func (c *Candidate) txHashes() []common.Hash {
+	return append([]common.Hash{c.TxHash}, c.Replaced...)
+}
+
+// Block returns the rollup block for the candidate. The header is only set
+// once the candidate has reached StageHeaderBuilt.
+func (c *Candidate) Block() *Block {
+	return &Block{CanonicalStateChainHeader: c.Header, Bundles: c.bundles, candidate: c}
+}
+
+// loadCandidate returns the candidate persisted in the local store, or nil if
+// there is none.
+func (r *Rollup) loadCandidate(ctx context.Context) (*Candidate, error) {
+	if !r.Opts.Store {
+		return nil, nil
+	}
+
+	buf, err := r.Node.Store.Get(ctx, candidateKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate from store: %w", err)
+	}
+
+	c := &Candidate{}
+	if err := json.Unmarshal(buf, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal candidate: %w", err)
+	}
+
+	return c, nil
+}
+
+// saveCandidate persists the candidate to the local store.
+func (r *Rollup) saveCandidate(ctx context.Context, c *Candidate) error {
+	if !r.Opts.Store {
+		return nil
+	}
+
+	if err := r.Node.Store.Put(ctx, candidateKey, utils.MustJsonMarshal(c)); err != nil {
+		return fmt.Errorf("failed to store candidate: %w", err)
+	}
+
+	return nil
+}
+
+// resumeCandidate loads the persisted candidate and checks it still extends
+// the current rollup head. It returns nil if there is no candidate to resume.
+func (r *Rollup) resumeCandidate(ctx context.Context) (*Candidate, error) {
+	log := r.Opts.Logger.With("func", "resumeCandidate")
+
+	c, err := r.loadCandidate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.Stage == StageNone || c.Stage == StageTxConfirmed {
+		return nil, nil
+	}
+
+	head, err := r.Ethereum.GetRollupHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup head: %w", err)
+	}
+	headHash, err := r.Ethereum.HashHeader(ctx, &head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash rollup head: %w", err)
+	}
+
+	// the candidate has already been included on L1
+	if c.Stage >= StageHeaderBuilt && c.Hash == headHash {
+		log.Info("Candidate rollup block is already the rollup head", "hash", c.Hash.Hex(), "stage", c.Stage)
+		c.Stage = StageTxConfirmed
+		return nil, r.saveCandidate(ctx, c)
+	}
+
+	// the rollup chain has moved on without the candidate
+	if c.PrevHash != headHash {
+		log.Warn("Discarding stale candidate rollup block", "stage", c.Stage, "prevHash", c.PrevHash.Hex(), "head", headHash.Hex())
+		return nil, r.Node.Store.Delete(ctx, candidateKey)
+	}
+
+	// reload the candidates bundles
+	c.bundles = make([]*node.Bundle, 0, len(c.Bundles))
+	for _, br := range c.Bundles {
+		bundle, err := r.loadBundle(ctx, br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reload bundle %d-%d: %w", br.Start, br.End, err)
+		}
+		if bundle.Size() == 0 || bundle.Blocks[0].NumberU64() != br.Start || bundle.Height() != br.End || bundle.Blocks[len(bundle.Blocks)-1].Hash() != br.Hash {
+			log.Warn("Discarding candidate rollup block, reloaded bundle does not match", "start", br.Start, "end", br.End)
+			return nil, r.Node.Store.Delete(ctx, candidateKey)
+		}
+		c.bundles = append(c.bundles, bundle)
+	}
+
+	log.Info("Resuming candidate rollup block", "stage", c.Stage, "bundles", len(c.Bundles), "published", len(c.Pointers), "tx", c.TxHash.Hex())
+	return c, nil
+}
+
+// loadBundle returns the candidates bundle over the range, from the store
+// if it was stored when fetched, or from LightLink otherwise. A stored bundle
+// is packed again, so it is cut short like a fetched one if it is now over
+// Opts.MaxBlobSize.
+func (r *Rollup) loadBundle(ctx context.Context, br BundleRange) (*node.Bundle, error) {
+	if r.Opts.Store {
+		bundle, err := r.Node.Store.GetBundle(ctx, br.Start, br.End)
+		if err == nil {
+			r.Opts.Logger.Info("Loaded bundle from local database", "from", br.Start, "to", br.End, "bundle_size", bundle.Size())
+			return node.PackBundle(bundle.Blocks, r.Opts.MaxBlobSize)
+		}
+		r.Opts.Logger.Info("Failed to get bundle from local database, attempting to pull via RPC", "error", err)
+	}
+
+	return r.fetchBundle(ctx, br.Start, br.End)
+}
+
+// newCandidate fetches the next bundles of L2 blocks and returns them as a
+// new candidate in StageFetched.
+func (r *Rollup) newCandidate(ctx context.Context) (*Candidate, error) {
+	// 0. fetch the current epoch = eth height
+	epoch, err := r.Ethereum.GetHeight(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: Failed to get current epoch: %w", err)
+	}
+
+	// 1. fetch the confirmed ll height
+	llHeight, err := r.confirmedL2Height(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: Failed to get current llheight: %w", err)
+	}
+
+	// 2. fetch the last rollup header, and the last rolled up L2 block
+	head, err := r.Ethereum.GetRollupHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: Failed to get current head: %w", err)
+	}
+	if llHeight <= head.L2Height {
+		return nil, fmt.Errorf("createNextBlock: No confirmed L2 blocks after the rollup head at %d, llheight %d", head.L2Height, llHeight)
+	}
+	prevBlock, err := r.lastRolledUpBlock(ctx, head)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: %w", err)
+	}
+
+	// 3. calculate the number of bundles and blocks to fetch
+	maxBundles, _, err := r.limits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: %w", err)
+	}
+	maxBlocks, err := r.blocksPerRollup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: %w", err)
+	}
+	blocksToFetch := min(maxBlocks, llHeight-head.L2Height)
+
+	// 4. calc prevHash from the last rollup header
+	prevHash, err := r.Ethereum.HashHeader(ctx, &head)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: Failed to get current prevHash: %w", err)
+	}
+
+	// 5. fetch the next bundles of blocks from ll
+	fetchTarget := head.L2Height + blocksToFetch
+	fetchStart := head.L2Height + 1
+
+	bundles, err := r.fetchBundles(ctx, fetchStart, fetchTarget, maxBundles)
+	if err != nil {
+		return nil, fmt.Errorf("createNextBlock: Failed to fetch bundles: %w", err)
+	}
+
+	// 6. validate the bundles form a chain from the last rolled up block to
+	// a block still on the canonical chain
+	err = ValidateBundles(bundles, head.L2Height)
+	if err == nil {
+		err = ValidateExtends(bundles, prevBlock)
+	}
+	if err == nil {
+		err = r.checkCanonical(ctx, bundles)
+	}
+	if err != nil {
+		if errors.Is(err, ErrL2Reorg) {
+			r.discardBundles(ctx, bundles)
+		}
+		return nil, fmt.Errorf("createNextBlock: Failed to validate bundles: %w", err)
+	}
+
+	c := &Candidate{
+		Stage:    StageFetched,
+		Epoch:    epoch,
+		PrevHash: prevHash,
+		Bundles:  make([]BundleRange, len(bundles)),
+		Pointers: make([]*node.CelestiaPointer, 0, len(bundles)),
+		bundles:  bundles,
+	}
+	for i, bundle := range bundles {
+		c.Bundles[i] = BundleRange{Start: bundle.Blocks[0].NumberU64(), End: bundle.Height(), Hash: bundle.Blocks[len(bundle.Blocks)-1].Hash()}
+	}
+
+	r.Opts.Logger.Info("Fetched candidate rollup block", "bundles", len(bundles), "l2_blocks", bundles[len(bundles)-1].Height()-head.L2Height, "ll_height", llHeight, "ll_epoch", epoch)
+	return c, r.saveCandidate(ctx, c)
+}
+
+// lastRolledUpBlock returns the L2 block at the height of the rollup head,
+// checking it is the block the heads output root commits to. If it is not,
+// LightLink has reorged below the rollup head, which re-fetching can not fix.
+func (r *Rollup) lastRolledUpBlock(ctx context.Context, head canonicalStateChainContract.CanonicalStateChainHeader) (*types.Block, error) {
+	block, err := r.LightLink.GetBlock(ctx, head.L2Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last rolled up L2 block %d: %w", head.L2Height, err)
+	}
+
+	// the genesis header does not commit to an output
+	if head.OutputRoot == [32]byte{} {
+		return block, nil
+	}
+
+	output, err := r.LightLink.GetOutputV0(ctx, block.Header())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output of L2 block %d: %w", head.L2Height, err)
+	}
+	if output.Root() != common.Hash(head.OutputRoot) {
+		return nil, fmt.Errorf("L2 block %d %s does not match the output root %s of the rollup head, layer 2 has reorged below the rollup head", head.L2Height, block.Hash().Hex(), common.Hash(head.OutputRoot).Hex())
+	}
+
+	return block, nil
+}
+
+// ValidateExtends checks the first block of the bundles is the child of the
+// last rolled up block, prev.
+func ValidateExtends(bundles []*node.Bundle, prev *types.Block) error {
+	if len(bundles) == 0 || bundles[0].Size() == 0 {
+		return fmt.Errorf("bundles are empty")
+	}
+	if first := bundles[0].Blocks[0]; first.ParentHash() != prev.Hash() {
+		return fmt.Errorf("block %d is not the child of the last rolled up block %s: %w", first.NumberU64(), prev.Hash().Hex(), ErrL2Reorg)
+	}
+	return nil
+}
+
+// checkCanonical checks the last block of the bundles is still the block at
+// its height on LightLink. As the bundles are a chain of blocks, the rest of
+// them are too.
+func (r *Rollup) checkCanonical(ctx context.Context, bundles []*node.Bundle) error {
+	last := bundles[len(bundles)-1].Last()
+	block, err := r.LightLink.GetBlock(ctx, last.NumberU64())
+	if err != nil {
+		return fmt.Errorf("failed to get L2 block %d: %w", last.NumberU64(), err)
+	}
+	if block.Hash() != last.Hash() {
+		return fmt.Errorf("L2 block %d is %s, expected %s: %w", last.NumberU64(), block.Hash().Hex(), last.Hash().Hex(), ErrL2Reorg)
+	}
+	return nil
+}
+
+// discardBundles deletes the bundles from the local store, so that they are
+// fetched again from LightLink.
+func (r *Rollup) discardBundles(ctx context.Context, bundles []*node.Bundle) {
+	if !r.Opts.Store {
+		return
+	}
+
+	for _, bundle := range bundles {
+		if bundle.Size() == 0 {
+			continue
+		}
+		from, to := bundle.Blocks[0].NumberU64(), bundle.Height()
+		if err := r.Node.Store.DeleteBundle(ctx, from, to); err != nil {
+			r.Opts.Logger.Error("Failed to delete reorged bundle from local database", "from", from, "to", to, "error", err)
+		}
+	}
+}
+
+// discardCandidate deletes the candidate and its bundles, after its L2
+// blocks were reorged out of the chain.
+func (r *Rollup) discardCandidate(ctx context.Context, c *Candidate) error {
+	r.discardBundles(ctx, c.bundles)
+	if !r.Opts.Store {
+		return nil
+	}
+	return r.Node.Store.Delete(ctx, candidateKey)
+}
+
+// publishCandidate publishes the candidates bundles to Celestia that have
+// not already been published, packing as many as fit into each pay for
+// blobs tx, and persisting the pointers after each tx.
+func (r *Rollup) publishCandidate(ctx context.Context, c *Candidate) error {
+	if c.Stage >= StagePublished {
+		return nil
+	}
+
+	r.Opts.Logger.Info("Publishing bundles to Celestia", "bundles", len(c.bundles), "already_published", len(c.Pointers))
+	// each tx is only returned once included, so the next one does not
+	// need to wait to avoid 'incorrect account sequence' errors
+	for len(c.Pointers) < len(c.bundles) {
+		bundles := make([]node.Bundle, 0, len(c.bundles)-len(c.Pointers))
+		for _, bundle := range c.bundles[len(c.Pointers):] {
+			bundles = append(bundles, *bundle)
+		}
+
+		pointers, fee, err := r.Celestia.PublishBundles(ctx, bundles)
+		if err != nil {
+			return fmt.Errorf("createNextBlock: Failed to publish bundles: %w", err)
+		}
+		r.Opts.Logger.Debug("Published bundles to Celestia", "gas_price", fee.GasPrice, "fee_tia", fee.TIA(), "first_bundle", len(c.Pointers), "bundles", len(pointers), "celestia_height", pointers[0].Height, "celestia_tx", pointers[0].TxHash.Hex())
+
+		c.Pointers = append(c.Pointers, pointers...)
+		c.Fees = append(c.Fees, fee)
+		if err := r.saveCandidate(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	c.Stage = StagePublished
+	return r.saveCandidate(ctx, c)
+}
+
+// buildCandidateHeader builds and hashes the rollup header for a published
+// candidate.
+func (r *Rollup) buildCandidateHeader(ctx context.Context, c *Candidate) error {
+	if c.Stage >= StageHeaderBuilt {
+		return nil
+	}
+
+	if len(c.bundles) == 0 {
+		return fmt.Errorf("createNextBlock: No bundles to publish")
+	}
+
+	// 8. create the rollup header
+	output, err := r.LightLink.GetOutputV0(ctx, c.bundles[len(c.bundles)-1].Last().Header())
+	if err != nil {
+		return fmt.Errorf("createNextBlock: Failed to get output: %w", err)
+	}
+
+	pointers := make([]canonicalStateChainContract.CanonicalStateChainCelestiaPointer, len(c.Pointers))
+	for i, pointer := range c.Pointers {
+		pointers[i] = canonicalStateChainContract.CanonicalStateChainCelestiaPointer{
+			Height:     pointer.Height,
+			ShareStart: big.NewInt(int64(pointer.ShareStart)),
+			ShareLen:   uint16(pointer.ShareLen),
+		}
+	}
+
+	header := &canonicalStateChainContract.CanonicalStateChainHeader{
+		Epoch:            c.Epoch,
+		L2Height:         c.bundles[len(c.bundles)-1].Height(),
+		PrevHash:         c.PrevHash,
+		OutputRoot:       output.Root(),
+		CelestiaPointers: pointers,
+	}
+
+	// 9. calculate the hash of the header
+	hash, err := r.Ethereum.HashHeader(ctx, header)
+	if err != nil {
+		return fmt.Errorf("createNextBlock: Failed to hash header: %w", err)
+	}
+
+	// 10. Optionally store the header in the local database
+	if r.Opts.Store {
+		key := append([]byte("rheader_"), hash[:]...)
+		if err := r.Node.Store.Put(ctx, key, utils.MustJsonMarshal(header)); err != nil {
+			return fmt.Errorf("createNextBlock: Failed to store header: %w", err)
+		}
+	}
+
+	// 11. Optionally store the Celestia fees paid for the block
+	if r.Opts.Store {
+		key := append([]byte("dafee_"), hash[:]...)
+		if err := r.Node.Store.Put(ctx, key, utils.MustJsonMarshal(c.DAFee())); err != nil {
+			return fmt.Errorf("createNextBlock: Failed to store Celestia fees: %w", err)
+		}
+	}
+
+	// 12. Optionally store the Celestia pointers, with the blob commitments,
+	// in the local database
+	if r.Opts.Store {
+		for _, pointer := range c.Pointers {
+			pointer.RollupHash = hash
+		}
+		if err := r.Node.Store.PutDAPointers(ctx, hash, c.Pointers); err != nil {
+			return fmt.Errorf("createNextBlock: Failed to store Celestia pointers: %w", err)
+		}
+	}
+
+	c.Header = header
+	c.Hash = hash
+	c.Stage = StageHeaderBuilt
+	return r.saveCandidate(ctx, c)
+}
+
+// markCandidateSent records the L1 tx the candidate was pushed in.
+func (r *Rollup) markCandidateSent(ctx context.Context, c *Candidate, txHash common.Hash, nonce uint64) error {
+	c.TxHash = txHash
+	c.TxNonce = nonce
+	c.Replaced = nil
+	c.Stage = StageTxSent
+	return r.saveCandidate(ctx, c)
+}
+
+// markCandidateReplaced records a replacement of the candidates L1 tx, so it
+// is re-attached to after a restart.
+func (r *Rollup) markCandidateReplaced(ctx context.Context, c *Candidate, txHash common.Hash) error {
+	c.Replaced = append(c.Replaced, txHash)
+	return r.saveCandidate(ctx, c)
+}
+
+// markCandidateConfirmed records that the candidates L1 tx was mined.
+func (r *Rollup) markCandidateConfirmed(ctx context.Context, c *Candidate) error {
+	c.Stage = StageTxConfirmed
+	return r.saveCandidate(ctx, c)
+}
+
+// rebuildCandidateHeader rolls the candidate back to StagePublished with a
+// fresh epoch, so that the header is rebuilt, e.g. after the L1 tx reverted.
+// The published Celestia pointers are kept.
+func (r *Rollup) rebuildCandidateHeader(ctx context.Context, c *Candidate) error {
+	epoch, err := r.Ethereum.GetHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current epoch: %w", err)
+	}
+
+	c.Epoch = epoch
+	c.Header = nil
+	c.Hash = common.Hash{}
+	c.TxHash = common.Hash{}
+	c.TxNonce = 0
+	c.Replaced = nil
+	c.Stage = StagePublished
+	return r.saveCandidate(ctx, c)
+}
+
+// resetCandidateTx rolls the candidate back to StageHeaderBuilt so that the
+// header is pushed again, e.g. after the L1 tx nonce was used by another tx.
+func (r *Rollup) resetCandidateTx(ctx context.Context, c *Candidate) error {
+	c.TxHash = common.Hash{}
+	c.TxNonce = 0
+	c.Replaced = nil
+	c.Stage = StageHeaderBuilt
+	return r.saveCandidate(ctx, c)
+}
+
+// checkCandidateCanonical checks the candidates L2 blocks are still on the
+// canonical chain, discarding the candidate if not.
+func (r *Rollup) checkCandidateCanonical(ctx context.Context, c *Candidate) error {
+	err := r.checkCanonical(ctx, c.bundles)
+	if !errors.Is(err, ErrL2Reorg) {
+		return err
+	}
+
+	r.Opts.Logger.Warn("Discarding candidate rollup block, its L2 blocks were reorged", "stage", c.Stage, "published", len(c.Pointers), "error", err)
+	if err := r.discardCandidate(ctx, c); err != nil {
+		return fmt.Errorf("failed to discard reorged candidate: %w", err)
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 package rollup
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -20,18 +21,18 @@ type RollupInfo struct {
 	} `pretty:"Latest Rollup Block"`
 }
 
-func (r *Rollup) GetInfo() (*RollupInfo, error) {
+func (r *Rollup) GetInfo(ctx context.Context) (*RollupInfo, error) {
 	info := &RollupInfo{}
 
 	// get rollup height
-	rollupHeight, err := r.Ethereum.GetRollupHeight()
+	rollupHeight, err := r.Ethereum.GetRollupHeight(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rollup height: %w", err)
 	}
 	r.Opts.Logger.Info("Rollup Height", "height", rollupHeight)
 
 	// get latest rollup head
-	latestRollupHead, err := r.Ethereum.GetRollupHead()
+	latestRollupHead, err := r.Ethereum.GetRollupHead(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rollup head: %w", err)
 	}
@@ -39,7 +40,7 @@ func (r *Rollup) GetInfo() (*RollupInfo, error) {
 	r.Opts.Logger.Info("Latest Rollup Head", "head", latestRollupHead)
 
 	// hash latest rollup head
-	latestRollupHash, err := r.Ethereum.HashHeader(&latestRollupHead)
+	latestRollupHash, err := r.Ethereum.HashHeader(ctx, &latestRollupHead)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash rollup head: %w", err)
 	}
@@ -47,7 +48,7 @@ func (r *Rollup) GetInfo() (*RollupInfo, error) {
 	r.Opts.Logger.Info("Latest Rollup Hash", "hash", latestRollupHash)
 
 	// get previous rollup header
-	prevRollupHeader, err := r.Ethereum.GetRollupHeaderByHash(latestRollupHead.PrevHash)
+	prevRollupHeader, err := r.Ethereum.GetRollupHeaderByHash(ctx, latestRollupHead.PrevHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get previous rollup header: %w", err)
 	}
@@ -56,7 +57,7 @@ func (r *Rollup) GetInfo() (*RollupInfo, error) {
 	bundleSize := latestRollupHead.L2Height - prevRollupHeader.L2Height
 
 	// get genesis header
-	genesisHeader, err := r.Ethereum.GetRollupHeader(0)
+	genesisHeader, err := r.Ethereum.GetRollupHeader(ctx, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get genesis header: %w", err)
 	}
@@ -65,7 +66,7 @@ func (r *Rollup) GetInfo() (*RollupInfo, error) {
 	l2BlocksRolledUp := latestRollupHead.L2Height - genesisHeader.L2Height
 
 	// get layer 2 height
-	l2Height, err := r.LightLink.GetHeight()
+	l2Height, err := r.LightLink.GetHeight(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get layer 2 height: %w", err)
 	}
@@ -101,8 +102,8 @@ type RollupBlockInfo struct {
 	} `pretty:"Distance"`
 }
 
-func (r *Rollup) GetBlockInfo(hash common.Hash) (*RollupBlockInfo, error) {
-	info, err := r.GetInfo()
+func (r *Rollup) GetBlockInfo(ctx context.Context, hash common.Hash) (*RollupBlockInfo, error) {
+	info, err := r.GetInfo(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rollup info: %w", err)
 	}
@@ -110,31 +111,31 @@ func (r *Rollup) GetBlockInfo(hash common.Hash) (*RollupBlockInfo, error) {
 	rbi := &RollupBlockInfo{}
 
 	// get rollup header
-	header, err := r.Ethereum.GetRollupHeaderByHash(hash)
+	header, err := r.Ethereum.GetRollupHeaderByHash(ctx, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rollup header: %w", err)
 	}
 
 	// get previous rollup header
-	prevRollupHeader, err := r.Ethereum.GetRollupHeaderByHash(header.PrevHash)
+	prevRollupHeader, err := r.Ethereum.GetRollupHeaderByHash(ctx, header.PrevHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get previous rollup header: %w", err)
 	}
 
 	// hash rollup header
-	hash, err = r.Ethereum.HashHeader(&header)
+	hash, err = r.Ethereum.HashHeader(ctx, &header)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash rollup header: %w", err)
 	}
 
 	// get l2 height
-	llHeight, err := r.LightLink.GetHeight()
+	llHeight, err := r.LightLink.GetHeight(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get l2 height: %w", err)
 	}
 
 	// get l1 height
-	ethHeight, err := r.Ethereum.GetHeight()
+	ethHeight, err := r.Ethereum.GetHeight(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get l1 height: %w", err)
 	}
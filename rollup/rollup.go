@@ -1,33 +1,47 @@
 package rollup
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hummingbird/node"
+	"hummingbird/node/ethereum"
 	"hummingbird/utils"
 	"log/slog"
-	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-
-	canonicalStateChainContract "hummingbird/node/contracts/CanonicalStateChain.sol"
 )
 
 type Opts struct {
-	BundleCount uint64        // BundleCount is the number of bundles to include in each rollup block.
-	BundleSize  uint64        // BundleSize is the number of blocks to include in each bundle.
+	BundleCount uint64        // BundleCount is the max number of bundles in each rollup block, capped by CanonicalStateChain.maxPointers. 0 uses the on-chain max.
+	BundleSize  uint64        // BundleSize is the max number of blocks in each bundle, 0 for no limit other than MaxBlobSize.
+	MaxBlobSize uint64        // MaxBlobSize is the max encoded size of each bundle in bytes, bundles are packed with blocks up to it. Defaults to node.BundleSizeLimit.
+	MaxLatency  time.Duration // MaxLatency is the max time to wait for a full rollup block before rolling up the L2 blocks there are, 0 waits for a full block.
 	L1PollDelay time.Duration // PollDelay is the time to wait between polling for new blocks on the L1 rollup contract.
 	L2PollDelay time.Duration // PollDelay is the time to wait between polling for new blocks on the L2 lightlink network.
-	Logger      *slog.Logger
-	DryRun      bool // DryRun indicates whether or not to actually submit the block to the L1 rollup contract.
+
+	L2Confirmations uint64 // L2Confirmations is the number of blocks an L2 block must be buried under before it is rolled up.
+	L2BlockTag      string // L2BlockTag if set, e.g. "safe" or "finalized", only rolls up L2 blocks up to the tagged block, instead of using L2Confirmations.
+
+	Logger *slog.Logger
+	DryRun bool // DryRun indicates whether or not to actually submit the block to the L1 rollup contract.
 
 	Store bool // StoreBundles indicates whether or not to store pointer, headers & bundles in the local database.
 }
 
+// ErrL2Reorg is returned when the L2 blocks being rolled up are no longer on
+// the canonical LightLink chain. The blocks are discarded so that they are
+// fetched again, rather than publishing a fork.
+var ErrL2Reorg = errors.New("layer 2 reorg")
+
 type Rollup struct {
 	*node.Node
 	Opts *Opts
+
+	maxPointers   uint64 // CanonicalStateChain.maxPointers, read on first use
+	maxBundleSize uint64 // Challenge.maxBundleSize, read on first use
 }
 
 func NewRollup(n *node.Node, opts *Opts) *Rollup {
@@ -50,6 +64,9 @@ func NewRollup(n *node.Node, opts *Opts) *Rollup {
 	if disableStorage {
 		opts.Store = false
 	}
+	if opts.MaxBlobSize == 0 || opts.MaxBlobSize > node.BundleSizeLimit {
+		opts.MaxBlobSize = node.BundleSizeLimit
+	}
 
 	return &Rollup{Node: n, Opts: opts}
 }
@@ -58,228 +75,288 @@ func NewRollup(n *node.Node, opts *Opts) *Rollup {
 // lightlink network and pushes it to the data availability layer (Celestia).
 // It returns the new block and an error if one occurred.
 //
+// If a candidate rollup block was left part way through publishing, it is
+// resumed rather than fetching and publishing new bundles.
+//
 // Note: This function does not submit the block to the L1 rollup contract.
 // See: Rollup.SubmitBlock
-func (r *Rollup) CreateNextBlock() (*Block, error) {
-	// 0. fetch the current epoch = eth height
-	epoch, err := r.Ethereum.GetHeight()
+func (r *Rollup) CreateNextBlock(ctx context.Context) (*Block, error) {
+	c, err := r.resumeCandidate(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to get current epoch: %w", err)
+		return nil, fmt.Errorf("createNextBlock: Failed to resume candidate: %w", err)
 	}
 
-	// 1. fetch ll height
-	llHeight, err := r.LightLink.GetHeight()
-	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to get current llheight: %w", err)
+	if c == nil {
+		c, err = r.newCandidate(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// 2. fetch the last rollup header
-	head, err := r.Ethereum.GetRollupHead()
-	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to get current head: %w", err)
-	}
+	return r.advanceCandidate(ctx, c)
+}
 
-	// 3. calculate bundle size
-	blocksToFetch := r.Opts.BundleSize * r.Opts.BundleCount
-	if llHeight-head.L2Height < blocksToFetch {
-		blocksToFetch = llHeight - head.L2Height
+// advanceCandidate publishes the candidates bundles and builds its header,
+// skipping any steps that have already been completed.
+//
+// The candidates L2 blocks are checked to still be canonical before they are
+// published to Celestia, and again before the header commits them to L1. If
+// they were reorged out, the candidate is discarded and ErrL2Reorg returned.
+func (r *Rollup) advanceCandidate(ctx context.Context, c *Candidate) (*Block, error) {
+	// 7. upload the bundles to celestia
+	if c.Stage < StagePublished {
+		if err := r.checkCandidateCanonical(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.publishCandidate(ctx, c); err != nil {
+		return nil, err
 	}
 
-	// 4. calc prevHash from the last rollup header
-	prevHash, err := r.Ethereum.HashHeader(&head)
-	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to get current prevHash: %w", err)
+	// 8. build the rollup header
+	if c.Stage < StageHeaderBuilt {
+		if err := r.checkCandidateCanonical(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.buildCandidateHeader(ctx, c); err != nil {
+		return nil, err
 	}
 
-	// 5. fetch the next bundles of blocks from ll
-	fetchTarget := head.L2Height + blocksToFetch
-	fetchStart := head.L2Height + 1
+	return c.Block(), nil
+}
 
-	bundles, err := r.fetchBundles(fetchStart, fetchTarget)
-	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to fetch bundles: %w", err)
+const (
+	markSentRetries    = 3           // Number of attempts to record the tx a rollup block was submitted in.
+	markSentRetryDelay = time.Second // Delay between attempts to record the tx.
+)
+
+// SubmitBlock pushes the rollup blocks header to L1. If the tx is sent but
+// cannot be recorded on the candidate, the tx is returned with the error.
+func (b *Rollup) SubmitBlock(ctx context.Context, block *Block) (*types.Transaction, error) {
+	log := b.Opts.Logger.With("func", "SubmitBlock")
+
+	if block.candidate != nil && block.candidate.Stage >= StageTxSent {
+		return nil, fmt.Errorf("rollup block %s was already submitted in tx %s", block.candidate.Hash.Hex(), block.candidate.TxHash.Hex())
 	}
 
-	// 6. validate the bundles
-	err = ValidateBundles(bundles, head.L2Height)
+	tx, err := b.Ethereum.PushRollupHead(ctx, block.CanonicalStateChainHeader)
 	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to validate bundles: %w", err)
+		log.Error("Failed to push rollup head", "error", err)
+		return nil, err
 	}
 
-	// 7. upload the bundle to celestia
-	r.Opts.Logger.Info("Publishing bundles to Celestia", "bundles", len(bundles), "bundles_size", fetchStart-head.L2Height-1, "ll_height", llHeight, "ll_epoch", epoch)
-	pointers := make([]canonicalStateChainContract.CanonicalStateChainCelestiaPointer, 0)
-	for i, bundle := range bundles {
-		pointer, gasPrice, err := r.Celestia.PublishBundle(*bundle)
+	// the tx is already sent, so the write is retried before giving up, as a
+	// restart without it would push the header again
+	if block.candidate != nil {
+		var err error
+		for i := 0; i < markSentRetries; i++ {
+			if err = b.markCandidateSent(ctx, block.candidate, tx.Hash(), tx.Nonce()); err == nil {
+				break
+			}
+			log.Warn("Failed to record rollup block tx, retrying", "tx", tx.Hash().Hex(), "attempt", i+1, "error", err)
+			if err := utils.Sleep(ctx, markSentRetryDelay); err != nil {
+				break
+			}
+		}
 		if err != nil {
-			return nil, fmt.Errorf("createNextBlock: Failed to publish bundle: %w", err)
+			log.Error("Failed to record rollup block tx", "tx", tx.Hash().Hex(), "error", err)
+			return tx, fmt.Errorf("failed to record rollup block tx %s: %w", tx.Hash().Hex(), err)
 		}
-		r.Opts.Logger.Debug("Published bundle to Celestia", "gas_price", gasPrice, "bundle", i, "bundle_size", bundle.Size(), "celestia_tx", pointer.TxHash.Hex())
-		pointers = append(pointers, canonicalStateChainContract.CanonicalStateChainCelestiaPointer{
-			Height:     pointer.Height,
-			ShareStart: big.NewInt(int64(pointer.ShareStart)),
-			ShareLen:   uint16(pointer.ShareLen),
-		})
-
-		// Delay between publishing bundles to Celestia to mitigate 'incorrect account sequence' errors
-		time.Sleep(20 * time.Second)
 	}
 
-	if len(bundles) == 0 {
-		return nil, fmt.Errorf("createNextBlock: No bundles to publish")
-	}
+	log.Info("Submitted rollup block", "tx", tx.Hash().Hex(), "epoch", block.Epoch, "l2Height", block.L2Height, "celestiaHeights", block.CelestiaHeights())
+	return tx, nil
+}
 
-	// 8. create the rollup header
-	output, err := r.LightLink.GetOutputV0(bundles[len(bundles)-1].Last().Header())
-	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to get output: %w", err)
-	}
+// awaitBlock waits for the L1 tx the rollup block was submitted in, or any
+// of its replacements, to be mined. Replacements are recorded on the
+// candidate as they are sent, so a restarted publisher re-attaches to them.
+//
+// If the tx reverts the header is rebuilt, and if its nonce was used by some
+// other tx the header is pushed again. On any other error, e.g. a timeout,
+// the candidate keeps the tx to re-attach to.
+func (r *Rollup) awaitBlock(ctx context.Context, block *Block) (*types.Receipt, error) {
+	log := r.Opts.Logger.With("func", "awaitBlock")
 
-	header := &canonicalStateChainContract.CanonicalStateChainHeader{
-		Epoch:            epoch,
-		L2Height:         bundles[len(bundles)-1].Height(),
-		PrevHash:         prevHash,
-		OutputRoot:       output.Root(),
-		CelestiaPointers: pointers,
+	c := block.candidate
+	if c == nil || c.Stage < StageTxSent {
+		return nil, fmt.Errorf("rollup block was not submitted")
 	}
 
-	// 9. calculate the hash of the header
-	hash, err := r.Ethereum.HashHeader(header)
+	receipt, err := r.Ethereum.WaitTx(ctx, c.TxNonce, c.txHashes(), func(hash common.Hash) {
+		if err := r.markCandidateReplaced(ctx, c, hash); err != nil {
+			log.Error("Failed to record rollup block replacement tx", "tx", hash.Hex(), "error", err)
+		}
+	})
+	r.Metrics.ObserveRollupPush(receipt)
+	if errors.Is(err, ethereum.ErrNonceUsed) {
+		if err := r.resetCandidateTx(ctx, c); err != nil {
+			log.Error("Failed to reset candidate tx", "error", err)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("createNextBlock: Failed to hash header: %w", err)
+		return nil, fmt.Errorf("failed to wait for tx %s: %w", c.TxHash.Hex(), err)
 	}
 
-	// 10. Optionally store the header in the local database
-	if r.Opts.Store {
-		key := append([]byte("rheader_"), hash[:]...)
-		if err := r.Node.Store.Put(key, utils.MustJsonMarshal(header)); err != nil {
-			return nil, fmt.Errorf("createNextBlock: Failed to store header: %w", err)
+	if receipt.Status != 1 {
+		if err := r.rebuildCandidateHeader(ctx, c); err != nil {
+			log.Error("Failed to reset candidate header", "error", err)
 		}
+		return nil, fmt.Errorf("rollup block tx %s failed with status %d", receipt.TxHash.Hex(), receipt.Status)
 	}
 
-	// 11. Optionally store the Celestia pointer in the local database
-	// Required for the Celestia proof.
-	// if r.Opts.StoreCelestiaPointers {
-	// 	key := append([]byte("pointer_"), hash[:]...)
-	// 	if err := r.Node.Store.Put(key, utils.MustJsonMarshal(pointer)); err != nil {
-	// 		return nil, fmt.Errorf("createNextBlock: Failed to store Celestia pointer: %w", err)
-	// 	}
-	// }
-	// TODO: FIX THIS ^ ITS NEEDED
-
-	return &Block{CanonicalStateChainHeader: header, Bundles: bundles}, nil
-}
-
-func (b *Rollup) SubmitBlock(block *Block) (*types.Transaction, error) {
-	log := b.Opts.Logger.With("func", "SubmitBlock")
-
-	tx, err := b.Ethereum.PushRollupHead(block.CanonicalStateChainHeader)
-	if err != nil {
-		log.Error("Failed to push rollup head", "error", err)
-		return nil, err
+	if err := r.markCandidateConfirmed(ctx, c); err != nil {
+		log.Error("Failed to record rollup block confirmation", "error", err)
 	}
 
-	log.Info("Submitted rollup block", "tx", tx.Hash().Hex(), "epoch", block.Epoch, "l2Height", block.L2Height, "celestiaHeights", block.CelestiaHeights())
-	return tx, nil
+	return receipt, nil
 }
 
-func (r *Rollup) CreateAndSubmitNextBlock() (*Block, uint64, error) {
+func (r *Rollup) CreateAndSubmitNextBlock(ctx context.Context) (*Block, uint64, error) {
 	log := r.Opts.Logger.With("func", "CreateAndSubmitNextBlock")
 
 	// 1. create the next rollup block
-	block, err := r.CreateNextBlock()
+	block, err := r.CreateNextBlock(ctx)
 	if err != nil {
 		log.Error("Failed to create next block", "error", err)
 		return nil, 0, err
 	}
 
-	// 2. submit the block to the rollup contract
-	tx, err := r.SubmitBlock(block)
-	if err != nil {
-		log.Error("Failed to submit block", "error", err)
-		return nil, 0, err
+	// 2. submit the block to the rollup contract, unless it was already
+	// submitted before a restart
+	if block.candidate.Stage < StageTxSent {
+		if _, err := r.SubmitBlock(ctx, block); err != nil {
+			log.Error("Failed to submit block", "error", err)
+			return nil, 0, err
+		}
 	}
 
 	// 3. wait for the tx
-	receipt, err := r.Ethereum.Wait(tx.Hash())
+	receipt, err := r.awaitBlock(ctx, block)
 	if err != nil {
 		log.Error("Failed to wait for tx", "error", err)
 		return nil, 0, err
 	}
 
-	if receipt.Status != 1 {
-		log.Error("Transaction failed", "tx", tx.Hash().Hex(), "status", receipt.Status)
-		return nil, 0, err
-	}
-
 	// 3. wait for the block to be mined
-	h, err := r.Ethereum.GetRollupHeight()
+	h, err := r.Ethereum.GetRollupHeight(ctx)
 	if err != nil {
 		log.Error("Failed to get rollup height", "error", err)
 		return nil, 0, err
 	}
 
-	log.Info("Rollup chain updated", "rollup_l2height", block.L2Height, "bundle_size", len(block.L2Blocks()), "rollup_height", h, "epoch", block.Epoch, "tx", receipt.TxHash.Hex(), "gas_used", receipt.GasUsed)
+	log.Info("Rollup chain updated", "rollup_l2height", block.L2Height, "bundle_size", len(block.L2Blocks()), "rollup_height", h, "epoch", block.Epoch, "tx", receipt.TxHash.Hex(), "gas_used", receipt.GasUsed, "da_fee_tia", block.candidate.DAFee().TIA())
 	return block, h, nil
 }
 
-func (r *Rollup) Run() error {
+func (r *Rollup) Run(ctx context.Context) error {
 	log := r.Opts.Logger.With("func", "Run")
 
 	// get last rollup height
-	head, err := r.Ethereum.GetRollupHead()
+	head, err := r.Ethereum.GetRollupHead(ctx)
 	if err != nil {
 		log.Error("Failed to get rollup height", "error", err)
 		return err
 	}
 	log.Info("Starting rollup", "rollup_ll_height", head.L2Height, "rollup_ll_epoch", head.Epoch)
 
+	maxBundles, maxBlocks, err := r.limits(ctx)
+	if err != nil {
+		log.Error("Failed to get rollup block limits", "error", err)
+		return err
+	}
+	log.Info("Rollup block limits", "max_bundles", maxBundles, "max_l2_blocks", maxBlocks, "max_blob_size", r.Opts.MaxBlobSize)
+
 	for {
-		// 1. get next rollup target height
-		target, err := r.nextRollupTarget()
-		if err != nil {
-			log.Error("Failed to get next rollup target", "error", err)
-			return err
-		}
-		log.Debug("Estimated next rollup target", "target", target)
+		// 0. record how far the rollup is behind LightLink, as publishing a
+		// candidate can stall for a while
+		r.recordL2Lag(ctx)
+		r.Heartbeat.Beat("resume")
 
-		// 2. wait for the target height to be reached
-		err = r.awaitL2Height(target)
+		// 1. resume the last candidate rollup block if one is in progress
+		c, err := r.resumeCandidate(ctx)
 		if err != nil {
-			log.Error("Failed to await L2 height", "error", err)
+			log.Error("Failed to resume candidate rollup block", "error", err)
 			return err
 		}
-		log.Debug("Reached next rollup target", "target", target)
 
-		log.Info("Building candidate rollup block...")
+		if c == nil {
+			// 2. get next rollup target height
+			head, target, err := r.nextRollupTarget(ctx)
+			if err != nil {
+				log.Error("Failed to get next rollup target", "error", err)
+				return err
+			}
+			log.Debug("Estimated next rollup target", "target", target)
+
+			// 3. wait for the target height to be reached
+			r.Heartbeat.Beat("awaitL2Height")
+			err = r.awaitL2Height(ctx, head, target)
+			if err != nil {
+				log.Error("Failed to await L2 height", "error", err)
+				return err
+			}
+			log.Debug("Reached next rollup target", "target", target)
+
+			log.Info("Building candidate rollup block...")
+
+			// 4. fetch the bundles for the next rollup block
+			c, err = r.newCandidate(ctx)
+			if errors.Is(err, ErrL2Reorg) {
+				log.Warn("Layer 2 reorged while fetching bundles, fetching again", "error", err)
+				if err := utils.Sleep(ctx, r.Opts.L2PollDelay); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				log.Error("Failed to create next block", "error", err)
+				return err
+			}
+		}
 
-		// 3. create the next rollup block
-		block, err := r.CreateNextBlock()
+		// 5. publish the bundles and build the header
+		r.Heartbeat.Beat("publish")
+		block, err := r.advanceCandidate(ctx, c)
+		if errors.Is(err, ErrL2Reorg) {
+			log.Warn("Layer 2 reorged before the candidate was submitted, fetching again", "error", err)
+			if err := utils.Sleep(ctx, r.Opts.L2PollDelay); err != nil {
+				return err
+			}
+			continue
+		}
 		if err != nil {
 			log.Error("Failed to create next block", "error", err)
 			return err
 		}
 		log.Info("Created candidate rollup block", "epoch", block.Epoch, "l2Height", block.L2Height, "celestiaHeight", block.CelestiaHeights(), "l2_blocks", len(block.L2Blocks()))
 
-		// 4. submit the block to the rollup contract
-		tx, err := r.SubmitBlock(block)
-		if err != nil {
-			log.Error("Failed to submit block", "error", err)
-			return err
-		}
+		// 6. submit the block to the rollup contract, or re-attach to the
+		// tx it was already submitted in
+		if c.Stage < StageTxSent {
+			r.Heartbeat.Beat("submit")
+			tx, err := r.SubmitBlock(ctx, block)
+			if err != nil {
+				log.Error("Failed to submit block", "error", err)
+				return err
+			}
 
-		hash, _ := r.Ethereum.HashHeader(block.CanonicalStateChainHeader)
-		log.Info("Submitted rollup block",
-			"tx", tx.Hash().Hex(),
-			"hash", hash,
-			"epoch", block.Epoch,
-			"l2Height", block.L2Height,
-			"celestiaHeight", block.CelestiaHeights(),
-			// "daTx", block.CelestiaPointer.TxHash.Hex(),
-			"l2_blocks", len(block.L2Blocks()),
-		)
+			log.Info("Submitted rollup block",
+				"tx", tx.Hash().Hex(),
+				"hash", c.Hash,
+				"epoch", block.Epoch,
+				"l2Height", block.L2Height,
+				"celestiaHeight", block.CelestiaHeights(),
+				"l2_blocks", len(block.L2Blocks()),
+			)
+		} else {
+			log.Info("Re-attaching to submitted rollup block tx", "tx", c.TxHash.Hex(), "nonce", c.TxNonce, "replacements", len(c.Replaced), "hash", c.Hash)
+		}
 
-		_, err = r.Ethereum.Wait(tx.Hash())
+		// 7. wait for the tx to be mined
+		r.Heartbeat.Beat("wait")
+		_, err = r.awaitBlock(ctx, block)
 		if err != nil {
 			log.Error("failed to wait for tx", "error", err)
 			return err
@@ -288,38 +365,141 @@ func (r *Rollup) Run() error {
 
 }
 
-// returns the layer2 block height that will trigger the next rollup
-func (r *Rollup) nextRollupTarget() (uint64, error) {
+// returns the layer2 height of the rollup head, and the layer2 block height
+// that will trigger the next rollup
+func (r *Rollup) nextRollupTarget(ctx context.Context) (uint64, uint64, error) {
 	log := r.Opts.Logger.With("func", "nextRollupTarget")
 
-	head, err := r.Ethereum.GetRollupHead()
+	head, err := r.Ethereum.GetRollupHead(ctx)
 	if err != nil {
 		log.Error("Failed to get rollup height", "error", err)
-		return 0, err
+		return 0, 0, err
+	}
+
+	maxBlocks, err := r.blocksPerRollup(ctx)
+	if err != nil {
+		log.Error("Failed to get rollup block limits", "error", err)
+		return 0, 0, err
 	}
 
 	// get the next rollup target
-	return head.L2Height + r.Opts.BundleSize*r.Opts.BundleCount, nil
+	return head.L2Height, head.L2Height + maxBlocks, nil
+}
+
+// limits returns the max number of bundles and L2 blocks in a rollup block,
+// from the contracts, with the bundles capped by Opts.BundleCount. The
+// contract limits are read once and cached.
+func (r *Rollup) limits(ctx context.Context) (uint64, uint64, error) {
+	if r.maxPointers == 0 || r.maxBundleSize == 0 {
+		maxPointers, err := r.Ethereum.GetMaxPointers(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get max pointers: %w", err)
+		}
+		maxBundleSize, err := r.Ethereum.GetMaxBundleSize(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get max bundle size: %w", err)
+		}
+		if maxPointers == 0 || maxBundleSize == 0 {
+			return 0, 0, fmt.Errorf("invalid on-chain limits, max pointers %d, max bundle size %d", maxPointers, maxBundleSize)
+		}
+		r.maxPointers, r.maxBundleSize = uint64(maxPointers), maxBundleSize
+	}
+
+	maxBundles := r.maxPointers
+	if r.Opts.BundleCount > 0 {
+		maxBundles = min(r.Opts.BundleCount, maxBundles)
+	}
+	return maxBundles, r.maxBundleSize, nil
+}
+
+// blocksPerRollup returns the number of L2 blocks in a full rollup block.
+// With no BundleSize the number depends on the size of the blocks, so a
+// rollup block is only full at the on-chain max.
+func (r *Rollup) blocksPerRollup(ctx context.Context) (uint64, error) {
+	maxBundles, maxBlocks, err := r.limits(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if r.Opts.BundleSize == 0 {
+		return maxBlocks, nil
+	}
+	return min(r.Opts.BundleSize*maxBundles, maxBlocks), nil
 }
 
-func (r *Rollup) awaitL2Height(h uint64) error {
+// recordL2Lag records the number of LightLink blocks not yet rolled up.
+func (r *Rollup) recordL2Lag(ctx context.Context) {
+	if r.Metrics == nil {
+		return
+	}
+
+	head, err := r.Ethereum.GetRollupHead(ctx)
+	if err != nil {
+		r.Opts.Logger.Debug("Failed to get rollup head for L2 lag", "error", err)
+		return
+	}
+	llHeight, err := r.LightLink.GetHeight(ctx)
+	if err != nil {
+		r.Opts.Logger.Debug("Failed to get layer 2 height for L2 lag", "error", err)
+		return
+	}
+	r.Metrics.SetL2Lag(llHeight, head.L2Height)
+}
+
+// confirmedL2Height returns the height of the last layer2 block that is
+// safe to roll up, either the block with the L2BlockTag, or the block
+// L2Confirmations below the tip.
+func (r *Rollup) confirmedL2Height(ctx context.Context) (uint64, error) {
+	if r.Opts.L2BlockTag != "" {
+		return r.LightLink.GetHeightByTag(ctx, r.Opts.L2BlockTag)
+	}
+
+	llHeight, err := r.LightLink.GetHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if llHeight < r.Opts.L2Confirmations {
+		return 0, nil
+	}
+	return llHeight - r.Opts.L2Confirmations, nil
+}
 
+// awaitL2Height waits for the confirmed layer2 height to pass h, recording
+// the lag behind the rollup head as it goes. The heartbeat only beats while
+// the layer2 height advances, so a stalled layer2 shows as a stuck loop.
+//
+// If Opts.MaxLatency passes first, it returns once there is any layer2
+// block after the head, so a partial rollup block is published when
+// traffic is low.
+func (r *Rollup) awaitL2Height(ctx context.Context, head, h uint64) error {
+	var last uint64
+	start := time.Now()
 	for {
-		llHeight, err := r.LightLink.GetHeight()
+		llHeight, err := r.confirmedL2Height(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to get layer 2 height: %w", err)
 		}
+		r.Metrics.SetL2Lag(llHeight, head)
+		if llHeight > last {
+			r.Heartbeat.Beat("awaitL2Height")
+			last = llHeight
+		}
 
 		if llHeight > h {
 			return nil
 		}
+		if r.Opts.MaxLatency > 0 && llHeight > head && time.Since(start) >= r.Opts.MaxLatency {
+			r.Opts.Logger.Info("Max latency reached, rolling up a partial block", "ll_height", llHeight, "target", h, "waited", time.Since(start))
+			return nil
+		}
 
-		time.Sleep(r.Opts.L2PollDelay)
+		if err := utils.Sleep(ctx, r.Opts.L2PollDelay); err != nil {
+			return err
+		}
 	}
 }
 
-func (r *Rollup) GetBlockByHash(hash common.Hash) (*Block, error) {
-	header, err := r.Ethereum.GetRollupHeaderByHash(hash)
+func (r *Rollup) GetBlockByHash(ctx context.Context, hash common.Hash) (*Block, error) {
+	header, err := r.Ethereum.GetRollupHeaderByHash(ctx, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rollup header: %w", err)
 	}
@@ -332,7 +512,7 @@ func (r *Rollup) GetBlockByHash(hash common.Hash) (*Block, error) {
 			ShareLen:   uint64(header.CelestiaPointers[i].ShareLen),
 		}
 
-		shares, err := r.Celestia.GetSharesByPointer(pointer)
+		shares, err := r.Celestia.GetSharesByPointer(ctx, pointer)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get shares by pointer: %w", err)
 		}
@@ -348,48 +528,48 @@ func (r *Rollup) GetBlockByHash(hash common.Hash) (*Block, error) {
 	return &Block{CanonicalStateChainHeader: &header, Bundles: bundles}, nil
 }
 
-func (r *Rollup) fetchBundles(fetchStart, fetchTarget uint64) ([]*node.Bundle, error) {
+// fetchBundles fetches up to maxBundles bundles of the blocks from
+// fetchStart to fetchTarget. Each bundle is packed with as many blocks as
+// fit in Opts.MaxBlobSize, up to Opts.BundleSize, and the next bundle
+// starts after the last block that fit.
+func (r *Rollup) fetchBundles(ctx context.Context, fetchStart, fetchTarget, maxBundles uint64) ([]*node.Bundle, error) {
 	bundles := make([]*node.Bundle, 0)
 
-	for fetchStart < fetchTarget && uint64(len(bundles)) < r.Opts.BundleCount {
+	for fetchStart <= fetchTarget && uint64(len(bundles)) < maxBundles {
 		from := fetchStart
-		to := fetchStart + r.Opts.BundleSize - 1
-		if to > fetchTarget {
-			to = fetchTarget
+		to := fetchTarget
+		if r.Opts.BundleSize > 0 {
+			to = min(fetchStart+r.Opts.BundleSize-1, fetchTarget)
 		}
 
-		bundle, err := r.fetchBundle(from, to)
+		bundle, err := r.fetchBundle(ctx, from, to)
 		if err != nil {
-			r.Opts.Logger.Error("Failed to fetch bundle", "from", from, "to", to, "error", err)
-		} else {
-			bundles = append(bundles, bundle)
+			return nil, fmt.Errorf("failed to fetch bundle %d-%d: %w", from, to, err)
 		}
+		bundles = append(bundles, bundle)
 
-		fetchStart = bundle.Blocks[len(bundle.Blocks)-1].Number().Uint64() + 1
+		fetchStart = bundle.Height() + 1
 	}
 
 	return bundles, nil
 }
 
-func (r *Rollup) fetchBundle(from, to uint64) (*node.Bundle, error) {
-	if r.Opts.Store {
-		bundle, err := r.Node.Store.GetBundle(from, to)
-		if err == nil {
-			r.Opts.Logger.Info("Fetched bundle from local database", "from", from, "to", to, "bundle_size", bundle.Size())
-			return bundle, nil
-		}
-		r.Opts.Logger.Info("Failed to get bundle from local database, attempting to pull via RPC", "error", err)
-	}
-
-	l2blocks, err := r.LightLink.GetBlocks(from, to)
+// fetchBundle fetches a bundle of the blocks from LightLink, starting at
+// from and packed with as many blocks up to to as fit in Opts.MaxBlobSize.
+// The bundle is stored under the range it holds, for a candidate to reload
+// on resume.
+func (r *Rollup) fetchBundle(ctx context.Context, from, to uint64) (*node.Bundle, error) {
+	l2blocks, err := r.LightLink.GetBlocks(ctx, from, to, r.Opts.MaxBlobSize)
 	if err != nil {
 		return nil, fmt.Errorf("createNextBlock: Failed to get l2blocks: %w", err)
 	}
-
+	if len(l2blocks) == 0 {
+		return nil, fmt.Errorf("createNextBlock: no l2blocks from %d to %d", from, to)
+	}
 	bundle := &node.Bundle{Blocks: l2blocks}
 
 	if r.Opts.Store {
-		err := r.Node.Store.PutBundle(bundle)
+		err := r.Node.Store.PutBundle(ctx, bundle)
 		if err != nil {
 			r.Opts.Logger.Info("Failed to store bundle in local database", "from", from, "to", to, "error", err)
 		} else {
@@ -400,6 +580,10 @@ func (r *Rollup) fetchBundle(from, to uint64) (*node.Bundle, error) {
 	return bundle, nil
 }
 
+// ValidateBundles checks the bundles hold a chain of blocks, each the child
+// of the one before it, starting at the block after head. A block that is
+// not the child of the one before it means the bundles were fetched across
+// a reorg, and the error wraps ErrL2Reorg.
 func ValidateBundles(bundles []*node.Bundle, head uint64) error {
 	// check if the bundles are empty
 	if len(bundles) == 0 {
@@ -426,6 +610,10 @@ func ValidateBundles(bundles []*node.Bundle, head uint64) error {
 			if j > 0 && block.Number().Uint64() != bundle.Blocks[j-1].Number().Uint64()+1 {
 				return fmt.Errorf("block %d in bundle %d is not sequential", j, i)
 			}
+			// check the block is the child of the previous block
+			if j > 0 && block.ParentHash() != bundle.Blocks[j-1].Hash() {
+				return fmt.Errorf("block %d in bundle %d is not the child of the previous block: %w", j, i, ErrL2Reorg)
+			}
 		}
 
 		if i > 0 {
@@ -435,6 +623,9 @@ func ValidateBundles(bundles []*node.Bundle, head uint64) error {
 			if firstBlock.Number().Uint64() != prevBundleLastBlock.Number().Uint64()+1 {
 				return fmt.Errorf("first block in bundle %d is not the correct height", i)
 			}
+			if firstBlock.ParentHash() != prevBundleLastBlock.Hash() {
+				return fmt.Errorf("first block in bundle %d is not the child of the previous bundles last block: %w", i, ErrL2Reorg)
+			}
 		}
 	}
 	return nil
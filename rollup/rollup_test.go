@@ -1,6 +1,7 @@
 package rollup
 
 import (
+	"errors"
 	"hummingbird/node"
 	"hummingbird/node/lightlink/types"
 	"math/big"
@@ -15,9 +16,10 @@ func TestValidateBundles(t *testing.T) {
 		head    uint64
 	}
 	tests := []struct {
-		name   string
-		args   args
-		errStr string
+		name     string
+		args     args
+		unlinked bool // unlinked leaves the parent hashes of the blocks unset
+		errStr   string
 	}{
 		{
 			name: "happy path should pass",
@@ -233,15 +235,56 @@ func TestValidateBundles(t *testing.T) {
 			},
 			errStr: "first block in bundle 1 is not the correct height",
 		},
+		{
+			name: "test should fail as block 3 is not the child of block 2",
+			args: args{
+				bundles: []*node.Bundle{
+					{Blocks: []*types.Block{
+						types.NewBlockWithHeader(&ethtypes.Header{
+							Number: big.NewInt(2),
+						}),
+						types.NewBlockWithHeader(&ethtypes.Header{
+							Number: big.NewInt(3),
+						})}},
+				},
+				head: 1,
+			},
+			unlinked: true,
+			errStr:   "block 1 in bundle 0 is not the child of the previous block: layer 2 reorg",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if !tt.unlinked {
+				linkBlocks(tt.args.bundles)
+			}
 			err := ValidateBundles(tt.args.bundles, tt.args.head)
 			if err != nil && err.Error() != tt.errStr {
 				t.Errorf("ValidateBundles() error = %v, wantErrStr %v", err, tt.errStr)
 			} else if err == nil && tt.errStr != "" {
 				t.Errorf("ValidateBundles() expected error but got none, wantErrStr: %v", tt.errStr)
+			} else if tt.unlinked && !errors.Is(err, ErrL2Reorg) {
+				t.Errorf("ValidateBundles() error = %v, want ErrL2Reorg", err)
 			}
 		})
 	}
 }
+
+// linkBlocks sets the parent hash of each block to the hash of the block
+// before it, across bundles, as on a real chain.
+func linkBlocks(bundles []*node.Bundle) {
+	var prev *types.Block
+	for _, bundle := range bundles {
+		for i, block := range bundle.Blocks {
+			if block == nil {
+				continue
+			}
+			if prev != nil {
+				header := block.Header()
+				header.ParentHash = prev.Hash()
+				bundle.Blocks[i] = types.NewBlockWithHeader(header)
+			}
+			prev = bundle.Blocks[i]
+		}
+	}
+}
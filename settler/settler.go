@@ -0,0 +1,455 @@
+// Package settler settles expired challenges and claims the rewards of
+// settled ones, so bonds and rewards are not left in Challenge.sol.
+package settler
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"hummingbird/metrics"
+	"hummingbird/node"
+	"hummingbird/node/contracts"
+	"hummingbird/utils"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	lastScannedKey = []byte("settler_last_scanned") // last L1 block scanned for challenges
+	challengesKey  = []byte("settler_challenges")   // every challenge seen, keyed by challenge key
+)
+
+// maxScanRange is the max number of L1 blocks to filter logs for at once, to
+// avoid hitting eth_getLogs range limits.
+const maxScanRange = uint64(10000)
+
+// Kind is the type of a challenge.
+type Kind string
+
+const (
+	KindDA       Kind = "da"
+	KindL2Header Kind = "l2-header"
+)
+
+// Record is the settlement state of a challenge. It is persisted after every
+// step, so a restarted settler picks up where it left off.
+type Record struct {
+	Kind       Kind
+	Key        common.Hash    // DA challenge key or L2 header challenge hash
+	Status     uint8          // last status read from Challenge.sol
+	Expiry     int64          // L1 block time the challenge can be settled after
+	Challenger common.Address // last challenger read from Challenge.sol
+	Claimed    bool           // true once the reward is claimed, by anyone
+
+	// set for DA challenges
+	BlockHash    common.Hash
+	PointerIndex uint8
+	ShareIndex   uint32
+
+	SettleTx common.Hash // set once settled by us
+	ClaimTx  common.Hash // set once the reward is claimed by us
+	Lost     bool        // true once a loss has been recorded
+	Done     bool        // true once there is nothing left to do
+	Error    string      // last error, if any
+}
+
+type Opts struct {
+	Logger      *slog.Logger
+	WorkerDelay time.Duration  // Delay between each sweep.
+	Address     common.Address // Address of our L1 signer, rewards are only claimed for challenges it won.
+}
+
+type Settler struct {
+	*node.Node
+	Opts *Opts
+
+	lastScanned uint64
+	records     map[common.Hash]*Record // used when there is no store
+}
+
+func NewSettler(node *node.Node, opts *Opts) *Settler {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return &Settler{Node: node, Opts: opts, records: make(map[common.Hash]*Record)}
+}
+
+// Start sweeps for challenges to settle or claim every WorkerDelay.
+func (s *Settler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.Opts.WorkerDelay)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep tracks any new challenges, then settles every expired challenge and
+// claims the reward of every challenge it has seen settled in our favour.
+func (s *Settler) Sweep(ctx context.Context) error {
+	records, err := s.loadRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load challenges: %w", err)
+	}
+
+	// 1. track any new challenges
+	if err := s.scan(ctx, records); err != nil {
+		return err
+	}
+	if err := s.saveRecords(ctx, records); err != nil {
+		return fmt.Errorf("failed to save challenges: %w", err)
+	}
+
+	// 2. settle or claim each challenge that is not done
+	for _, r := range records {
+		if r.Done {
+			continue
+		}
+
+		// a failed challenge is retried on the next sweep, even if its tx
+		// reverted. The status is re-read first, so it is only done once
+		// settled and, if the reward is ours, claimed by someone
+		if err := s.advance(ctx, r); err != nil {
+			r.Error = err.Error()
+			s.Opts.Logger.Warn("Failed to settle challenge", "kind", r.Kind, "key", r.Key.Hex(), "error", err)
+		}
+
+		if err := s.saveRecords(ctx, records); err != nil {
+			return fmt.Errorf("failed to save challenges: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// scan adds every challenge seen since the last scanned L1 block, or the
+// start of the challenge window, to records.
+func (s *Settler) scan(ctx context.Context, records map[common.Hash]*Record) error {
+	lastScanned, err := s.loadLastScanned(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load last scanned block: %w", err)
+	}
+
+	scanRanges, err := s.Ethereum.GetChallengeWindowBlockRanges(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get challenge window block ranges: %w", err)
+	}
+
+	start := scanRanges[0][0]
+	end := scanRanges[len(scanRanges)-1][1]
+	if lastScanned >= start {
+		start = lastScanned + 1
+	}
+
+	for from := start; from <= end; from += maxScanRange {
+		to := min(from+maxScanRange-1, end)
+		opts := &bind.FilterOpts{Start: from, End: &to, Context: ctx}
+
+		daChallenges, err := s.Ethereum.FilterChallengeDAUpdate(opts, nil, nil, nil)
+		if err != nil {
+			return fmt.Errorf("error getting DA challenges: %w", err)
+		}
+		for daChallenges.Next() {
+			ev := daChallenges.Event
+			key, err := s.Ethereum.DataRootInclusionChallengeKey(nil, ev.BlockHash, uint8(ev.PointerIndex.Uint64()), ev.ShareIndex)
+			if err != nil {
+				return fmt.Errorf("error getting DA challenge key: %w", err)
+			}
+			if _, ok := records[key]; !ok {
+				records[key] = &Record{
+					Kind:         KindDA,
+					Key:          key,
+					Status:       ev.Status,
+					Expiry:       ev.Expiry.Int64(),
+					BlockHash:    ev.BlockHash,
+					PointerIndex: uint8(ev.PointerIndex.Uint64()),
+					ShareIndex:   ev.ShareIndex,
+				}
+			}
+		}
+		if err := daChallenges.Error(); err != nil {
+			return fmt.Errorf("error getting DA challenges: %w", err)
+		}
+
+		l2HeaderChallenges, err := s.Ethereum.FilterL2HeaderChallengeUpdate(opts, nil, nil, nil)
+		if err != nil {
+			return fmt.Errorf("error getting L2 header challenges: %w", err)
+		}
+		for l2HeaderChallenges.Next() {
+			ev := l2HeaderChallenges.Event
+			if _, ok := records[ev.ChallengeHash]; !ok {
+				records[ev.ChallengeHash] = &Record{
+					Kind:   KindL2Header,
+					Key:    ev.ChallengeHash,
+					Status: ev.Status,
+					Expiry: ev.Expiry.Int64(),
+				}
+			}
+		}
+		if err := l2HeaderChallenges.Error(); err != nil {
+			return fmt.Errorf("error getting L2 header challenges: %w", err)
+		}
+
+		if err := s.saveLastScanned(ctx, to); err != nil {
+			return fmt.Errorf("failed to save last scanned block: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// advance refreshes the challenge status, then settles it if it has expired
+// and claims its reward if it has been settled in our favour.
+func (s *Settler) advance(ctx context.Context, r *Record) error {
+	log := s.Opts.Logger.With("kind", r.Kind, "key", r.Key.Hex())
+
+	// 1. refresh the status, the challenge may have been defended or
+	// settled by someone else
+	if err := s.refresh(ctx, r); err != nil {
+		return fmt.Errorf("failed to get challenge: %w", err)
+	}
+
+	// 2. settle the challenge once it has expired, by the time of the
+	// latest L1 block as Challenge.sol checks it
+	if s.isPending(r) {
+		height, err := s.Ethereum.GetHeight(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get L1 height: %w", err)
+		}
+		now, err := s.Ethereum.GetBlockTime(ctx, height)
+		if err != nil {
+			return fmt.Errorf("failed to get time of L1 block %d: %w", height, err)
+		}
+		if now.Unix() <= r.Expiry {
+			return nil
+		}
+
+		log.Info("Settling expired challenge", "expiry", time.Unix(r.Expiry, 0).Format(time.RFC1123Z))
+		tx, err := s.settle(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to settle challenge: %w", err)
+		}
+		r.SettleTx = tx.Hash()
+		if err := s.wait(ctx, tx.Hash()); err != nil {
+			return fmt.Errorf("failed to settle challenge: %w", err)
+		}
+		log.Info("Settled expired challenge", "tx", tx.Hash().Hex())
+
+		if err := s.refresh(ctx, r); err != nil {
+			return fmt.Errorf("failed to get challenge: %w", err)
+		}
+	}
+
+	if !s.isSettled(r) {
+		return nil
+	}
+
+	// 3. record a loss if the challenge was settled against us
+	won, lost, err := s.outcome(ctx, r)
+	if err != nil {
+		return fmt.Errorf("failed to get challenge outcome: %w", err)
+	}
+	if lost && !r.Lost {
+		log.Warn("Challenge settled against us", "status", r.Status)
+		s.Metrics.Challenge(string(r.Kind), metrics.ChallengeLost)
+		r.Lost = true
+	}
+
+	// 4. claim the reward if it is ours and not claimed yet
+	if won && !r.Claimed {
+		log.Info("Claiming challenge reward", "status", r.Status)
+		txHash, err := s.claim(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to claim challenge reward: %w", err)
+		}
+		r.ClaimTx = *txHash
+		if err := s.wait(ctx, *txHash); err != nil {
+			return fmt.Errorf("failed to claim challenge reward: %w", err)
+		}
+		r.Claimed = true
+		log.Info("Claimed challenge reward", "tx", txHash.Hex())
+	}
+
+	r.Done = true
+	r.Error = ""
+	return nil
+}
+
+// wait waits for the tx and returns an error if it failed.
+func (s *Settler) wait(ctx context.Context, txHash common.Hash) error {
+	receipt, err := s.Ethereum.Wait(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to wait for tx %s: %w", txHash.Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("tx %s failed", txHash.Hex())
+	}
+	return nil
+}
+
+// refresh reads the challenge status, expiry, challenger and whether its
+// reward was claimed from Challenge.sol.
+func (s *Settler) refresh(ctx context.Context, r *Record) error {
+	switch r.Kind {
+	case KindDA:
+		info, err := s.Ethereum.GetDataRootInclusionChallenge(ctx, r.BlockHash, r.PointerIndex, r.ShareIndex)
+		if err != nil {
+			return err
+		}
+		r.Status = info.Status
+		r.Expiry = info.Expiry.Int64()
+		r.Challenger = common.HexToAddress(info.Challenger)
+		r.Claimed = info.Claimed
+	case KindL2Header:
+		info, err := s.Ethereum.GetL2HeaderChallenge(ctx, r.Key)
+		if err != nil {
+			return err
+		}
+		r.Status = info.Status
+		r.Expiry = info.ChallengeEnd.Int64()
+		r.Challenger = info.Challenger
+		r.Claimed = info.Claimed
+	default:
+		return fmt.Errorf("unknown challenge kind %q", r.Kind)
+	}
+	return nil
+}
+
+func (s *Settler) isPending(r *Record) bool {
+	if r.Kind == KindDA {
+		return r.Status == contracts.ChallengeDAStatusChallengerInitiated
+	}
+	return r.Status == contracts.ChallengeL2HeaderStatusChallengerInitiated
+}
+
+func (s *Settler) isSettled(r *Record) bool {
+	if r.Kind == KindDA {
+		return r.Status == contracts.ChallengeDAStatusChallengerWon || r.Status == contracts.ChallengeDAStatusDefenderWon
+	}
+	return r.Status == contracts.ChallengeL2HeaderStatusChallengerWon || r.Status == contracts.ChallengeL2HeaderStatusDefenderWon
+}
+
+// challengerWon returns true if the challenge was settled in favour of the
+// challenger.
+func (s *Settler) challengerWon(r *Record) bool {
+	if r.Kind == KindDA {
+		return r.Status == contracts.ChallengeDAStatusChallengerWon
+	}
+	return r.Status == contracts.ChallengeL2HeaderStatusChallengerWon
+}
+
+// outcome returns whether a settled challenge was won or lost by our
+// signer, as the challenger or the defender set in Challenge.sol. A
+// challenge we are not a party to is neither.
+func (s *Settler) outcome(ctx context.Context, r *Record) (won, lost bool, err error) {
+	defender, err := s.Ethereum.GetDefender(ctx)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get defender: %w", err)
+	}
+
+	challengerWon := s.challengerWon(r)
+	if s.Opts.Address == r.Challenger {
+		won, lost = won || challengerWon, lost || !challengerWon
+	}
+	if s.Opts.Address == defender {
+		won, lost = won || !challengerWon, lost || challengerWon
+	}
+	return won, lost, nil
+}
+
+func (s *Settler) settle(ctx context.Context, r *Record) (*types.Transaction, error) {
+	if r.Kind == KindDA {
+		return s.Ethereum.SettleDataRootInclusion(ctx, r.Key)
+	}
+	return s.Ethereum.SettleL2HeaderChallenge(ctx, r.Key)
+}
+
+func (s *Settler) claim(ctx context.Context, r *Record) (*common.Hash, error) {
+	if r.Kind == KindDA {
+		return s.Ethereum.ClaimDAChallengeReward(ctx, r.Key)
+	}
+	return s.Ethereum.ClaimL2HeaderChallengeReward(ctx, r.Key)
+}
+
+// Records returns every challenge the settler has seen.
+func (s *Settler) Records(ctx context.Context) (map[common.Hash]*Record, error) {
+	return s.loadRecords(ctx)
+}
+
+// loadRecords returns every challenge seen, from the store if there is one.
+func (s *Settler) loadRecords(ctx context.Context) (map[common.Hash]*Record, error) {
+	if s.Store == nil {
+		return s.records, nil
+	}
+
+	buf, err := s.Store.Get(ctx, challengesKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return make(map[common.Hash]*Record), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[common.Hash]*Record)
+	if err := json.Unmarshal(buf, &records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal challenges: %w", err)
+	}
+	return records, nil
+}
+
+// saveRecords persists every challenge seen.
+func (s *Settler) saveRecords(ctx context.Context, records map[common.Hash]*Record) error {
+	if s.Store == nil {
+		s.records = records
+		return nil
+	}
+
+	return s.Store.Put(ctx, challengesKey, utils.MustJsonMarshal(records))
+}
+
+// loadLastScanned returns the last L1 block scanned for challenges, or 0 if
+// the settler has not run before.
+func (s *Settler) loadLastScanned(ctx context.Context) (uint64, error) {
+	if s.Store == nil {
+		return s.lastScanned, nil
+	}
+
+	buf, err := s.Store.Get(ctx, lastScannedKey)
+	if errors.Is(err, node.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("invalid last scanned block")
+	}
+
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// saveLastScanned persists the last L1 block scanned for challenges.
+func (s *Settler) saveLastScanned(ctx context.Context, block uint64) error {
+	s.lastScanned = block
+	if s.Store == nil {
+		return nil
+	}
+
+	return s.Store.Put(ctx, lastScannedKey, binary.BigEndian.AppendUint64(nil, block))
+}
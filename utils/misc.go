@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 func NoErr(err error) {
@@ -13,6 +15,20 @@ func NoErr(err error) {
 	}
 }
 
+// Sleep pauses for the duration, returning early with the context error if
+// the context is done first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
 func IndentText(text, indent string) string {
 	return strings.ReplaceAll(text, "\n", "\n"+indent)
 }